@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"log"
+	"merch-api/config"
+	"merch-api/migrations"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Использование: migrate [-env-file .env] <команда>
+
+Команды:
+  up        применить все недостающие миграции
+  down [N]  откатить N последних миграций (по умолчанию 1)
+  status    показать состояние миграций
+  check     проверить, что данные не нарушают ограничений ожидающих миграций`
+
+func main() {
+	dbConfig, args, err := config.LoadDB(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Некорректная конфигурация: %v", err)
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql.Open("pgx", dbConfig.DSN())
+	if err != nil {
+		log.Fatalf("Ошибка при соединении с БД: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Не удалось прочитать миграции: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		printDone("применена", done)
+		if err != nil {
+			log.Fatalf("Ошибка при применении миграций: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("Схема актуальна")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("Некорректное число миграций для отката: %q", args[1])
+			}
+		}
+		done, err := migrator.Down(ctx, steps)
+		printDone("откачена", done)
+		if err != nil {
+			log.Fatalf("Ошибка при откате миграций: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Не удалось получить состояние миграций: %v", err)
+		}
+		printStatus(statuses)
+	case "check":
+		reports, err := migrator.Check(ctx)
+		if err != nil {
+			log.Fatalf("Не удалось проверить данные: %v", err)
+		}
+		for _, report := range reports {
+			fmt.Println(report.Error())
+		}
+		if len(reports) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("Нарушений не найдено")
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func printDone(verb string, done []migrations.Migration) {
+	for _, migration := range done {
+		fmt.Printf("%s: %s\n", verb, migration)
+	}
+}
+
+func printStatus(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ВЕРСИЯ\tИМЯ\tСОСТОЯНИЕ\tПРИМЕНЕНА")
+	for _, status := range statuses {
+		state := "ожидает"
+		switch {
+		case status.Dirty:
+			state = "dirty"
+		case status.Missing:
+			state = "нет файла"
+		case status.Applied:
+			state = "применена"
+		}
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	w.Flush()
+}
@@ -1,46 +1,92 @@
 package main
 
 import (
-	"fmt"
-	"github.com/joho/godotenv"
+	"context"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"log/slog"
+	"merch-api/config"
+	"merch-api/logging"
+	"merch-api/migrations"
 	"merch-api/router"
+	"merch-api/server"
+	"merch-api/tracing"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
-func init() {
-	err := godotenv.Load()
+func main() {
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		log.Fatalf("Некорректная конфигурация: %v", err)
 	}
-}
 
-func main() {
-	db := InitDB()
-	r := router.SetupRouter(db)
+	slog.SetDefault(logging.NewLogger(os.Stdout, cfg.Log.Level))
+	for _, warning := range cfg.Warnings() {
+		slog.Warn("unsafe configuration", slog.String("warning", warning))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	db := InitDB(cfg)
+	if cfg.Migrate.OnStart {
+		migrate(ctx, db)
+	}
+	r := router.SetupRouter(db, cfg)
+	srv := server.New(cfg.HTTP, r)
+
+	if err := server.Run(ctx, srv, cfg.HTTP.ShutdownTimeout); err != nil {
+		slog.Error("http server stopped with error", slog.String("error", err.Error()))
+	}
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("failed to close db pool", slog.String("error", err.Error()))
+		}
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
+	slog.Info("server stopped")
 }
 
-func InitDB() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"user=%s password=%s dbname=%s host=%s port=%s sslmode=disable",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-	)
-
-	var err error
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func InitDB(cfg *config.Config) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.DB.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(slog.Default(), cfg.Log.DBLevel),
+	})
 	if err != nil {
 		log.Fatalf("Ошибка при соединении с БД: %v", err)
 	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to register tracing plugin: %v", err)
+	}
 
 	return db
 }
+
+func migrate(ctx context.Context, db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Ошибка при получении пула соединений: %v", err)
+	}
+	migrator, err := migrations.NewMigrator(sqlDB, migrations.FS)
+	if err != nil {
+		log.Fatalf("Не удалось прочитать миграции: %v", err)
+	}
+	done, err := migrator.Up(ctx)
+	for _, migration := range done {
+		slog.Info("migration applied", slog.String("migration", migration.String()))
+	}
+	if err != nil {
+		log.Fatalf("Ошибка при применении миграций: %v", err)
+	}
+}
@@ -0,0 +1,607 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"io/fs"
+	"math"
+	"merch-api/server"
+	"merch-api/tracing"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minJWTSecretLength = 32
+	maxJWTClockSkew    = 5 * time.Minute
+)
+
+type Config struct {
+	HTTP     server.Config
+	DB       DBConfig
+	JWT      JWTConfig
+	Auth     AuthConfig
+	Log      LogConfig
+	Tracing  tracing.Config
+	Features FeatureConfig
+	Migrate  MigrateConfig
+}
+
+type DBConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+}
+
+func (c DBConfig) DSN() string {
+	return fmt.Sprintf(
+		"user=%s password=%s dbname=%s host=%s port=%s sslmode=%s",
+		c.User, c.Password, c.Name, c.Host, c.Port, c.SSLMode,
+	)
+}
+
+type JWTConfig struct {
+	// Secret подписывает токены HS256, пока не заданы SigningKeys; после перехода на ключи
+	// он только проверяет выданные раньше токены, и через TTL его можно убрать
+	Secret []byte
+	TTL    time.Duration
+	// Issuer и Audience записываются в выданные токены и обязательны при проверке
+	Issuer   string
+	Audience string
+	// ClockSkew — допустимое расхождение часов при проверке exp, nbf и iat
+	ClockSkew time.Duration
+	// SigningKeys — асимметричные ключи из JWT_KEYS в порядке ActiveFrom
+	SigningKeys []SigningKey
+	// SecretAcceptUntil — с этого момента HS256-токены, подписанные Secret, больше не принимаются;
+	// нулевое значение оставляет их действительными бессрочно
+	SecretAcceptUntil time.Time
+}
+
+type AuthConfig struct {
+	// PasswordResetTTL — сколько действует токен сброса пароля, выданный администратором
+	PasswordResetTTL time.Duration
+	Throttle         LoginThrottleConfig
+	PasswordHash     PasswordHashConfig
+	// Provider проверяет пароль на POST /auth: local — хеш из таблицы employee, ldap — bind в каталог
+	Provider string
+	LDAP     LDAPConfig
+	OIDC     OIDCConfig
+}
+
+const (
+	ProviderLocal = "local"
+	ProviderLDAP  = "ldap"
+)
+
+// LDAPConfig описывает вход через каталог: сервис ищет запись сотрудника по UserFilter
+// (от имени BindDN или анонимно) и проверяет пароль bind'ом от имени найденной записи
+type LDAPConfig struct {
+	URL                  string
+	StartTLS             bool
+	BindDN               string
+	BindPassword         string
+	BaseDN               string
+	UserFilter           string
+	UsernameAttribute    string
+	DisplayNameAttribute string
+	EmailAttribute       string
+	Timeout              time.Duration
+}
+
+// OIDCConfig включает вход через SSO по authorization code flow; пустой Issuer выключает его
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+}
+
+// userEditableOIDCClaims — claim'ы, которые пользователь обычно правит сам в профиле у провайдера
+var userEditableOIDCClaims = []string{"preferred_username", "nickname", "name", "given_name"}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// PasswordHashConfig выбирает алгоритм для новых хешей паролей. Хеши, сделанные другим
+// алгоритмом или с более слабыми параметрами, пересчитываются при следующем успешном входе.
+type PasswordHashConfig struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Config
+}
+
+type Argon2Config struct {
+	// Memory — объём памяти в КиБ
+	Memory      int
+	Iterations  int
+	Parallelism int
+}
+
+// LoginThrottleConfig задаёт защиту POST /auth от подбора пароля. После MaxFailuresPerUser
+// (или MaxFailuresPerIP) неудач подряд вход блокируется на LockoutBase, и каждая следующая
+// неудача удваивает блокировку, но не больше LockoutMax. Счётчик сбрасывается успешным входом
+// или если неудач не было дольше FailureWindow.
+type LoginThrottleConfig struct {
+	MaxFailuresPerUser int
+	MaxFailuresPerIP   int
+	LockoutBase        time.Duration
+	LockoutMax         time.Duration
+	FailureWindow      time.Duration
+}
+
+type LogConfig struct {
+	Level   string
+	DBLevel string
+}
+
+type FeatureConfig struct {
+	// LegacyBuyGet оставляет включённым GET /buy/:item, меняющий баланс
+	LegacyBuyGet bool
+}
+
+type MigrateConfig struct {
+	// OnStart применяет недостающие миграции при старте сервера
+	OnStart bool
+}
+
+// Load читает конфигурацию из env-файла, переменных окружения и флагов (в порядке возрастания приоритета)
+// и проверяет её. Отсутствие env-файла по умолчанию не считается ошибкой.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("merch-api", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "путь к env-файлу")
+	httpHost := flags.String("http-host", "", "адрес, на котором слушает HTTP-сервер")
+	httpPort := flags.String("http-port", "", "порт HTTP-сервера")
+	logLevel := flags.String("log-level", "", "уровень логов: debug, info, warn, error")
+	dbLogLevel := flags.String("db-log-level", "", "уровень SQL-логов GORM: silent, error, warn, info")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := loadEnvFile(flags, *envFile); err != nil {
+		return nil, err
+	}
+
+	cfg, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http-host":
+			cfg.HTTP.Host = *httpHost
+		case "http-port":
+			cfg.HTTP.Port = *httpPort
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "db-log-level":
+			cfg.Log.DBLevel = *dbLogLevel
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadDB читает только настройки БД — для утилит вроде cmd/migrate, которым не нужны JWT и HTTP
+func LoadDB(args []string) (DBConfig, []string, error) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "путь к env-файлу")
+	if err := flags.Parse(args); err != nil {
+		return DBConfig{}, nil, err
+	}
+	if err := loadEnvFile(flags, *envFile); err != nil {
+		return DBConfig{}, nil, err
+	}
+
+	e := &envReader{}
+	db := dbFromEnv(e)
+	if err := errors.Join(append(e.errs, db.validate()...)...); err != nil {
+		return DBConfig{}, nil, err
+	}
+	return db, flags.Args(), nil
+}
+
+// loadEnvFile загружает env-файл; отсутствие файла по умолчанию не считается ошибкой
+func loadEnvFile(flags *flag.FlagSet, envFile string) error {
+	envFileSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "env-file" {
+			envFileSet = true
+		}
+	})
+	if err := godotenv.Load(envFile); err != nil && (envFileSet || !errors.Is(err, fs.ErrNotExist)) {
+		return fmt.Errorf("не удалось загрузить %s: %w", envFile, err)
+	}
+	return nil
+}
+
+func dbFromEnv(e *envReader) DBConfig {
+	return DBConfig{
+		Host:     e.string("DB_HOST", "localhost"),
+		Port:     e.string("DB_PORT", "5432"),
+		User:     e.string("DB_USER", ""),
+		Password: e.string("DB_PASSWORD", ""),
+		Name:     e.string("DB_NAME", ""),
+		SSLMode:  e.string("DB_SSLMODE", "disable"),
+	}
+}
+
+func FromEnv() (*Config, error) {
+	e := &envReader{}
+	cfg := &Config{
+		HTTP: server.Config{
+			Host:              e.string("HTTP_HOST", ""),
+			Port:              e.string("HTTP_PORT", "8080"),
+			ReadTimeout:       e.duration("HTTP_READ_TIMEOUT", 10*time.Second),
+			ReadHeaderTimeout: e.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      e.duration("HTTP_WRITE_TIMEOUT", 15*time.Second),
+			IdleTimeout:       e.duration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+			ShutdownTimeout:   e.duration("HTTP_SHUTDOWN_TIMEOUT", 20*time.Second),
+			RequestTimeout:    e.duration("HTTP_REQUEST_TIMEOUT", 5*time.Second),
+			TrustedProxies:    e.list("HTTP_TRUSTED_PROXIES"),
+		},
+		DB: dbFromEnv(e),
+		JWT: JWTConfig{
+			Secret:            []byte(e.string("JWT_SECRET", "")),
+			TTL:               e.duration("JWT_TTL", 24*time.Hour),
+			Issuer:            e.string("JWT_ISSUER", "merch-api"),
+			Audience:          e.string("JWT_AUDIENCE", "merch-api"),
+			ClockSkew:         e.duration("JWT_CLOCK_SKEW", 30*time.Second),
+			SigningKeys:       e.signingKeys("JWT_KEYS"),
+			SecretAcceptUntil: e.time("JWT_SECRET_ACCEPT_UNTIL"),
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL: e.duration("PASSWORD_RESET_TTL", 24*time.Hour),
+			Throttle: LoginThrottleConfig{
+				MaxFailuresPerUser: e.int("AUTH_MAX_FAILURES_PER_USER", 5),
+				MaxFailuresPerIP:   e.int("AUTH_MAX_FAILURES_PER_IP", 50),
+				LockoutBase:        e.duration("AUTH_LOCKOUT_BASE", 30*time.Second),
+				LockoutMax:         e.duration("AUTH_LOCKOUT_MAX", 15*time.Minute),
+				FailureWindow:      e.duration("AUTH_FAILURE_WINDOW", time.Hour),
+			},
+			Provider: e.string("AUTH_PROVIDER", ProviderLocal),
+			LDAP: LDAPConfig{
+				URL:                  e.string("LDAP_URL", ""),
+				StartTLS:             e.bool("LDAP_START_TLS", false),
+				BindDN:               e.string("LDAP_BIND_DN", ""),
+				BindPassword:         e.string("LDAP_BIND_PASSWORD", ""),
+				BaseDN:               e.string("LDAP_BASE_DN", ""),
+				UserFilter:           e.string("LDAP_USER_FILTER", "(uid=%s)"),
+				UsernameAttribute:    e.string("LDAP_USERNAME_ATTRIBUTE", "uid"),
+				DisplayNameAttribute: e.string("LDAP_DISPLAY_NAME_ATTRIBUTE", "cn"),
+				EmailAttribute:       e.string("LDAP_EMAIL_ATTRIBUTE", "mail"),
+				Timeout:              e.duration("LDAP_TIMEOUT", 5*time.Second),
+			},
+			OIDC: OIDCConfig{
+				Issuer:        e.string("OIDC_ISSUER", ""),
+				ClientID:      e.string("OIDC_CLIENT_ID", ""),
+				ClientSecret:  e.string("OIDC_CLIENT_SECRET", ""),
+				RedirectURL:   e.string("OIDC_REDIRECT_URL", ""),
+				Scopes:        e.listOr("OIDC_SCOPES", []string{"openid", "profile", "email"}),
+				UsernameClaim: e.string("OIDC_USERNAME_CLAIM", "preferred_username"),
+			},
+			PasswordHash: PasswordHashConfig{
+				Algorithm:  e.string("PASSWORD_HASH_ALGORITHM", HashBcrypt),
+				BcryptCost: e.int("BCRYPT_COST", 12),
+				Argon2: Argon2Config{
+					Memory:      e.int("ARGON2_MEMORY_KIB", 64*1024),
+					Iterations:  e.int("ARGON2_ITERATIONS", 3),
+					Parallelism: e.int("ARGON2_PARALLELISM", 4),
+				},
+			},
+		},
+		Log: LogConfig{
+			Level:   e.string("LOG_LEVEL", "info"),
+			DBLevel: e.string("DB_LOG_LEVEL", "silent"),
+		},
+		Tracing: tracing.Config{
+			Endpoint:    e.string("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			Insecure:    e.bool("OTEL_EXPORTER_OTLP_INSECURE", false),
+			ServiceName: e.string("OTEL_SERVICE_NAME", "merch-api"),
+		},
+		Features: FeatureConfig{
+			LegacyBuyGet: e.bool("LEGACY_BUY_GET_ENABLED", true),
+		},
+		Migrate: MigrateConfig{
+			OnStart: e.bool("MIGRATE_ON_START", false),
+		},
+	}
+
+	// Раньше подпись и проверка токенов читали ключ из разных переменных
+	for _, legacy := range []string{"JWT_SECRET_KEY", "JWT_KEY"} {
+		if value, ok := os.LookupEnv(legacy); ok && value != string(cfg.JWT.Secret) {
+			e.errs = append(e.errs, fmt.Errorf("%s устарела и не совпадает с JWT_SECRET, оставьте только JWT_SECRET", legacy))
+		}
+	}
+
+	if err := errors.Join(e.errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	errs = append(errs, c.JWT.validate()...)
+	if c.Auth.PasswordResetTTL <= 0 {
+		errs = append(errs, errors.New("PASSWORD_RESET_TTL должен быть положительным"))
+	}
+	errs = append(errs, c.Auth.Throttle.validate()...)
+	errs = append(errs, c.Auth.PasswordHash.validate()...)
+	switch c.Auth.Provider {
+	case ProviderLocal:
+	case ProviderLDAP:
+		errs = append(errs, c.Auth.LDAP.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("AUTH_PROVIDER должен быть %s или %s", ProviderLocal, ProviderLDAP))
+	}
+	if c.Auth.OIDC.Enabled() {
+		errs = append(errs, c.Auth.OIDC.validate()...)
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("некорректный адрес в HTTP_TRUSTED_PROXIES: %q", proxy))
+		}
+	}
+
+	errs = append(errs, c.DB.validate()...)
+
+	if _, err := strconv.ParseUint(c.HTTP.Port, 10, 16); err != nil {
+		errs = append(errs, fmt.Errorf("некорректный HTTP_PORT %q", c.HTTP.Port))
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("HTTP_SHUTDOWN_TIMEOUT должен быть положительным"))
+	}
+	if c.HTTP.RequestTimeout < 0 {
+		errs = append(errs, errors.New("HTTP_REQUEST_TIMEOUT не может быть отрицательным"))
+	}
+	if c.HTTP.RequestTimeout > 0 && c.HTTP.WriteTimeout > 0 && c.HTTP.RequestTimeout >= c.HTTP.WriteTimeout {
+		errs = append(errs, errors.New("HTTP_REQUEST_TIMEOUT должен быть меньше HTTP_WRITE_TIMEOUT, иначе ответ о таймауте не успеет уйти клиенту"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings возвращает настройки, которые допустимы, но небезопасны; сервер пишет их в лог при запуске
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Auth.OIDC.Enabled() && slices.Contains(userEditableOIDCClaims, c.Auth.OIDC.UsernameClaim) {
+		warnings = append(warnings, fmt.Sprintf("OIDC_USERNAME_CLAIM=%s: у многих провайдеров пользователь меняет его сам и может "+
+			"назваться логином сотрудника, ещё не связанного с SSO; лучше неизменяемый claim вроде email или upn", c.Auth.OIDC.UsernameClaim))
+	}
+	if len(c.JWT.Secret) > 0 && len(c.JWT.SigningKeys) > 0 && c.JWT.SecretAcceptUntil.IsZero() {
+		warnings = append(warnings, "JWT_SECRET задан вместе с JWT_KEYS без JWT_SECRET_ACCEPT_UNTIL: токены HS256 "+
+			"принимаются бессрочно, задайте срок или удалите JWT_SECRET после перехода на ключи")
+	}
+	return warnings
+}
+
+func (c LoginThrottleConfig) validate() []error {
+	var errs []error
+	if c.MaxFailuresPerUser < 1 {
+		errs = append(errs, errors.New("AUTH_MAX_FAILURES_PER_USER должен быть положительным"))
+	}
+	if c.MaxFailuresPerIP < 1 {
+		errs = append(errs, errors.New("AUTH_MAX_FAILURES_PER_IP должен быть положительным"))
+	}
+	if c.LockoutBase <= 0 {
+		errs = append(errs, errors.New("AUTH_LOCKOUT_BASE должен быть положительным"))
+	}
+	if c.LockoutMax < c.LockoutBase {
+		errs = append(errs, errors.New("AUTH_LOCKOUT_MAX не может быть меньше AUTH_LOCKOUT_BASE"))
+	}
+	if c.FailureWindow <= 0 {
+		errs = append(errs, errors.New("AUTH_FAILURE_WINDOW должен быть положительным"))
+	}
+	return errs
+}
+
+func (c PasswordHashConfig) validate() []error {
+	var errs []error
+	switch c.Algorithm {
+	case HashBcrypt:
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			errs = append(errs, fmt.Errorf("BCRYPT_COST должен быть от %d до %d", bcrypt.MinCost, bcrypt.MaxCost))
+		}
+	case HashArgon2id:
+		if c.Argon2.Parallelism < 1 || c.Argon2.Parallelism > math.MaxUint8 {
+			errs = append(errs, fmt.Errorf("ARGON2_PARALLELISM должен быть от 1 до %d", math.MaxUint8))
+		}
+		if c.Argon2.Memory < 8*c.Argon2.Parallelism || c.Argon2.Memory > math.MaxUint32 {
+			errs = append(errs, errors.New("ARGON2_MEMORY_KIB должен быть не меньше 8 КиБ на поток"))
+		}
+		if c.Argon2.Iterations < 1 || c.Argon2.Iterations > math.MaxUint32 {
+			errs = append(errs, errors.New("ARGON2_ITERATIONS должен быть положительным"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("PASSWORD_HASH_ALGORITHM должен быть %s или %s", HashBcrypt, HashArgon2id))
+	}
+	return errs
+}
+
+func (c JWTConfig) validate() []error {
+	var errs []error
+	switch {
+	case len(c.Secret) == 0 && len(c.SigningKeys) == 0:
+		errs = append(errs, errors.New("JWT_SECRET не задан"))
+	case len(c.Secret) > 0 && len(c.Secret) < minJWTSecretLength:
+		errs = append(errs, fmt.Errorf("JWT_SECRET короче %d байт", minJWTSecretLength))
+	}
+	if !c.SecretAcceptUntil.IsZero() && (len(c.Secret) == 0 || len(c.SigningKeys) == 0) {
+		errs = append(errs, errors.New("JWT_SECRET_ACCEPT_UNTIL имеет смысл только вместе с JWT_SECRET и JWT_KEYS"))
+	}
+	if c.TTL <= 0 {
+		errs = append(errs, errors.New("JWT_TTL должен быть положительным"))
+	}
+	if c.ClockSkew < 0 || c.ClockSkew > maxJWTClockSkew {
+		errs = append(errs, fmt.Errorf("JWT_CLOCK_SKEW должен быть от 0 до %s", maxJWTClockSkew))
+	}
+
+	ids := make(map[string]bool, len(c.SigningKeys))
+	for i, key := range c.SigningKeys {
+		if ids[key.ID] {
+			errs = append(errs, fmt.Errorf("JWT_KEYS: ключ %s указан дважды", key.ID))
+		}
+		ids[key.ID] = true
+		if i > 0 && key.ActiveFrom.Equal(c.SigningKeys[i-1].ActiveFrom) {
+			errs = append(errs, fmt.Errorf("JWT_KEYS: ключи %s и %s начинают действовать одновременно", c.SigningKeys[i-1].ID, key.ID))
+		}
+	}
+	return errs
+}
+
+func (c LDAPConfig) validate() []error {
+	var errs []error
+	if c.URL == "" {
+		errs = append(errs, errors.New("LDAP_URL не задан"))
+	}
+	if c.BaseDN == "" {
+		errs = append(errs, errors.New("LDAP_BASE_DN не задан"))
+	}
+	if strings.Count(c.UserFilter, "%s") != 1 {
+		errs = append(errs, errors.New("LDAP_USER_FILTER должен содержать ровно один %s для логина"))
+	}
+	if c.UsernameAttribute == "" {
+		errs = append(errs, errors.New("LDAP_USERNAME_ATTRIBUTE не задан"))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, errors.New("LDAP_TIMEOUT должен быть положительным"))
+	}
+	return errs
+}
+
+func (c OIDCConfig) validate() []error {
+	var errs []error
+	if c.ClientID == "" {
+		errs = append(errs, errors.New("OIDC_CLIENT_ID не задан"))
+	}
+	if c.RedirectURL == "" {
+		errs = append(errs, errors.New("OIDC_REDIRECT_URL не задан"))
+	}
+	if !slices.Contains(c.Scopes, "openid") {
+		errs = append(errs, errors.New("OIDC_SCOPES должен содержать openid"))
+	}
+	if c.UsernameClaim == "" {
+		errs = append(errs, errors.New("OIDC_USERNAME_CLAIM не задан"))
+	}
+	return errs
+}
+
+func (c DBConfig) validate() []error {
+	var errs []error
+	if c.User == "" {
+		errs = append(errs, errors.New("DB_USER не задан"))
+	}
+	if c.Name == "" {
+		errs = append(errs, errors.New("DB_NAME не задан"))
+	}
+	if _, err := strconv.ParseUint(c.Port, 10, 16); err != nil {
+		errs = append(errs, fmt.Errorf("некорректный DB_PORT %q", c.Port))
+	}
+	return errs
+}
+
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (e *envReader) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("некорректное значение %s: %w", key, err))
+		return fallback
+	}
+	return parsed
+}
+
+// time читает время в RFC 3339, пустая переменная даёт нулевое время
+func (e *envReader) time(key string) time.Time {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("некорректное значение %s: %w", key, err))
+		return time.Time{}
+	}
+	return parsed
+}
+
+func (e *envReader) bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("некорректное значение %s: %w", key, err))
+		return fallback
+	}
+	return parsed
+}
+
+func (e *envReader) int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("некорректное значение %s: %w", key, err))
+		return fallback
+	}
+	return parsed
+}
+
+// listOr читает список через запятую или возвращает fallback, если переменная пуста
+func (e *envReader) listOr(key string, fallback []string) []string {
+	if values := e.list(key); len(values) > 0 {
+		return values
+	}
+	return fallback
+}
+
+// list читает значения через запятую, пустые элементы отбрасываются
+func (e *envReader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const minRSAKeyBits = 2048
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// SigningKey — закрытый ключ подписи токенов (RSA для RS256 или Ed25519 для EdDSA).
+// С ActiveFrom ключ начинает подписывать новые токены вместо предыдущего
+type SigningKey struct {
+	ID         string
+	ActiveFrom time.Time
+	Private    crypto.Signer
+}
+
+// signingKeys читает JWT_KEYS: через запятую kid=путь к PEM-файлу, для запланированной
+// ротации — kid=путь@время в RFC 3339. Ключи возвращаются в порядке ActiveFrom
+func (e *envReader) signingKeys(key string) []SigningKey {
+	var keys []SigningKey
+	for _, entry := range e.list(key) {
+		signingKey, err := parseSigningKey(entry)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("некорректное значение %s: %w", key, err))
+			continue
+		}
+		keys = append(keys, signingKey)
+	}
+	slices.SortStableFunc(keys, func(a, b SigningKey) int {
+		return a.ActiveFrom.Compare(b.ActiveFrom)
+	})
+	return keys
+}
+
+func parseSigningKey(entry string) (SigningKey, error) {
+	id, path, ok := strings.Cut(entry, "=")
+	if !ok || !keyIDPattern.MatchString(id) {
+		return SigningKey{}, fmt.Errorf("%q: ожидается kid=путь[@время], kid из латиницы, цифр, точки, _ и -", entry)
+	}
+
+	var activeFrom time.Time
+	if at := strings.LastIndex(path, "@"); at >= 0 {
+		var err error
+		if activeFrom, err = time.Parse(time.RFC3339, path[at+1:]); err != nil {
+			return SigningKey{}, fmt.Errorf("ключ %s: время начала действия не в формате RFC 3339: %w", id, err)
+		}
+		path = path[:at]
+	}
+
+	private, err := readPrivateKey(path)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("ключ %s: %w", id, err)
+	}
+	return SigningKey{ID: id, ActiveFrom: activeFrom, Private: private}, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s не PEM-файл", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: неподдерживаемый тип PEM %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s: RSA-ключ короче %d бит", path, minRSAKeyBits)
+		}
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, errors.New(path + ": поддерживаются только ключи RSA и Ed25519")
+	}
+}
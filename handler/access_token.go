@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AccessTokenHandler struct {
+	service service.AccessTokenService
+}
+
+func NewAccessTokenHandler(svc service.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		service: svc,
+	}
+}
+
+type AccessTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateToken выпускает токен доступа; сам токен есть только в этом ответе
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	var input AccessTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	token, err := h.service.CreateToken(c.Request.Context(), username, service.CreateAccessTokenRequest{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, token, token)
+}
+
+func (h *AccessTokenHandler) ListTokens(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	tokens, err := h.service.ListTokens(c.Request.Context(), username)
+	if err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	response := gin.H{"tokens": tokens}
+	respondOK(c, response, response)
+}
+
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		httpx.RespondError(c, http.StatusBadRequest, "Некорректный id токена")
+		return
+	}
+
+	if err := h.service.RevokeToken(c.Request.Context(), username, uint(id)); err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	message := gin.H{"message": "Токен отозван"}
+	respondOK(c, message, message)
+}
+
+func respondAccessTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAccessTokenRequest):
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTooManyAccessTokens):
+		httpx.RespondError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrAccessTokenNotFound), errors.Is(err, service.ErrEmployeeNotFound):
+		httpx.RespondError(c, http.StatusNotFound, err.Error())
+	default:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
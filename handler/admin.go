@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net"
+	"net/http"
+)
+
+type AdminHandler struct {
+	employees service.EmployeeService
+	logins    service.LoginUnlocker
+}
+
+func NewAdminHandler(employees service.EmployeeService, logins service.LoginUnlocker) *AdminHandler {
+	return &AdminHandler{
+		employees: employees,
+		logins:    logins,
+	}
+}
+
+type DeactivateInput struct {
+	ForfeitBalance bool   `json:"forfeitBalance"`
+	TransferTo     string `json:"transferTo"`
+}
+
+func (h *AdminHandler) DeactivateEmployee(c *gin.Context) {
+	var input DeactivateInput
+	// Тело необязательно: без него баланс остаётся у сотрудника
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	status, err := h.employees.Deactivate(c.Request.Context(), service.DeactivateRequest{
+		Username:       c.Param("username"),
+		ForfeitBalance: input.ForfeitBalance,
+		TransferTo:     input.TransferTo,
+	})
+	if err != nil {
+		respondEmployeeError(c, err)
+		return
+	}
+
+	respondOK(c, status, status)
+}
+
+func (h *AdminHandler) ReactivateEmployee(c *gin.Context) {
+	status, err := h.employees.Reactivate(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		respondEmployeeError(c, err)
+		return
+	}
+
+	respondOK(c, status, status)
+}
+
+// UnlockEmployeeLogin снимает блокировку входа по логину после неудачных попыток
+func (h *AdminHandler) UnlockEmployeeLogin(c *gin.Context) {
+	username := c.Param("username")
+	if err := h.logins.UnlockUser(c.Request.Context(), username); err != nil {
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	message := gin.H{"message": "Вход для " + username + " разблокирован"}
+	respondOK(c, message, message)
+}
+
+// UnlockIPLogin снимает блокировку входа с IP-адреса, например офисного NAT
+func (h *AdminHandler) UnlockIPLogin(c *gin.Context) {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Некорректный IP-адрес")
+		return
+	}
+	if err := h.logins.UnlockIP(c.Request.Context(), ip.String()); err != nil {
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	message := gin.H{"message": "Вход с " + ip.String() + " разблокирован"}
+	respondOK(c, message, message)
+}
+
+func respondEmployeeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmployeeNotFound):
+		httpx.RespondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrEmployeeDeactivated), errors.Is(err, service.ErrEmployeeAlreadyActive):
+		httpx.RespondError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidBalanceAction):
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTransferTargetDeactivated):
+		httpx.RespondError(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
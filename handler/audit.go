@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var auditCSVHeader = []string{"id", "createdAt", "actor", "accessTokenId", "action", "target", "balanceBefore", "balanceAfter", "details", "ip", "requestId"}
+
+type AuditHandler struct {
+	service service.AuditService
+}
+
+func NewAuditHandler(svc service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		service: svc,
+	}
+}
+
+// SearchAudit отдаёт страницу журнала аудита от новых записей к старым
+func (h *AuditHandler) SearchAudit(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.Search(c.Request.Context(), query)
+	if err != nil {
+		respondAuditError(c, err)
+		return
+	}
+
+	respondOK(c, page, page)
+}
+
+// ExportAudit выгружает все записи под фильтр в CSV (по умолчанию) или JSON Lines (format=jsonl)
+func (h *AuditHandler) ExportAudit(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.Limit = 0
+
+	var export auditExport
+	switch format := c.DefaultQuery("format", "csv"); format {
+	case "csv":
+		export = &csvAuditExport{writer: csv.NewWriter(c.Writer)}
+	case "jsonl":
+		export = &jsonlAuditExport{encoder: json.NewEncoder(c.Writer)}
+	default:
+		httpx.RespondError(c, http.StatusBadRequest, "Неизвестный формат выгрузки "+format+", поддерживаются csv и jsonl")
+		return
+	}
+
+	// Заголовки ответа отправляются с первой записью: до неё ошибку ещё можно вернуть статусом
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		c.Header("Content-Type", export.contentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log.%s"`, export.extension()))
+		c.Status(http.StatusOK)
+		return export.begin()
+	}
+
+	err = h.service.Export(c.Request.Context(), query, func(entry service.AuditEntry) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return export.write(entry)
+	})
+	if err == nil {
+		err = start()
+	}
+	if err == nil {
+		err = export.flush()
+	}
+	if err != nil {
+		if !started {
+			respondAuditError(c, err)
+			return
+		}
+		// Статус уже отправлен, клиент получит оборванный файл
+		slog.ErrorContext(c.Request.Context(), "audit export interrupted", slog.String("error", err.Error()))
+		c.Abort()
+	}
+}
+
+func parseAuditQuery(c *gin.Context) (service.AuditQuery, error) {
+	query := service.AuditQuery{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+	}
+
+	var err error
+	if query.From, err = parseAuditTime(c, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = parseAuditTime(c, "to"); err != nil {
+		return query, err
+	}
+	if value := c.Query("before"); value != "" {
+		before, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return query, errors.New("before должен быть id записи")
+		}
+		query.Before = uint(before)
+	}
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil {
+			return query, errors.New("limit должен быть числом")
+		}
+	}
+	return query, nil
+}
+
+func parseAuditTime(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s должен быть временем в формате RFC 3339", name)
+	}
+	return parsed, nil
+}
+
+func respondAuditError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidAuditQuery) {
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+}
+
+type auditExport interface {
+	contentType() string
+	extension() string
+	begin() error
+	write(entry service.AuditEntry) error
+	flush() error
+}
+
+type csvAuditExport struct {
+	writer *csv.Writer
+}
+
+func (e *csvAuditExport) contentType() string { return "text/csv; charset=utf-8" }
+func (e *csvAuditExport) extension() string   { return "csv" }
+
+func (e *csvAuditExport) begin() error {
+	return e.writer.Write(auditCSVHeader)
+}
+
+func (e *csvAuditExport) write(entry service.AuditEntry) error {
+	return e.writer.Write([]string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		csvText(entry.Actor),
+		optionalCSV(entry.AccessTokenID),
+		csvText(entry.Action),
+		csvText(entry.Target),
+		optionalCSV(entry.BalanceBefore),
+		optionalCSV(entry.BalanceAfter),
+		csvText(entry.Details),
+		csvText(entry.IP),
+		csvText(entry.RequestID),
+	})
+}
+
+func (e *csvAuditExport) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonlAuditExport struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlAuditExport) contentType() string { return "application/x-ndjson" }
+func (e *jsonlAuditExport) extension() string   { return "jsonl" }
+func (e *jsonlAuditExport) begin() error        { return nil }
+func (e *jsonlAuditExport) flush() error        { return nil }
+
+func (e *jsonlAuditExport) write(entry service.AuditEntry) error {
+	return e.encoder.Encode(entry)
+}
+
+// csvText не даёт табличным редакторам принять текст за формулу: логины, объекты и
+// X-Request-ID задаёт клиент, и ячейка вида =HYPERLINK(...) сработала бы у администратора
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func optionalCSV[T uint | int](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
@@ -3,9 +3,12 @@ package handler
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"math"
+	"merch-api/httpx"
+	"merch-api/metrics"
 	"merch-api/service"
 	"net/http"
+	"strconv"
 )
 
 type AuthHandler struct {
@@ -19,34 +22,50 @@ func NewAuthHandler(svc service.AuthService) *AuthHandler {
 }
 
 func (h *AuthHandler) Authenticate(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-
 	var input struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": "invalid input"})
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureInvalidInput).Inc()
+		httpx.RespondError(c, http.StatusBadRequest, "invalid input")
 		return
 	}
 
-	token, err := h.service.AuthenticateUser(db, input.Username, input.Password)
+	token, err := h.service.AuthenticateUser(c.Request.Context(), service.LoginRequest{
+		Username: input.Username,
+		Password: input.Password,
+		ClientIP: c.ClientIP(),
+	})
 	if err != nil {
+		var throttled *service.TooManyAttemptsError
 		switch {
+		case errors.As(err, &throttled):
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureThrottled).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			httpx.RespondError(c, http.StatusTooManyRequests, "too many failed login attempts")
 		case errors.Is(err, service.ErrInvalidInput):
-			c.JSON(http.StatusBadRequest, gin.H{"errors": "invalid input"})
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureInvalidInput).Inc()
+			httpx.RespondError(c, http.StatusBadRequest, "invalid input")
 		case errors.Is(err, service.ErrPasswordMismatch):
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "invalid password"})
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailurePasswordMismatch).Inc()
+			httpx.RespondError(c, http.StatusUnauthorized, "invalid password")
+		case errors.Is(err, service.ErrEmployeeDeactivated):
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureDeactivated).Inc()
+			httpx.RespondError(c, http.StatusForbidden, "account deactivated")
+		case errors.Is(err, service.ErrExternalIdentityConflict):
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureIdentityConflict).Inc()
+			httpx.RespondError(c, http.StatusConflict, "username is taken by an account not linked to this provider")
 		case errors.Is(err, service.ErrFailedToCreateUser):
-			c.JSON(http.StatusInternalServerError, gin.H{"errors": "failed to find or create employee"})
+			httpx.RespondError(c, http.StatusInternalServerError, "failed to find or create employee")
 		case errors.Is(err, service.ErrFailedToGenerateToken):
-			c.JSON(http.StatusInternalServerError, gin.H{"errors": "failed to generate token"})
+			httpx.RespondError(c, http.StatusInternalServerError, "failed to generate token")
 		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"errors": "internal server error"})
+			httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	respondOK(c, gin.H{"token": token}, gin.H{"token": token})
 }
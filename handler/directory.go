@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net/http"
+	"strconv"
+)
+
+type DirectoryHandler struct {
+	service service.DirectoryService
+}
+
+func NewDirectoryHandler(svc service.DirectoryService) *DirectoryHandler {
+	return &DirectoryHandler{
+		service: svc,
+	}
+}
+
+func (h *DirectoryHandler) SearchEmployees(c *gin.Context) {
+	search := service.DirectorySearch{Query: c.Query("q")}
+	var ok bool
+	if search.Limit, ok = queryInt(c, "limit", service.DefaultDirectoryLimit); !ok {
+		httpx.RespondError(c, http.StatusBadRequest, "Некорректный параметр limit")
+		return
+	}
+	if search.Offset, ok = queryInt(c, "offset", 0); !ok {
+		httpx.RespondError(c, http.StatusBadRequest, "Некорректный параметр offset")
+		return
+	}
+
+	page, err := h.service.SearchEmployees(c.Request.Context(), search)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDirectorySearch) {
+			httpx.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	respondOK(c, page, page)
+}
+
+// queryInt возвращает def, если параметр не передан, и false, если он не является числом
+func queryInt(c *gin.Context, param string, def int) (int, bool) {
+	raw, ok := c.GetQuery(param)
+	if !ok {
+		return def, true
+	}
+	value, err := strconv.Atoi(raw)
+	return value, err == nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	db                       *gorm.DB
+	expectedMigrationVersion int64
+}
+
+func NewHealthHandler(db *gorm.DB, expectedMigrationVersion int64) *HealthHandler {
+	return &HealthHandler{
+		db:                       db,
+		expectedMigrationVersion: expectedMigrationVersion,
+	}
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Latency  string `json:"latency,omitempty"`
+	Current  *int64 `json:"current,omitempty"`
+	Expected *int64 `json:"expected,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	response := ReadinessResponse{
+		Status: "ready",
+		Checks: map[string]CheckResult{
+			"database":   h.checkDatabase(ctx),
+			"migrations": h.checkMigrations(ctx),
+		},
+	}
+
+	status := http.StatusOK
+	for _, check := range response.Checks {
+		if check.Status != "ok" {
+			response.Status = "not_ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	c.JSON(status, response)
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) CheckResult {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return CheckResult{Status: "fail", Error: err.Error()}
+	}
+
+	start := time.Now()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return CheckResult{Status: "fail", Error: err.Error()}
+	}
+
+	return CheckResult{Status: "ok", Latency: time.Since(start).String()}
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) CheckResult {
+	expected := h.expectedMigrationVersion
+	result := CheckResult{Status: "ok", Expected: &expected}
+
+	var migration struct {
+		Version int64
+		Dirty   bool
+	}
+	err := h.db.WithContext(ctx).
+		Table("schema_migrations").
+		Select("version, dirty").
+		Order("version DESC").
+		Limit(1).
+		Scan(&migration).Error
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Current = &migration.Version
+	switch {
+	case migration.Dirty:
+		result.Status = "fail"
+		result.Error = "последняя миграция применена не до конца"
+	case migration.Version != expected:
+		result.Status = "fail"
+		result.Error = "версия схемы БД не совпадает с ожидаемой"
+	}
+	return result
+}
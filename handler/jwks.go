@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"merch-api/service"
+	"net/http"
+)
+
+// jwksMaxAge — сколько другие сервисы могут кешировать ключи. Новые ключи публикуются
+// заранее, поэтому кеш не мешает ротации, если ActiveFrom назначен с запасом больше этого
+const jwksMaxAge = "public, max-age=300"
+
+type JWKSHandler struct {
+	keys *service.JWTKeys
+}
+
+func NewJWKSHandler(keys *service.JWTKeys) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// Keys отдаёт открытые ключи в стандартном формате JWKS, без конверта API
+func (h *JWKSHandler) Keys(c *gin.Context) {
+	c.Header("Cache-Control", jwksMaxAge)
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"merch-api/metrics"
+	"merch-api/service"
+	"net/http"
+	"time"
+)
+
+const (
+	oidcStateCookie    = "oidc_state"
+	oidcNonceCookie    = "oidc_nonce"
+	oidcVerifierCookie = "oidc_verifier"
+	// oidcCookieTTL ограничивает время, за которое нужно успеть войти у провайдера
+	oidcCookieTTL = 10 * time.Minute
+)
+
+// OIDCHandler хранит state, nonce и PKCE verifier между редиректом и callback'ом
+// в HttpOnly-cookie: так сервису не нужно своё хранилище незавершённых входов
+type OIDCHandler struct {
+	service       service.OIDCAuth
+	secureCookies bool
+}
+
+func NewOIDCHandler(svc service.OIDCAuth, secureCookies bool) *OIDCHandler {
+	return &OIDCHandler{
+		service:       svc,
+		secureCookies: secureCookies,
+	}
+}
+
+func (h *OIDCHandler) Login(c *gin.Context) {
+	login, err := h.service.BeginLogin(c.Request.Context())
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	// Lax, а не Strict: callback приходит переходом с домена провайдера, и Strict-cookie туда не попадут
+	c.SetSameSite(http.SameSiteLaxMode)
+	maxAge := int(oidcCookieTTL.Seconds())
+	c.SetCookie(oidcStateCookie, login.State, maxAge, "/", "", h.secureCookies, true)
+	c.SetCookie(oidcNonceCookie, login.Nonce, maxAge, "/", "", h.secureCookies, true)
+	c.SetCookie(oidcVerifierCookie, login.Verifier, maxAge, "/", "", h.secureCookies, true)
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	state, _ := c.Cookie(oidcStateCookie)
+	nonce, _ := c.Cookie(oidcNonceCookie)
+	verifier, _ := c.Cookie(oidcVerifierCookie)
+	h.clearCookies(c)
+
+	if c.Query("error") != "" {
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureSSORejected).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Провайдер SSO отклонил вход")
+		return
+	}
+	code := c.Query("code")
+	if code == "" || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureSSORejected).Inc()
+		httpx.RespondError(c, http.StatusBadRequest, "Вход через SSO устарел или начат в другом браузере, начните заново")
+		return
+	}
+
+	token, err := h.service.CompleteLogin(c.Request.Context(), code, verifier, nonce)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	respondOK(c, gin.H{"token": token}, gin.H{"token": token})
+}
+
+func (h *OIDCHandler) clearCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	for _, name := range []string{oidcStateCookie, oidcNonceCookie, oidcVerifierCookie} {
+		c.SetCookie(name, "", -1, "/", "", h.secureCookies, true)
+	}
+}
+
+func respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOIDCUnavailable):
+		httpx.RespondError(c, http.StatusServiceUnavailable, "Провайдер SSO недоступен")
+	case errors.Is(err, service.ErrInvalidOIDCLogin):
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureSSORejected).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Вход через SSO не подтверждён")
+	case errors.Is(err, service.ErrEmployeeDeactivated):
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureDeactivated).Inc()
+		httpx.RespondError(c, http.StatusForbidden, "account deactivated")
+	case errors.Is(err, service.ErrExternalIdentityConflict):
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureIdentityConflict).Inc()
+		httpx.RespondError(c, http.StatusConflict, "Логин занят учётной записью, не связанной с SSO, обратитесь к администратору")
+	default:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
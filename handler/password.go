@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net/http"
+)
+
+type PasswordHandler struct {
+	service service.PasswordService
+}
+
+func NewPasswordHandler(svc service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		service: svc,
+	}
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// ChangePassword отдаёт новый токен: все выданные ранее, включая текущий, после смены пароля отозваны
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	token, err := h.service.ChangePassword(c.Request.Context(), username, input.OldPassword, input.NewPassword)
+	if err != nil {
+		respondPasswordError(c, err)
+		return
+	}
+
+	respondOK(c, gin.H{"token": token}, gin.H{"token": token})
+}
+
+func (h *PasswordHandler) IssueReset(c *gin.Context) {
+	reset, err := h.service.IssueReset(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		respondPasswordError(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, reset, reset)
+}
+
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), input.Token, input.NewPassword); err != nil {
+		respondPasswordError(c, err)
+		return
+	}
+
+	message := gin.H{"message": "Пароль изменён"}
+	respondOK(c, message, message)
+}
+
+func respondPasswordError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPasswordMismatch):
+		httpx.RespondError(c, http.StatusForbidden, "Неверный текущий пароль")
+	case errors.Is(err, service.ErrInvalidResetToken):
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmployeeNotFound):
+		httpx.RespondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrEmployeeDeactivated):
+		httpx.RespondError(c, http.StatusConflict, err.Error())
+	default:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
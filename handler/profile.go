@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"merch-api/service"
+	"net/http"
+)
+
+type ProfileHandler struct {
+	service service.ProfileService
+}
+
+func NewProfileHandler(svc service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		service: svc,
+	}
+}
+
+type ProfileInput struct {
+	DisplayName *string `json:"displayName"`
+	Email       *string `json:"email"`
+	Department  *string `json:"department"`
+	Team        *string `json:"team"`
+	AvatarURL   *string `json:"avatarUrl"`
+}
+
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	profile, err := h.service.GetProfile(c.Request.Context(), username)
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	respondOK(c, profile, profile)
+}
+
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	var input ProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(c.Request.Context(), username, service.ProfileUpdate{
+		DisplayName: input.DisplayName,
+		Email:       input.Email,
+		Department:  input.Department,
+		Team:        input.Team,
+		AvatarURL:   input.AvatarURL,
+	})
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	respondOK(c, profile, profile)
+}
+
+func respondProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProfile):
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmployeeNotFound):
+		httpx.RespondError(c, http.StatusNotFound, err.Error())
+	default:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -1,8 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"merch-api/httpx"
 	"merch-api/service"
 	"net/http"
 )
@@ -20,35 +21,83 @@ func NewPurchaseHandler(svc service.PurchaseService) *PurchaseHandler {
 func (h *PurchaseHandler) BuyItem(c *gin.Context) {
 	username, exists := c.Get("username")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Неавторизован"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
 	usernameString, ok := username.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Некорректный username"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Некорректный username")
 		return
 	}
 
 	itemName := c.Param("item")
 
-	db, exists := c.Get("db")
+	message, err := h.service.PurchaseMerch(c.Request.Context(), usernameString, itemName)
+	if err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondOK(c, gin.H{"message": message}, gin.H{"message": message})
+}
+
+type PurchaseInput struct {
+	Item           string `json:"item" binding:"required"`
+	Quantity       int    `json:"quantity"`
+	IdempotencyKey string `json:"idempotencyKey"`
+}
+
+func (h *PurchaseHandler) CreatePurchase(c *gin.Context) {
+	username, exists := c.Get("username")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "БД недоступна"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
-	gdb, ok := db.(*gorm.DB)
+	usernameString, ok := username.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "Кривое подключение к БД"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Некорректный username")
+		return
+	}
+
+	var input PurchaseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+	if input.IdempotencyKey == "" {
+		input.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	}
 
-	message, err := h.service.PurchaseMerch(gdb, usernameString, itemName)
+	result, err := h.service.CreatePurchase(c.Request.Context(), service.PurchaseRequest{
+		Username:       usernameString,
+		ItemName:       input.Item,
+		Quantity:       input.Quantity,
+		IdempotencyKey: input.IdempotencyKey,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		switch {
+		case errors.Is(err, service.ErrMerchNotFound), errors.Is(err, service.ErrEmployeeNotFound):
+			httpx.RespondError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			httpx.RespondError(c, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrInsufficientFunds):
+			httpx.RespondError(c, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrInvalidQuantity), errors.Is(err, service.ErrInvalidIdempotencyKey):
+			httpx.RespondError(c, http.StatusBadRequest, err.Error())
+		default:
+			httpx.RespondError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": message})
+	status := http.StatusCreated
+	if result.Replayed {
+		status = http.StatusOK
+	}
+	respond(c, status, result, result)
 }
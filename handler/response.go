@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"net/http"
+)
+
+func respondOK(c *gin.Context, legacy interface{}, data interface{}) {
+	respond(c, http.StatusOK, legacy, data)
+}
+
+// respond в v1 отдаёт legacy как есть, в v2 заворачивает data в {"data": ...}
+func respond(c *gin.Context, status int, legacy interface{}, data interface{}) {
+	if httpx.APIVersion(c) == httpx.APIVersionV2 {
+		c.JSON(status, gin.H{"data": data})
+		return
+	}
+	c.JSON(status, legacy)
+}
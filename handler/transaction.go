@@ -2,7 +2,8 @@ package handler
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"merch-api/httpx"
+	"merch-api/metrics"
 	"merch-api/service"
 	"net/http"
 )
@@ -25,43 +26,29 @@ type TransactionInput struct {
 func (h *TransactionHandler) SendCoin(c *gin.Context) {
 	fromUsername, exists := c.Get("username")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Неавторизован"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
 	fromUsernameString, ok := fromUsername.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Некорректный username"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Некорректный username")
 		return
 	}
 	var input TransactionInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": "Неверный запрос"})
-		return
-	}
-	if input.ToUser == fromUsernameString {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": "Нельзя отправить монеты самому себе"})
-		return
-	}
-
-	db, exists := c.Get("db")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "БД недоступна"})
-		return
-	}
-
-	gdb, ok := db.(*gorm.DB)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "Кривое подключение к БД"})
+		metrics.FailedTransfersTotal.WithLabelValues(metrics.TransferFailureInvalidRequest).Inc()
+		httpx.RespondError(c, http.StatusBadRequest, "Неверный запрос")
 		return
 	}
 
-	message, err := h.service.SendCoins(gdb, fromUsernameString, input.ToUser, input.Amount)
+	// Причину отказа, в том числе перевод самому себе, в failed_transfers_total записывает сервис
+	message, err := h.service.SendCoins(c.Request.Context(), fromUsernameString, input.ToUser, input.Amount)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": message})
+	respondOK(c, gin.H{"message": message}, gin.H{"message": message})
 }
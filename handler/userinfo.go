@@ -2,7 +2,7 @@ package handler
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"merch-api/httpx"
 	"merch-api/service"
 	"net/http"
 )
@@ -20,33 +20,21 @@ func NewUserInfoHandler(svc service.UserInfoService) *UserInfoHandler {
 func (h *UserInfoHandler) InfoHandler(c *gin.Context) {
 	username, exists := c.Get("username")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Неавторизован"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
 	usernameString, ok := username.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"errors": "Некорректный username"})
+		httpx.RespondError(c, http.StatusUnauthorized, "Некорректный username")
 		return
 	}
 
-	db, exists := c.Get("db")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "БД недоступна"})
-		return
-	}
-
-	gdb, ok := db.(*gorm.DB)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"errors": "Кривое подключение к БД"})
-		return
-	}
-
-	userInfo, err := h.service.GetUserInfo(gdb, usernameString)
+	userInfo, err := h.service.GetUserInfo(c.Request.Context(), usernameString)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		httpx.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, userInfo)
+	respondOK(c, userInfo, userInfo)
 }
@@ -0,0 +1,74 @@
+// Package httpx содержит ответы HTTP API, общие для обработчиков и middleware.
+package httpx
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	APIVersionKey = "apiVersion"
+	APIVersionV1  = "v1"
+	APIVersionV2  = "v2"
+)
+
+const (
+	ErrRequestTimeout  = "превышено время обработки запроса"
+	ErrRequestCanceled = "запрос отменён"
+)
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIVersion возвращает версию API запроса; без неё запрос считается запросом к v1
+func APIVersion(c *gin.Context) string {
+	if version := c.GetString(APIVersionKey); version != "" {
+		return version
+	}
+	return APIVersionV1
+}
+
+func RespondError(c *gin.Context, status int, message string) {
+	// Ошибка, полученная после отмены контекста, скорее всего вызвана самой отменой
+	if c.Request != nil {
+		switch c.Request.Context().Err() {
+		case context.DeadlineExceeded:
+			status, message = http.StatusGatewayTimeout, ErrRequestTimeout
+		case context.Canceled:
+			status, message = http.StatusServiceUnavailable, ErrRequestCanceled
+		}
+	}
+	if APIVersion(c) == APIVersionV2 {
+		c.JSON(status, gin.H{"error": ErrorBody{Code: errorCode(status), Message: message}})
+		return
+	}
+	c.JSON(status, gin.H{"errors": message})
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable_entity"
+	case http.StatusTooManyRequests:
+		return "too_many_requests"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	default:
+		return "internal_error"
+	}
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const slowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger пишет SQL-логи GORM через slog вместо stdout
+type GormLogger struct {
+	logger *slog.Logger
+	level  gormlogger.LogLevel
+}
+
+func NewGormLogger(logger *slog.Logger, level string) *GormLogger {
+	return &GormLogger{logger: logger, level: ParseGormLevel(level)}
+}
+
+func ParseGormLevel(level string) gormlogger.LogLevel {
+	switch strings.ToLower(level) {
+	case "info":
+		return gormlogger.Info
+	case "warn", "warning":
+		return gormlogger.Warn
+	case "error":
+		return gormlogger.Error
+	default:
+		return gormlogger.Silent
+	}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "sql query failed",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+			slog.String("error", err.Error()),
+		)
+	case elapsed > slowQueryThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "slow sql query",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.logger.InfoContext(ctx, "sql query",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		)
+	}
+}
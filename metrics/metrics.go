@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "merch"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Длительность HTTP-запросов по маршрутам gin.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"method", "route", "status"})
+
+	PurchasesTotal = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_total",
+		Help:      "Количество купленных единиц мерча по товарам.",
+	}, []string{"item"})
+
+	CoinsTransferredTotal = promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_transferred_total",
+		Help:      "Сумма монет, переданных между сотрудниками.",
+	})
+
+	FailedTransfersTotal = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_transfers_total",
+		Help:      "Неудачные переводы монет по причинам.",
+	}, []string{"reason"})
+
+	AuthFailuresTotal = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Неудачные попытки аутентификации по причинам.",
+	}, []string{"reason"})
+)
+
+const (
+	TransferFailureSenderNotFound      = "sender_not_found"
+	TransferFailureReceiverNotFound    = "receiver_not_found"
+	TransferFailureInsufficientFunds   = "insufficient_funds"
+	TransferFailureSelfTransfer        = "self_transfer"
+	TransferFailureInvalidRequest      = "invalid_request"
+	TransferFailureDatabase            = "database_error"
+	TransferFailureSenderDeactivated   = "sender_deactivated"
+	TransferFailureReceiverDeactivated = "receiver_deactivated"
+
+	AuthFailureInvalidInput       = "invalid_input"
+	AuthFailurePasswordMismatch   = "password_mismatch"
+	AuthFailureMissingToken       = "missing_token"
+	AuthFailureMalformedToken     = "malformed_token"
+	AuthFailureInvalidToken       = "invalid_token"
+	AuthFailureInvalidClaims      = "invalid_claims"
+	AuthFailureDeactivated        = "deactivated"
+	AuthFailureUnknownEmployee    = "unknown_employee"
+	AuthFailureRevokedToken       = "revoked_token"
+	AuthFailureThrottled          = "throttled"
+	AuthFailureSSORejected        = "sso_rejected"
+	AuthFailureIdentityConflict   = "identity_conflict"
+	AuthFailureInvalidAccessToken = "invalid_access_token"
+)
+
+// RegisterDBStats публикует статистику пула соединений из sql.DB.Stats()
+func RegisterDBStats(db *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return nil
+	}
+	return err
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
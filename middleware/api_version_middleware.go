@@ -2,12 +2,12 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"merch-api/httpx"
 )
 
-func DatabaseMiddleware(db *gorm.DB) gin.HandlerFunc {
+func APIVersion(version string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Set("db", db)
+		c.Set(httpx.APIVersionKey, version)
 		c.Next()
 	}
 }
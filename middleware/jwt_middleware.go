@@ -1,54 +1,206 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"merch-api/httpx"
+	"merch-api/metrics"
+	"merch-api/model"
+	"merch-api/repository"
+	"merch-api/service"
 	"net/http"
-	"os"
 	"strings"
 )
 
-var JWTSecretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
+// PrincipalKey — ключ gin-контекста, под которым JWTMiddleware кладёт service.Principal;
+// тот же Principal доступен из контекста запроса через service.PrincipalFromContext
+const PrincipalKey = "principal"
 
-func JWTMiddleware() gin.HandlerFunc {
+// EmployeeFinder загружает сотрудника из токена, чтобы отсечь деактивированных и узнать роль
+type EmployeeFinder interface {
+	FindByUsername(ctx context.Context, username string) (*model.Employee, error)
+}
+
+// TokenParser проверяет подпись и стандартные поля токена
+type TokenParser interface {
+	ParseToken(tokenString string) (*service.Claims, error)
+}
+
+// AccessTokenAuthenticator находит сотрудника по токену доступа бота или интеграции
+type AccessTokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*model.Employee, *model.AccessToken, error)
+}
+
+// JWTMiddleware принимает JWT, а если accessTokens не nil — и токены доступа с префиксом
+// service.AccessTokenPrefix. Права сотрудника из токена проверяет RequireScope
+func JWTMiddleware(tokens TokenParser, employees EmployeeFinder, accessTokens AccessTokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Authorization token is required"})
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureMissingToken).Inc()
+			httpx.RespondError(c, http.StatusUnauthorized, "Authorization token is required")
 			c.Abort()
 			return
 		}
 
 		tokenParts := strings.Split(tokenString, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Кривой формат токена"})
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || tokenParts[1] == "" {
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureMalformedToken).Inc()
+			httpx.RespondError(c, http.StatusUnauthorized, "Кривой формат токена")
 			c.Abort()
 			return
 		}
 
-		token, err := jwt.Parse(tokenParts[1], func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return JWTSecretKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Invalid or expired token"})
+		var principal service.Principal
+		var ok bool
+		if accessTokens != nil && strings.HasPrefix(tokenParts[1], service.AccessTokenPrefix) {
+			principal, ok = authenticateAccessToken(c, accessTokens, tokenParts[1])
+		} else {
+			principal, ok = authenticateJWT(c, tokens, employees, tokenParts[1])
+		}
+		if !ok {
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			username := claims["username"].(string)
-			c.Set("username", username)
+		c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), principal))
+		c.Set(PrincipalKey, principal)
+		c.Set("username", principal.Username)
+
+		c.Next()
+	}
+}
+
+func authenticateJWT(c *gin.Context, tokens TokenParser, employees EmployeeFinder, tokenString string) (service.Principal, bool) {
+	claims, err := tokens.ParseToken(tokenString)
+	if err != nil {
+		if invalidClaims(err) {
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureInvalidClaims).Inc()
+			httpx.RespondError(c, http.StatusUnauthorized, "Invalid token claims")
 		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Invalid token claims"})
+			metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureInvalidToken).Inc()
+			httpx.RespondError(c, http.StatusUnauthorized, "Invalid or expired token")
+		}
+		return service.Principal{}, false
+	}
+
+	employee, err := employees.FindByUsername(c.Request.Context(), claims.Username)
+	if !checkEmployee(c, employee, err) {
+		return service.Principal{}, false
+	}
+
+	// Токены, выданные до смены пароля, несут старую версию
+	if claims.TokenVersion != employee.TokenVersion {
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureRevokedToken).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Токен отозван, войдите заново")
+		return service.Principal{}, false
+	}
+
+	return service.Principal{
+		EmployeeID: employee.ID,
+		Username:   employee.Username,
+		Roles:      []string{employee.Role},
+		Scopes:     service.EffectiveScopes(employee.Role, claims.ScopeList(employee.Role)),
+	}, true
+}
+
+// authenticateAccessToken не сверяет TokenVersion: смена пароля не должна ломать интеграции,
+// ненужный токен сотрудник отзывает сам
+func authenticateAccessToken(c *gin.Context, accessTokens AccessTokenAuthenticator, tokenString string) (service.Principal, bool) {
+	employee, accessToken, err := accessTokens.AuthenticateToken(c.Request.Context(), tokenString)
+	if errors.Is(err, service.ErrInvalidAccessToken) {
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureInvalidAccessToken).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Токен доступа недействителен, отозван или истёк")
+		return service.Principal{}, false
+	}
+	if !checkEmployee(c, employee, err) {
+		return service.Principal{}, false
+	}
+
+	return service.Principal{
+		EmployeeID:    employee.ID,
+		Username:      employee.Username,
+		Roles:         []string{employee.Role},
+		Scopes:        service.EffectiveScopes(employee.Role, accessToken.ScopeList()),
+		AccessTokenID: accessToken.ID,
+	}, true
+}
+
+// checkEmployee отвечает клиенту и возвращает false, если сотрудника нет или он деактивирован
+func checkEmployee(c *gin.Context, employee *model.Employee, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureUnknownEmployee).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Пользователь не найден")
+		return false
+	case err != nil:
+		httpx.RespondError(c, http.StatusInternalServerError, "internal server error")
+		return false
+	case !employee.Active():
+		metrics.AuthFailuresTotal.WithLabelValues(metrics.AuthFailureDeactivated).Inc()
+		httpx.RespondError(c, http.StatusUnauthorized, "Учётная запись деактивирована")
+		return false
+	}
+	return true
+}
+
+// invalidClaims отделяет подписанные, но непригодные для нас токены от поддельных и истёкших
+func invalidClaims(err error) bool {
+	for _, target := range []error{
+		service.ErrInvalidTokenClaims,
+		jwt.ErrTokenInvalidIssuer,
+		jwt.ErrTokenInvalidAudience,
+		jwt.ErrTokenNotValidYet,
+		jwt.ErrTokenUsedBeforeIssued,
+		jwt.ErrTokenRequiredClaimMissing,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentPrincipal возвращает сотрудника, которого аутентифицировал JWTMiddleware
+func CurrentPrincipal(c *gin.Context) (service.Principal, bool) {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return service.Principal{}, false
+	}
+	principal, ok := value.(service.Principal)
+	return principal, ok
+}
+
+// RequireScope пропускает запрос, только если у сотрудника есть все перечисленные права,
+// и в ответе 403 перечисляет недостающие. Ставится на группу маршрутов после JWTMiddleware
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := CurrentPrincipal(c)
+		if !ok {
+			httpx.RespondError(c, http.StatusForbidden, "Недостаточно прав")
+			c.Abort()
+			return
+		}
+		if missing := principal.MissingScopes(scopes...); len(missing) > 0 {
+			httpx.RespondError(c, http.StatusForbidden, "Недостаточно прав, нужно: "+strings.Join(missing, ", "))
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
+// RejectAccessTokens закрывает маршрут для токенов доступа: управление учётной записью,
+// справочник и администрирование доступны только самому сотруднику
+func RejectAccessTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := CurrentPrincipal(c); ok && principal.ViaAccessToken() {
+			httpx.RespondError(c, http.StatusForbidden, "Маршрут недоступен для токенов доступа")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
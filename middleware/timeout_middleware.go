@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"merch-api/httpx"
+	"net/http"
+	"time"
+)
+
+// Timeout ограничивает время обработки запроса: контекст c.Request отменяется по истечении timeout,
+// а если обработчик так ничего и не ответил, клиент получает 504 (или 503, если запрос отменён).
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if c.Writer.Written() || ctx.Err() == nil {
+			return
+		}
+		// RespondError сам выберет 504 или 503 по причине отмены контекста
+		httpx.RespondError(c, http.StatusServiceUnavailable, httpx.ErrRequestCanceled)
+	}
+}
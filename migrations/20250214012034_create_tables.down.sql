@@ -1,7 +1,7 @@
-DROP TABLE employee;
-DROP TABLE merch;
 DROP TABLE purchase;
 DROP TABLE transaction;
+DROP TABLE employee;
+DROP TABLE merch;
 
 DROP SEQUENCE merch_id_seq;
 DROP SEQUENCE purchase_id_seq;
@@ -0,0 +1,2 @@
+DELETE FROM merch
+WHERE name IN ('t-shirt', 'cup', 'book', 'pen', 'powerbank', 'hoody', 'umbrella', 'socks', 'wallet', 'pink-hoody');
@@ -1 +0,0 @@
-TRUNCATE TABLE merch;
\ No newline at end of file
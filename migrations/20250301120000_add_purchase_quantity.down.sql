@@ -0,0 +1,6 @@
+DROP INDEX idx_unique_purchase_employee_idempotency_key;
+
+ALTER TABLE purchase
+    DROP COLUMN idempotency_key;
+ALTER TABLE purchase
+    DROP COLUMN quantity;
@@ -0,0 +1,6 @@
+ALTER TABLE purchase
+    ADD COLUMN quantity INT NOT NULL DEFAULT 1;
+ALTER TABLE purchase
+    ADD COLUMN idempotency_key VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_unique_purchase_employee_idempotency_key ON purchase (employee_id, idempotency_key);
@@ -0,0 +1,9 @@
+ALTER TABLE transaction
+    DROP CONSTRAINT chk_transaction_amount_positive;
+
+ALTER TABLE employee
+    DROP CONSTRAINT chk_employee_balance_non_negative;
+ALTER TABLE employee
+    DROP CONSTRAINT uq_employee_username;
+
+CREATE INDEX idx_unique_employee_username ON employee (username);
@@ -0,0 +1,9 @@
+DROP INDEX idx_unique_employee_username;
+
+ALTER TABLE employee
+    ADD CONSTRAINT uq_employee_username UNIQUE (username);
+ALTER TABLE employee
+    ADD CONSTRAINT chk_employee_balance_non_negative CHECK (balance >= 0);
+
+ALTER TABLE transaction
+    ADD CONSTRAINT chk_transaction_amount_positive CHECK (amount > 0);
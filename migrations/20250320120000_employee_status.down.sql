@@ -0,0 +1,7 @@
+ALTER TABLE employee
+    DROP CONSTRAINT chk_employee_role;
+
+ALTER TABLE employee
+    DROP COLUMN deactivated_at;
+ALTER TABLE employee
+    DROP COLUMN role;
@@ -0,0 +1,7 @@
+ALTER TABLE employee
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'employee';
+ALTER TABLE employee
+    ADD COLUMN deactivated_at timestamp;
+
+ALTER TABLE employee
+    ADD CONSTRAINT chk_employee_role CHECK (role IN ('employee', 'admin'));
@@ -0,0 +1,10 @@
+ALTER TABLE employee
+    DROP COLUMN avatar_url;
+ALTER TABLE employee
+    DROP COLUMN team;
+ALTER TABLE employee
+    DROP COLUMN department;
+ALTER TABLE employee
+    DROP COLUMN email;
+ALTER TABLE employee
+    DROP COLUMN display_name;
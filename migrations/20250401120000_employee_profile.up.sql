@@ -0,0 +1,10 @@
+ALTER TABLE employee
+    ADD COLUMN display_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE employee
+    ADD COLUMN email VARCHAR(254) NOT NULL DEFAULT '';
+ALTER TABLE employee
+    ADD COLUMN department VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE employee
+    ADD COLUMN team VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE employee
+    ADD COLUMN avatar_url VARCHAR(512) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX idx_employee_display_name_trgm;
+DROP INDEX idx_employee_username_trgm;
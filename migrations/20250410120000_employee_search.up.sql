@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_employee_username_trgm ON employee USING gin (lower(username) gin_trgm_ops);
+CREATE INDEX idx_employee_display_name_trgm ON employee USING gin (lower(display_name) gin_trgm_ops);
@@ -0,0 +1,4 @@
+DROP TABLE password_reset_token;
+
+ALTER TABLE employee
+    DROP COLUMN token_version;
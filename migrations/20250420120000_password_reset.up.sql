@@ -0,0 +1,18 @@
+ALTER TABLE employee
+    ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+
+CREATE TABLE password_reset_token
+(
+    id          SERIAL PRIMARY KEY,
+    employee_id INT         NOT NULL,
+    token_hash  VARCHAR(64) NOT NULL,
+    expires_at  timestamp   NOT NULL,
+    used_at     timestamp,
+    created_at  timestamp DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uq_password_reset_token_hash ON password_reset_token (token_hash);
+CREATE INDEX idx_password_reset_token_employee_id ON password_reset_token (employee_id);
+
+ALTER TABLE password_reset_token
+    ADD CONSTRAINT fk_password_reset_token_employee_id_employee_id FOREIGN KEY (employee_id) REFERENCES employee (id) NOT DEFERRABLE INITIALLY IMMEDIATE;
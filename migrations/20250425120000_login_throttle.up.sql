@@ -0,0 +1,7 @@
+CREATE TABLE login_throttle
+(
+    throttle_key    VARCHAR(160) PRIMARY KEY,
+    failures        INT       NOT NULL,
+    last_failure_at timestamp NOT NULL,
+    locked_until    timestamp
+);
@@ -0,0 +1,19 @@
+CREATE TABLE access_token
+(
+    id           SERIAL PRIMARY KEY,
+    employee_id  INT          NOT NULL,
+    name         VARCHAR(64)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    scopes       VARCHAR(255) NOT NULL,
+    expires_at   timestamp,
+    last_used_at timestamp,
+    revoked_at   timestamp,
+    created_at   timestamp DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uq_access_token_hash ON access_token (token_hash);
+CREATE INDEX idx_access_token_employee_id ON access_token (employee_id);
+
+ALTER TABLE access_token
+    ADD CONSTRAINT fk_access_token_employee_id_employee_id FOREIGN KEY (employee_id) REFERENCES employee (id) NOT DEFERRABLE INITIALLY IMMEDIATE;
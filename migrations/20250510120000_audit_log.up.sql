@@ -0,0 +1,31 @@
+CREATE TABLE audit_log
+(
+    id              BIGSERIAL PRIMARY KEY,
+    actor           VARCHAR(64)  NOT NULL,
+    access_token_id INT,
+    action          VARCHAR(64)  NOT NULL,
+    target          VARCHAR(255) NOT NULL,
+    balance_before  INT,
+    balance_after   INT,
+    details         VARCHAR(255) NOT NULL,
+    ip              VARCHAR(45)  NOT NULL,
+    request_id      VARCHAR(128) NOT NULL,
+    created_at      timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor, id);
+CREATE INDEX idx_audit_log_target ON audit_log (target, id);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log: записи журнала аудита нельзя изменять или удалять';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,6 @@
+DROP INDEX uq_employee_auth_identity;
+
+ALTER TABLE employee
+    DROP COLUMN auth_subject;
+ALTER TABLE employee
+    DROP COLUMN auth_provider;
@@ -0,0 +1,6 @@
+ALTER TABLE employee
+    ADD COLUMN auth_provider VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE employee
+    ADD COLUMN auth_subject VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX uq_employee_auth_identity ON employee (auth_provider, auth_subject) WHERE auth_provider <> '';
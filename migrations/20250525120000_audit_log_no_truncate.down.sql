@@ -0,0 +1 @@
+DROP TRIGGER trg_audit_log_no_truncate ON audit_log;
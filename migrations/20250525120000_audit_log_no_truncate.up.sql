@@ -0,0 +1,5 @@
+CREATE TRIGGER trg_audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON audit_log
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_log_append_only();
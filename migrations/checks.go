@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxViolationRows ограничивает число примеров в отчёте по одной проверке
+const maxViolationRows = 20
+
+// Check — запрос, находящий данные, на которых миграция упадёт.
+// Каждая строка результата — одна текстовая колонка с описанием нарушения.
+type Check struct {
+	Description string
+	Query       string
+}
+
+// preflightChecks выполняются перед применением миграции с указанной версией
+var preflightChecks = map[int64][]Check{
+	20250310120000: {
+		{
+			Description: "повторяющиеся имена сотрудников",
+			Query:       "SELECT username || ' (' || COUNT(*) || ' записей)' FROM employee GROUP BY username HAVING COUNT(*) > 1 ORDER BY username",
+		},
+		{
+			Description: "сотрудники с отрицательным балансом",
+			Query:       "SELECT username || ': ' || balance FROM employee WHERE balance < 0 ORDER BY id",
+		},
+		{
+			Description: "переводы с неположительной суммой",
+			Query:       "SELECT 'id ' || id || ': ' || amount FROM transaction WHERE amount <= 0 ORDER BY id",
+		},
+	},
+}
+
+type Violation struct {
+	Check Check
+	Rows  []string
+}
+
+// ViolationsError возвращается, когда данные не позволяют применить миграцию
+type ViolationsError struct {
+	Migration  Migration
+	Violations []Violation
+}
+
+func (e *ViolationsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "данные нарушают ограничения миграции %s, исправьте их и повторите:", e.Migration)
+	for _, violation := range e.Violations {
+		fmt.Fprintf(&b, "\n  %s:", violation.Check.Description)
+		for _, row := range violation.Rows {
+			fmt.Fprintf(&b, "\n    %s", row)
+		}
+		if len(violation.Rows) == maxViolationRows {
+			b.WriteString("\n    ...")
+		}
+	}
+	return b.String()
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// preflight выполняет проверки миграции и возвращает *ViolationsError, если что-то нашлось
+func preflight(ctx context.Context, db queryer, migration Migration) error {
+	var violations []Violation
+	for _, check := range preflightChecks[migration.Version] {
+		rows, err := queryRows(ctx, db, check.Query)
+		if err != nil {
+			return fmt.Errorf("проверка %q: %w", check.Description, err)
+		}
+		if len(rows) > 0 {
+			violations = append(violations, Violation{Check: check, Rows: rows})
+		}
+	}
+	if len(violations) > 0 {
+		return &ViolationsError{Migration: migration, Violations: violations}
+	}
+	return nil
+}
+
+func queryRows(ctx context.Context, db queryer, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("%s LIMIT %d", query, maxViolationRows))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,311 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// advisoryLockID не даёт нескольким экземплярам сервиса накатывать миграции одновременно
+const advisoryLockID = 20250214
+
+// legacyBaseline — версии, схему которых раньше создавал migrations/init.sql, смонтированный
+// в docker-compose. В таких БД нет schema_migrations, и Up не должен выполнять их повторно
+var legacyBaseline = []int64{20250214012034, 20250214012839}
+
+var ErrDirty = errors.New("предыдущая миграция не завершилась, исправьте схему вручную и снимите флаг dirty в schema_migrations")
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+	// Missing — версия записана в БД, но файла миграции нет
+	Missing bool
+}
+
+type applied struct {
+	dirty     bool
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up применяет все ещё не применённые миграции по возрастанию версии
+func (m *Migrator) Up(ctx context.Context) (done []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn, state map[int64]applied) error {
+		if len(state) == 0 {
+			if err := m.adoptLegacySchema(ctx, conn, state); err != nil {
+				return err
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := state[migration.Version]; ok {
+				continue
+			}
+			if err := preflight(ctx, conn, migration); err != nil {
+				return err
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return fmt.Errorf("миграция %s: %w", migration, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// adoptLegacySchema записывает версии init.sql применёнными без выполнения, если таблицы уже есть,
+// а версий ещё нет: иначе первая миграция упадёт на существующих последовательностях и таблицах
+func (m *Migrator) adoptLegacySchema(ctx context.Context, conn *sql.Conn, state map[int64]applied) error {
+	for _, version := range legacyBaseline {
+		if !m.has(version) {
+			return nil
+		}
+	}
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('employee') IS NOT NULL").Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, version := range legacyBaseline {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("не удалось записать версию %d существующей схемы: %w", version, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, version := range legacyBaseline {
+		state[version] = applied{}
+		slog.InfoContext(ctx, "existing schema adopted without running migration", slog.Int64("version", version))
+	}
+	return nil
+}
+
+func (m *Migrator) has(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Check выполняет проверки данных для всех ожидающих миграций, ничего не меняя,
+// и возвращает отчёт о нарушениях, из-за которых up остановится
+func (m *Migrator) Check(ctx context.Context) ([]*ViolationsError, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int64]bool)
+	for _, status := range statuses {
+		if !status.Applied {
+			pending[status.Version] = true
+		}
+	}
+
+	var reports []*ViolationsError
+	for _, migration := range m.migrations {
+		if !pending[migration.Version] {
+			continue
+		}
+		err := preflight(ctx, m.db, migration)
+		var violations *ViolationsError
+		if errors.As(err, &violations) {
+			reports = append(reports, violations)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(ctx context.Context, steps int) (done []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn, state map[int64]applied) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := state[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("для миграции %s нет down-файла", migration)
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return fmt.Errorf("откат %s: %w", migration, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	state, err := readApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := state[migration.Version]; ok {
+			status.Applied = true
+			status.Dirty = a.dirty
+			status.AppliedAt = &a.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, a := range state {
+		if known[version] {
+			continue
+		}
+		appliedAt := a.appliedAt
+		statuses = append(statuses, Status{Version: version, Applied: true, Dirty: a.dirty, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// locked выполняет fn на одном соединении под advisory-блокировкой и отказывается работать с dirty-версией
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, state map[int64]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return fmt.Errorf("не удалось взять блокировку миграций: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	state, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for version, a := range state {
+		if a.dirty {
+			return fmt.Errorf("%w (версия %d)", ErrDirty, version)
+		}
+	}
+	return fn(conn, state)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	script, record := migration.Up, "INSERT INTO schema_migrations (version) VALUES ($1)"
+	if !up {
+		script, record = migration.Down, "DELETE FROM schema_migrations WHERE version = $1"
+	}
+
+	if inTransaction(script) {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, record, migration.Version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// Без транзакции версия помечается dirty до выполнения, чтобы сбой посередине был виден
+	mark := "INSERT INTO schema_migrations (version, dirty) VALUES ($1, true)"
+	if !up {
+		mark = "UPDATE schema_migrations SET dirty = true WHERE version = $1"
+	}
+	if _, err := conn.ExecContext(ctx, mark, migration.Version); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	finish := "UPDATE schema_migrations SET dirty = false WHERE version = $1"
+	if !up {
+		finish = record
+	}
+	_, err := conn.ExecContext(ctx, finish, migration.Version)
+	return err
+}
+
+func ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    BIGINT PRIMARY KEY,
+    dirty      BOOLEAN   NOT NULL DEFAULT false,
+    applied_at timestamp NOT NULL DEFAULT now()
+)`)
+	return err
+}
+
+func readApplied(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, dirty, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := make(map[int64]applied)
+	for rows.Next() {
+		var version int64
+		var a applied
+		if err := rows.Scan(&version, &a.dirty, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		state[version] = a
+	}
+	return state, rows.Err()
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// AccessToken — долгоживущий токен доступа, который сотрудник выпускает для ботов и интеграций.
+// Токен действует только в пределах Scopes; хранится только SHA-256 от него, а Prefix —
+// начало токена, по которому его можно узнать в списке
+type AccessToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	EmployeeID uint   `gorm:"not null"`
+	Name       string `gorm:"size:64;not null"`
+	TokenHash  string `gorm:"size:64;unique;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	// Scopes — права через пробел, как scope в OAuth 2.0
+	Scopes     string     `gorm:"size:255;not null"`
+	ExpiresAt  *time.Time `gorm:"default:null"`
+	LastUsedAt *time.Time `gorm:"default:null"`
+	RevokedAt  *time.Time `gorm:"default:null"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (t AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t AccessToken) Usable(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+func (AccessToken) TableName() string {
+	return "access_token"
+}
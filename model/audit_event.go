@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// AuditEvent — запись журнала аудита. Журнал только пополняется: изменить или удалить
+// запись не даёт триггер в БД
+type AuditEvent struct {
+	ID uint `gorm:"primaryKey"`
+	// Actor — логин того, кто выполнил действие; пусто, если запрос анонимный
+	Actor string `gorm:"size:64;not null"`
+	// AccessTokenID заполняется, если действие выполнил бот по токену доступа
+	AccessTokenID *uint  `gorm:"default:null"`
+	Action        string `gorm:"size:64;not null"`
+	// Target — над чем выполнено действие: логин, товар, IP-адрес или id токена
+	Target string `gorm:"size:255;not null"`
+	// BalanceBefore и BalanceAfter — баланс сотрудника, чей счёт изменило действие
+	BalanceBefore *int      `gorm:"default:null"`
+	BalanceAfter  *int      `gorm:"default:null"`
+	Details       string    `gorm:"size:255;not null"`
+	IP            string    `gorm:"size:45;not null"`
+	RequestID     string    `gorm:"size:128;not null"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// LoginThrottle — счётчик неудачных входов подряд для одного ключа (логина или IP-адреса)
+type LoginThrottle struct {
+	ThrottleKey   string     `gorm:"primaryKey;size:160"`
+	Failures      int        `gorm:"not null"`
+	LastFailureAt time.Time  `gorm:"not null"`
+	LockedUntil   *time.Time `gorm:"default:null"`
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttle"
+}
@@ -13,10 +13,12 @@ func (Merch) TableName() string {
 }
 
 type Purchase struct {
-	ID         uint      `gorm:"primaryKey"`
-	EmployeeID uint      `gorm:"not null"`
-	MerchID    uint      `gorm:"not null"`
-	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ID             uint      `gorm:"primaryKey"`
+	EmployeeID     uint      `gorm:"not null"`
+	MerchID        uint      `gorm:"not null"`
+	Quantity       int       `gorm:"not null;default:1"`
+	IdempotencyKey *string   `gorm:"size:64;default:null"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
 
 func (Purchase) TableName() string {
@@ -35,11 +37,47 @@ func (Transaction) TableName() string {
 	return "transaction"
 }
 
+const (
+	RoleEmployee = "employee"
+	RoleAdmin    = "admin"
+)
+
 type Employee struct {
 	ID       uint   `gorm:"primaryKey"`
 	Username string `gorm:"unique;not null"`
 	Password string `gorm:"not null"`
 	Balance  int    `gorm:"not null"`
+	Role     string `gorm:"not null;default:employee"`
+	// DeactivatedAt заполняется, когда сотрудник уходит из компании; такой сотрудник
+	// не может войти, пользоваться выданными токенами и получать монеты
+	DeactivatedAt *time.Time `gorm:"default:null"`
+	// TokenVersion увеличивается при смене пароля; токены с другой версией больше не принимаются
+	TokenVersion int `gorm:"not null;default:0"`
+	// AuthProvider и AuthSubject связывают сотрудника с учётной записью внешнего провайдера
+	// входа (iss и sub для OIDC, ldap и DN для LDAP); у локальных сотрудников они пустые
+	AuthProvider string          `gorm:"size:255;not null;default:''"`
+	AuthSubject  string          `gorm:"size:255;not null;default:''"`
+	Profile      EmployeeProfile `gorm:"embedded"`
+}
+
+type EmployeeProfile struct {
+	DisplayName string `gorm:"size:64;not null;default:''"`
+	Email       string `gorm:"size:254;not null;default:''"`
+	Department  string `gorm:"size:64;not null;default:''"`
+	Team        string `gorm:"size:64;not null;default:''"`
+	AvatarURL   string `gorm:"size:512;not null;default:''"`
+}
+
+// Name возвращает отображаемое имя сотрудника, а если оно не задано — логин
+func (e Employee) Name() string {
+	if e.Profile.DisplayName != "" {
+		return e.Profile.DisplayName
+	}
+	return e.Username
+}
+
+func (e Employee) Active() bool {
+	return e.DeactivatedAt == nil
 }
 
 func (Employee) TableName() string {
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// PasswordResetToken — одноразовый токен сброса пароля, выданный администратором.
+// Хранится только SHA-256 от токена, сам токен видит лишь тот, кто его запросил.
+type PasswordResetToken struct {
+	ID         uint       `gorm:"primaryKey"`
+	EmployeeID uint       `gorm:"not null"`
+	TokenHash  string     `gorm:"size:64;unique;not null"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	UsedAt     *time.Time `gorm:"default:null"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (t PasswordResetToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
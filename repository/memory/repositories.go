@@ -0,0 +1,459 @@
+package memory
+
+import (
+	"context"
+	"merch-api/model"
+	"merch-api/repository"
+	"sort"
+	"strings"
+	"time"
+)
+
+type employeeRepository struct {
+	store *Store
+}
+
+func (r *employeeRepository) FindByUsername(_ context.Context, username string) (*model.Employee, error) {
+	defer r.store.lock()()
+	for _, employee := range r.store.data.employees {
+		if employee.Username == username {
+			return &employee, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *employeeRepository) FindByUsernameForUpdate(ctx context.Context, username string) (*model.Employee, error) {
+	return r.FindByUsername(ctx, username)
+}
+
+func (r *employeeRepository) FindByID(_ context.Context, id uint) (*model.Employee, error) {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return nil, repository.ErrNotFound
+	}
+	found := *employee
+	return &found, nil
+}
+
+func (r *employeeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*model.Employee, error) {
+	return r.FindByID(ctx, id)
+}
+
+func (r *employeeRepository) FindByExternalIdentity(_ context.Context, provider, subject string) (*model.Employee, error) {
+	defer r.store.lock()()
+	for _, employee := range r.store.data.employees {
+		if employee.AuthProvider == provider && employee.AuthSubject == subject {
+			return &employee, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *employeeRepository) LinkExternalIdentity(_ context.Context, id uint, provider, subject string) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil || employee.AuthProvider != "" || employee.Password != "" {
+		return repository.ErrNotFound
+	}
+	employee.AuthProvider, employee.AuthSubject = provider, subject
+	return nil
+}
+
+func (r *employeeRepository) FirstOrCreate(ctx context.Context, employee *model.Employee) (*model.Employee, error) {
+	if found, err := r.FindByUsername(ctx, employee.Username); err == nil {
+		return found, nil
+	}
+	created := r.store.AddEmployee(*employee)
+	return &created, nil
+}
+
+func (r *employeeRepository) UpdateBalance(_ context.Context, id uint, balance int) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return repository.ErrNotFound
+	}
+	employee.Balance = balance
+	return nil
+}
+
+func (r *employeeRepository) SetDeactivatedAt(_ context.Context, id uint, deactivatedAt *time.Time) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return repository.ErrNotFound
+	}
+	employee.DeactivatedAt = deactivatedAt
+	return nil
+}
+
+func (r *employeeRepository) UpdateProfile(_ context.Context, id uint, profile model.EmployeeProfile) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return repository.ErrNotFound
+	}
+	employee.Profile = profile
+	return nil
+}
+
+func (r *employeeRepository) UpdatePassword(_ context.Context, id uint, passwordHash string) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return repository.ErrNotFound
+	}
+	employee.Password = passwordHash
+	employee.TokenVersion++
+	return nil
+}
+
+func (r *employeeRepository) ReplacePasswordHash(_ context.Context, id uint, oldHash, newHash string) error {
+	defer r.store.lock()()
+	employee := r.store.employeeByID(id)
+	if employee == nil {
+		return repository.ErrNotFound
+	}
+	if employee.Password == oldHash {
+		employee.Password = newHash
+	}
+	return nil
+}
+
+type merchRepository struct {
+	store *Store
+}
+
+func (r *merchRepository) FindByName(_ context.Context, name string) (*model.Merch, error) {
+	defer r.store.lock()()
+	for _, merch := range r.store.data.merch {
+		if merch.Name == name {
+			return &merch, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+type purchaseRepository struct {
+	store *Store
+}
+
+func (r *purchaseRepository) Create(_ context.Context, purchase *model.Purchase) error {
+	defer r.store.lock()()
+	purchase.ID = uint(len(r.store.data.purchases) + 1)
+	if purchase.Quantity == 0 {
+		purchase.Quantity = 1
+	}
+	if purchase.CreatedAt.IsZero() {
+		purchase.CreatedAt = r.store.Now()
+	}
+	r.store.data.purchases = append(r.store.data.purchases, *purchase)
+	return nil
+}
+
+func (r *purchaseRepository) FindByIdempotencyKey(_ context.Context, employeeID uint, key string) (*model.Purchase, error) {
+	defer r.store.lock()()
+	for _, purchase := range r.store.data.purchases {
+		if purchase.EmployeeID == employeeID && purchase.IdempotencyKey != nil && *purchase.IdempotencyKey == key {
+			return &purchase, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *purchaseRepository) InventoryByEmployee(_ context.Context, employeeID uint) ([]repository.InventoryRow, error) {
+	defer r.store.lock()()
+	var rows []repository.InventoryRow
+	index := make(map[uint]int)
+	for _, purchase := range r.store.data.purchases {
+		if purchase.EmployeeID != employeeID {
+			continue
+		}
+		if i, ok := index[purchase.MerchID]; ok {
+			rows[i].Quantity += purchase.Quantity
+			continue
+		}
+		var name string
+		for _, merch := range r.store.data.merch {
+			if merch.ID == purchase.MerchID {
+				name = merch.Name
+			}
+		}
+		index[purchase.MerchID] = len(rows)
+		rows = append(rows, repository.InventoryRow{Type: name, Quantity: purchase.Quantity})
+	}
+	return rows, nil
+}
+
+type transactionRepository struct {
+	store *Store
+}
+
+func (r *transactionRepository) Create(_ context.Context, transaction *model.Transaction) error {
+	defer r.store.lock()()
+	transaction.ID = uint(len(r.store.data.transactions) + 1)
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = r.store.Now()
+	}
+	r.store.data.transactions = append(r.store.data.transactions, *transaction)
+	return nil
+}
+
+func (r *transactionRepository) SentBy(_ context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	defer r.store.lock()()
+	var rows []repository.CoinTransfer
+	for _, transaction := range r.store.data.transactions {
+		if transaction.SenderID != employeeID {
+			continue
+		}
+		if receiver := r.store.employeeByID(transaction.ReceiverID); receiver != nil {
+			rows = append(rows, repository.CoinTransfer{Username: receiver.Username, DisplayName: receiver.Profile.DisplayName, Amount: transaction.Amount})
+		}
+	}
+	return rows, nil
+}
+
+func (r *transactionRepository) ReceivedBy(_ context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	defer r.store.lock()()
+	var rows []repository.CoinTransfer
+	for _, transaction := range r.store.data.transactions {
+		if transaction.ReceiverID != employeeID {
+			continue
+		}
+		if sender := r.store.employeeByID(transaction.SenderID); sender != nil {
+			rows = append(rows, repository.CoinTransfer{Username: sender.Username, DisplayName: sender.Profile.DisplayName, Amount: transaction.Amount})
+		}
+	}
+	return rows, nil
+}
+
+// Search в памяти приближает поиск Postgres: сначала совпадения по началу, затем по вхождению подстроки
+func (r *employeeRepository) Search(_ context.Context, search repository.EmployeeSearch) ([]model.Employee, int64, error) {
+	defer r.store.lock()()
+	q := strings.ToLower(search.Query)
+
+	type match struct {
+		employee model.Employee
+		prefix   bool
+	}
+	var matches []match
+	for _, employee := range r.store.data.employees {
+		if !employee.Active() {
+			continue
+		}
+		username, name := strings.ToLower(employee.Username), strings.ToLower(employee.Profile.DisplayName)
+		prefix := strings.HasPrefix(username, q) || strings.HasPrefix(name, q)
+		if prefix || strings.Contains(username, q) || strings.Contains(name, q) {
+			matches = append(matches, match{employee: employee, prefix: prefix})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].prefix != matches[j].prefix {
+			return matches[i].prefix
+		}
+		return matches[i].employee.Username < matches[j].employee.Username
+	})
+
+	var page []model.Employee
+	for i := search.Offset; i < len(matches) && len(page) < search.Limit; i++ {
+		page = append(page, matches[i].employee)
+	}
+	return page, int64(len(matches)), nil
+}
+
+type passwordResetRepository struct {
+	store *Store
+}
+
+func (r *passwordResetRepository) Create(_ context.Context, token *model.PasswordResetToken) error {
+	defer r.store.lock()()
+	token.ID = uint(len(r.store.data.resets) + 1)
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = r.store.Now()
+	}
+	r.store.data.resets = append(r.store.data.resets, *token)
+	return nil
+}
+
+func (r *passwordResetRepository) FindByHashForUpdate(_ context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	defer r.store.lock()()
+	for _, token := range r.store.data.resets {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *passwordResetRepository) RevokeByEmployee(_ context.Context, employeeID uint, revokedAt time.Time) error {
+	defer r.store.lock()()
+	for i := range r.store.data.resets {
+		if token := &r.store.data.resets[i]; token.EmployeeID == employeeID && token.UsedAt == nil {
+			token.UsedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+type accessTokenRepository struct {
+	store *Store
+}
+
+func (r *accessTokenRepository) Create(_ context.Context, token *model.AccessToken) error {
+	defer r.store.lock()()
+	token.ID = uint(len(r.store.data.accessTokens) + 1)
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = r.store.Now()
+	}
+	r.store.data.accessTokens = append(r.store.data.accessTokens, *token)
+	return nil
+}
+
+func (r *accessTokenRepository) FindByHash(_ context.Context, tokenHash string) (*model.AccessToken, error) {
+	defer r.store.lock()()
+	for _, token := range r.store.data.accessTokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *accessTokenRepository) ListActive(_ context.Context, employeeID uint, now time.Time) ([]model.AccessToken, error) {
+	defer r.store.lock()()
+	var tokens []model.AccessToken
+	for i := len(r.store.data.accessTokens) - 1; i >= 0; i-- {
+		token := r.store.data.accessTokens[i]
+		if token.EmployeeID == employeeID && token.RevokedAt == nil && (token.ExpiresAt == nil || token.ExpiresAt.After(now)) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *accessTokenRepository) CountActive(ctx context.Context, employeeID uint, now time.Time) (int64, error) {
+	tokens, err := r.ListActive(ctx, employeeID, now)
+	return int64(len(tokens)), err
+}
+
+func (r *accessTokenRepository) Revoke(_ context.Context, employeeID, id uint, revokedAt time.Time) error {
+	defer r.store.lock()()
+	for i := range r.store.data.accessTokens {
+		if token := &r.store.data.accessTokens[i]; token.ID == id && token.EmployeeID == employeeID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *accessTokenRepository) TouchLastUsed(_ context.Context, id uint, usedAt time.Time) error {
+	defer r.store.lock()()
+	for i := range r.store.data.accessTokens {
+		if r.store.data.accessTokens[i].ID == id {
+			r.store.data.accessTokens[i].LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+type loginThrottleRepository struct {
+	store *Store
+}
+
+func (r *loginThrottleRepository) Find(_ context.Context, keys ...string) ([]model.LoginThrottle, error) {
+	defer r.store.lock()()
+	var throttles []model.LoginThrottle
+	for _, key := range keys {
+		if throttle, ok := r.store.data.throttles[key]; ok {
+			throttles = append(throttles, throttle)
+		}
+	}
+	return throttles, nil
+}
+
+func (r *loginThrottleRepository) Reserve(_ context.Context, key string, at, windowStart time.Time, limit int, lockUntil time.Time) (int, bool, error) {
+	defer r.store.lock()()
+	throttle, ok := r.store.data.throttles[key]
+	if ok && throttle.LockedUntil != nil && throttle.LockedUntil.After(at) {
+		return throttle.Failures, false, nil
+	}
+	if !ok || throttle.LastFailureAt.Before(windowStart) {
+		throttle = model.LoginThrottle{ThrottleKey: key}
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = at
+	throttle.LockedUntil = nil
+	if throttle.Failures >= limit {
+		throttle.LockedUntil = &lockUntil
+	}
+	r.store.data.throttles[key] = throttle
+	return throttle.Failures, true, nil
+}
+
+func (r *loginThrottleRepository) Release(_ context.Context, key string, lockUntil time.Time) error {
+	defer r.store.lock()()
+	throttle, ok := r.store.data.throttles[key]
+	if !ok {
+		return nil
+	}
+	throttle.Failures = max(throttle.Failures-1, 0)
+	if throttle.LockedUntil != nil && throttle.LockedUntil.Equal(lockUntil) {
+		throttle.LockedUntil = nil
+	}
+	r.store.data.throttles[key] = throttle
+	return nil
+}
+
+func (r *loginThrottleRepository) LockUntil(_ context.Context, key string, until time.Time) error {
+	defer r.store.lock()()
+	throttle, ok := r.store.data.throttles[key]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	throttle.LockedUntil = &until
+	r.store.data.throttles[key] = throttle
+	return nil
+}
+
+func (r *loginThrottleRepository) Delete(_ context.Context, key string) error {
+	defer r.store.lock()()
+	delete(r.store.data.throttles, key)
+	return nil
+}
+
+type auditLogRepository struct {
+	store *Store
+}
+
+func (r *auditLogRepository) Append(_ context.Context, event *model.AuditEvent) error {
+	defer r.store.lock()()
+	event.ID = uint(len(r.store.data.auditLog) + 1)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = r.store.Now()
+	}
+	r.store.data.auditLog = append(r.store.data.auditLog, *event)
+	return nil
+}
+
+func (r *auditLogRepository) Search(_ context.Context, search repository.AuditSearch) ([]model.AuditEvent, error) {
+	defer r.store.lock()()
+	var events []model.AuditEvent
+	for i := len(r.store.data.auditLog) - 1; i >= 0 && (search.Limit <= 0 || len(events) < search.Limit); i-- {
+		event := r.store.data.auditLog[i]
+		switch {
+		case search.Actor != "" && event.Actor != search.Actor,
+			search.Action != "" && event.Action != search.Action,
+			search.Target != "" && event.Target != search.Target,
+			!search.From.IsZero() && event.CreatedAt.Before(search.From),
+			!search.To.IsZero() && !event.CreatedAt.Before(search.To),
+			search.BeforeID != 0 && event.ID >= search.BeforeID:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,172 @@
+// Package memory содержит хранилище в памяти для тестов сервисов.
+package memory
+
+import (
+	"context"
+	"maps"
+	"merch-api/model"
+	"merch-api/repository"
+	"sync"
+	"time"
+)
+
+type data struct {
+	employees    []model.Employee
+	merch        []model.Merch
+	purchases    []model.Purchase
+	transactions []model.Transaction
+	resets       []model.PasswordResetToken
+	accessTokens []model.AccessToken
+	auditLog     []model.AuditEvent
+	throttles    map[string]model.LoginThrottle
+}
+
+func (d *data) clone() *data {
+	return &data{
+		employees:    append([]model.Employee(nil), d.employees...),
+		merch:        append([]model.Merch(nil), d.merch...),
+		purchases:    append([]model.Purchase(nil), d.purchases...),
+		transactions: append([]model.Transaction(nil), d.transactions...),
+		resets:       append([]model.PasswordResetToken(nil), d.resets...),
+		accessTokens: append([]model.AccessToken(nil), d.accessTokens...),
+		auditLog:     append([]model.AuditEvent(nil), d.auditLog...),
+		throttles:    maps.Clone(d.throttles),
+	}
+}
+
+// Store хранит данные в памяти. Транзакции выполняются последовательно
+// над копией данных, которая заменяет исходные только при успешном завершении.
+type Store struct {
+	mu   *sync.Mutex
+	data *data
+	inTx bool
+	// Now подставляет время создания покупок и переводов
+	Now func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		mu:   &sync.Mutex{},
+		data: &data{throttles: map[string]model.LoginThrottle{}},
+		Now:  time.Now,
+	}
+}
+
+func (s *Store) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s *Store) Employees() repository.EmployeeRepository {
+	return &employeeRepository{store: s}
+}
+
+func (s *Store) Merch() repository.MerchRepository {
+	return &merchRepository{store: s}
+}
+
+func (s *Store) Purchases() repository.PurchaseRepository {
+	return &purchaseRepository{store: s}
+}
+
+func (s *Store) Transactions() repository.TransactionRepository {
+	return &transactionRepository{store: s}
+}
+
+func (s *Store) PasswordResets() repository.PasswordResetRepository {
+	return &passwordResetRepository{store: s}
+}
+
+func (s *Store) LoginThrottles() repository.LoginThrottleRepository {
+	return &loginThrottleRepository{store: s}
+}
+
+func (s *Store) AccessTokens() repository.AccessTokenRepository {
+	return &accessTokenRepository{store: s}
+}
+
+func (s *Store) AuditLog() repository.AuditLogRepository {
+	return &auditLogRepository{store: s}
+}
+
+func (s *Store) WithinTransaction(ctx context.Context, fn func(tx repository.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Store{mu: s.mu, data: s.data.clone(), inTx: true, Now: s.Now}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*s.data = *tx.data
+	return nil
+}
+
+// AddEmployee добавляет сотрудника и возвращает его с присвоенным ID
+func (s *Store) AddEmployee(employee model.Employee) model.Employee {
+	defer s.lock()()
+	employee.ID = uint(len(s.data.employees) + 1)
+	if employee.Role == "" {
+		employee.Role = model.RoleEmployee
+	}
+	s.data.employees = append(s.data.employees, employee)
+	return employee
+}
+
+// AddMerch добавляет товар и возвращает его с присвоенным ID
+func (s *Store) AddMerch(merch model.Merch) model.Merch {
+	defer s.lock()()
+	merch.ID = uint(len(s.data.merch) + 1)
+	s.data.merch = append(s.data.merch, merch)
+	return merch
+}
+
+// Employee возвращает копию сотрудника по имени
+func (s *Store) Employee(username string) (model.Employee, bool) {
+	defer s.lock()()
+	for _, employee := range s.data.employees {
+		if employee.Username == username {
+			return employee, true
+		}
+	}
+	return model.Employee{}, false
+}
+
+func (s *Store) PurchasesList() []model.Purchase {
+	defer s.lock()()
+	return append([]model.Purchase(nil), s.data.purchases...)
+}
+
+func (s *Store) TransactionsList() []model.Transaction {
+	defer s.lock()()
+	return append([]model.Transaction(nil), s.data.transactions...)
+}
+
+func (s *Store) PasswordResetsList() []model.PasswordResetToken {
+	defer s.lock()()
+	return append([]model.PasswordResetToken(nil), s.data.resets...)
+}
+
+func (s *Store) AccessTokensList() []model.AccessToken {
+	defer s.lock()()
+	return append([]model.AccessToken(nil), s.data.accessTokens...)
+}
+
+func (s *Store) AuditLogList() []model.AuditEvent {
+	defer s.lock()()
+	return append([]model.AuditEvent(nil), s.data.auditLog...)
+}
+
+func (s *Store) employeeByID(id uint) *model.Employee {
+	for i := range s.data.employees {
+		if s.data.employees[i].ID == id {
+			return &s.data.employees[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository"
+	"time"
+)
+
+const activeAccessTokenCondition = "employee_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)"
+
+type AccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *AccessTokenRepository) Create(ctx context.Context, token *model.AccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *AccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.AccessToken, error) {
+	var token model.AccessToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+func (r *AccessTokenRepository) ListActive(ctx context.Context, employeeID uint, now time.Time) ([]model.AccessToken, error) {
+	var tokens []model.AccessToken
+	err := r.db.WithContext(ctx).
+		Where(activeAccessTokenCondition, employeeID, now).
+		Order("id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *AccessTokenRepository) CountActive(ctx context.Context, employeeID uint, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.AccessToken{}).
+		Where(activeAccessTokenCondition, employeeID, now).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *AccessTokenRepository) Revoke(ctx context.Context, employeeID, id uint, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.AccessToken{}).
+		Where("id = ? AND employee_id = ? AND revoked_at IS NULL", id, employeeID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AccessTokenRepository) TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.AccessToken{ID: id}).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository"
+)
+
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *AuditLogRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *AuditLogRepository) Search(ctx context.Context, search repository.AuditSearch) ([]model.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Model(&model.AuditEvent{})
+	if search.Actor != "" {
+		query = query.Where("actor = ?", search.Actor)
+	}
+	if search.Action != "" {
+		query = query.Where("action = ?", search.Action)
+	}
+	if search.Target != "" {
+		query = query.Where("target = ?", search.Target)
+	}
+	if !search.From.IsZero() {
+		query = query.Where("created_at >= ?", search.From)
+	}
+	if !search.To.IsZero() {
+		query = query.Where("created_at < ?", search.To)
+	}
+	if search.BeforeID != 0 {
+		query = query.Where("id < ?", search.BeforeID)
+	}
+
+	if search.Limit > 0 {
+		query = query.Limit(search.Limit)
+	}
+
+	var events []model.AuditEvent
+	err := query.Order("id DESC").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"merch-api/model"
+	"merch-api/repository"
+	"strings"
+	"time"
+)
+
+type EmployeeRepository struct {
+	db *gorm.DB
+}
+
+func (r *EmployeeRepository) FindByUsername(ctx context.Context, username string) (*model.Employee, error) {
+	var employee model.Employee
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&employee).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &employee, nil
+}
+
+func (r *EmployeeRepository) FindByUsernameForUpdate(ctx context.Context, username string) (*model.Employee, error) {
+	var employee model.Employee
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("username = ?", username).
+		First(&employee).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &employee, nil
+}
+
+func (r *EmployeeRepository) FindByID(ctx context.Context, id uint) (*model.Employee, error) {
+	var employee model.Employee
+	if err := r.db.WithContext(ctx).First(&employee, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &employee, nil
+}
+
+func (r *EmployeeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*model.Employee, error) {
+	var employee model.Employee
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&employee, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &employee, nil
+}
+
+func (r *EmployeeRepository) FindByExternalIdentity(ctx context.Context, provider, subject string) (*model.Employee, error) {
+	var employee model.Employee
+	if err := r.db.WithContext(ctx).
+		Where("auth_provider = ? AND auth_subject = ?", provider, subject).
+		First(&employee).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &employee, nil
+}
+
+func (r *EmployeeRepository) LinkExternalIdentity(ctx context.Context, id uint, provider, subject string) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Where("auth_provider = '' AND password = ''").
+		Updates(map[string]any{"auth_provider": provider, "auth_subject": subject})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *EmployeeRepository) FirstOrCreate(ctx context.Context, employee *model.Employee) (*model.Employee, error) {
+	var found model.Employee
+	if err := r.db.WithContext(ctx).
+		Where("username = ?", employee.Username).
+		Attrs(*employee).
+		FirstOrCreate(&found).Error; err != nil {
+		// Параллельный первый вход того же пользователя упирается в уникальность username —
+		// значит, запись уже создана и её можно прочитать
+		if isUniqueViolation(err) {
+			return r.FindByUsername(ctx, employee.Username)
+		}
+		return nil, err
+	}
+	return &found, nil
+}
+
+func (r *EmployeeRepository) UpdateBalance(ctx context.Context, id uint, balance int) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Update("balance", balance).Error
+}
+
+func (r *EmployeeRepository) SetDeactivatedAt(ctx context.Context, id uint, deactivatedAt *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Update("deactivated_at", deactivatedAt).Error
+}
+
+func (r *EmployeeRepository) UpdateProfile(ctx context.Context, id uint, profile model.EmployeeProfile) error {
+	// Select нужен, чтобы пустые строки тоже записывались и очищали поля
+	return r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Select("display_name", "email", "department", "team", "avatar_url").
+		Updates(model.Employee{Profile: profile}).Error
+}
+
+func (r *EmployeeRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Updates(map[string]interface{}{
+			"password":      passwordHash,
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error
+}
+
+func (r *EmployeeRepository) ReplacePasswordHash(ctx context.Context, id uint, oldHash, newHash string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Employee{ID: id}).
+		Where("password = ?", oldHash).
+		Update("password", newHash).Error
+}
+
+// Search сначала отдаёт совпадения по началу логина или имени, затем похожие (pg_trgm),
+// оба условия обслуживаются trigram-индексами на lower(username) и lower(display_name)
+func (r *EmployeeRepository) Search(ctx context.Context, search repository.EmployeeSearch) ([]model.Employee, int64, error) {
+	q := strings.ToLower(search.Query)
+	prefix := escapeLike(q) + "%"
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("deactivated_at IS NULL")
+		if q == "" {
+			return db
+		}
+		return db.Where(
+			"lower(username) LIKE @prefix OR lower(display_name) LIKE @prefix OR @q <% lower(username) OR @q <% lower(display_name)",
+			sql.Named("prefix", prefix), sql.Named("q", q),
+		)
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&model.Employee{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "username"}}}}
+	if q != "" {
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL: "(lower(username) LIKE ? OR lower(display_name) LIKE ?) DESC, " +
+				"GREATEST(word_similarity(?, lower(username)), word_similarity(?, lower(display_name))) DESC, username",
+			Vars:               []interface{}{prefix, prefix, q, q},
+			WithoutParentheses: true,
+		}}
+	}
+
+	var employees []model.Employee
+	err := r.db.WithContext(ctx).
+		Scopes(filter).
+		Clauses(order).
+		Limit(search.Limit).
+		Offset(search.Offset).
+		Find(&employees).Error
+	return employees, total, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"time"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func (r *LoginThrottleRepository) Find(ctx context.Context, keys ...string) ([]model.LoginThrottle, error) {
+	var throttles []model.LoginThrottle
+	err := r.db.WithContext(ctx).Where("throttle_key IN ?", keys).Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *LoginThrottleRepository) Reserve(ctx context.Context, key string, at, windowStart time.Time, limit int, lockUntil time.Time) (int, bool, error) {
+	var failures int
+	result := r.db.WithContext(ctx).Raw(`
+INSERT INTO login_throttle (throttle_key, failures, last_failure_at, locked_until)
+VALUES (?, 1, ?, CASE WHEN 1 >= ? THEN CAST(? AS timestamp) END)
+ON CONFLICT (throttle_key) DO UPDATE SET
+    failures = CASE WHEN login_throttle.last_failure_at < ? THEN 1 ELSE login_throttle.failures + 1 END,
+    last_failure_at = EXCLUDED.last_failure_at,
+    locked_until = CASE
+        WHEN (CASE WHEN login_throttle.last_failure_at < ? THEN 1 ELSE login_throttle.failures + 1 END) >= ?
+        THEN CAST(? AS timestamp) END
+WHERE login_throttle.locked_until IS NULL OR login_throttle.locked_until <= EXCLUDED.last_failure_at
+RETURNING failures`, key, at, limit, lockUntil, windowStart, windowStart, limit, lockUntil).Scan(&failures)
+	return failures, result.RowsAffected > 0, result.Error
+}
+
+func (r *LoginThrottleRepository) Release(ctx context.Context, key string, lockUntil time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.LoginThrottle{ThrottleKey: key}).
+		Updates(map[string]interface{}{
+			"failures":     gorm.Expr("GREATEST(failures - 1, 0)"),
+			"locked_until": gorm.Expr("CASE WHEN locked_until = ? THEN NULL ELSE locked_until END", lockUntil),
+		}).Error
+}
+
+func (r *LoginThrottleRepository) LockUntil(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.LoginThrottle{ThrottleKey: key}).
+		Update("locked_until", until).Error
+}
+
+func (r *LoginThrottleRepository) Delete(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Delete(&model.LoginThrottle{ThrottleKey: key}).Error
+}
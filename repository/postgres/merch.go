@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+)
+
+type MerchRepository struct {
+	db *gorm.DB
+}
+
+func (r *MerchRepository) FindByName(ctx context.Context, name string) (*model.Merch, error) {
+	var merch model.Merch
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&merch).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &merch, nil
+}
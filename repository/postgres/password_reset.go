@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"merch-api/model"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *PasswordResetRepository) FindByHashForUpdate(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	var token model.PasswordResetToken
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).
+		First(&token).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+func (r *PasswordResetRepository) RevokeByEmployee(ctx context.Context, employeeID uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.PasswordResetToken{}).
+		Where("employee_id = ? AND used_at IS NULL", employeeID).
+		Update("used_at", revokedAt).Error
+}
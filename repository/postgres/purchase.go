@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository"
+)
+
+type PurchaseRepository struct {
+	db *gorm.DB
+}
+
+func (r *PurchaseRepository) Create(ctx context.Context, purchase *model.Purchase) error {
+	return r.db.WithContext(ctx).Create(purchase).Error
+}
+
+func (r *PurchaseRepository) FindByIdempotencyKey(ctx context.Context, employeeID uint, key string) (*model.Purchase, error) {
+	var purchase model.Purchase
+	if err := r.db.WithContext(ctx).
+		Where("employee_id = ? AND idempotency_key = ?", employeeID, key).
+		First(&purchase).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &purchase, nil
+}
+
+func (r *PurchaseRepository) InventoryByEmployee(ctx context.Context, employeeID uint) ([]repository.InventoryRow, error) {
+	var rows []repository.InventoryRow
+	err := r.db.WithContext(ctx).
+		Table("purchase").
+		Select("merch.name as type, SUM(purchase.quantity) as quantity").
+		Joins("JOIN merch ON merch.id = purchase.merch_id").
+		Where("purchase.employee_id = ?", employeeID).
+		Group("merch.name").
+		Scan(&rows).Error
+	return rows, err
+}
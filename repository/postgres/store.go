@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"merch-api/repository"
+)
+
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s *Store) Employees() repository.EmployeeRepository {
+	return &EmployeeRepository{db: s.db}
+}
+
+func (s *Store) Merch() repository.MerchRepository {
+	return &MerchRepository{db: s.db}
+}
+
+func (s *Store) Purchases() repository.PurchaseRepository {
+	return &PurchaseRepository{db: s.db}
+}
+
+func (s *Store) Transactions() repository.TransactionRepository {
+	return &TransactionRepository{db: s.db}
+}
+
+func (s *Store) PasswordResets() repository.PasswordResetRepository {
+	return &PasswordResetRepository{db: s.db}
+}
+
+func (s *Store) LoginThrottles() repository.LoginThrottleRepository {
+	return &LoginThrottleRepository{db: s.db}
+}
+
+func (s *Store) AccessTokens() repository.AccessTokenRepository {
+	return &AccessTokenRepository{db: s.db}
+}
+
+func (s *Store) AuditLog() repository.AuditLogRepository {
+	return &AuditLogRepository{db: s.db}
+}
+
+func (s *Store) WithinTransaction(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewStore(tx))
+	})
+}
+
+const uniqueViolationCode = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
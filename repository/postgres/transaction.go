@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository"
+)
+
+type TransactionRepository struct {
+	db *gorm.DB
+}
+
+func (r *TransactionRepository) Create(ctx context.Context, transaction *model.Transaction) error {
+	return r.db.WithContext(ctx).Create(transaction).Error
+}
+
+func (r *TransactionRepository) SentBy(ctx context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	var rows []repository.CoinTransfer
+	err := r.db.WithContext(ctx).
+		Table("transaction").
+		Select("employee.username as username, employee.display_name as display_name, transaction.amount").
+		Joins("JOIN employee employee ON transaction.receiver_id = employee.id").
+		Where("transaction.sender_id = ?", employeeID).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *TransactionRepository) ReceivedBy(ctx context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	var rows []repository.CoinTransfer
+	err := r.db.WithContext(ctx).
+		Table("transaction").
+		Select("employee.username as username, employee.display_name as display_name, transaction.amount").
+		Joins("JOIN employee employee ON transaction.sender_id = employee.id").
+		Where("transaction.receiver_id = ?", employeeID).
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"merch-api/model"
+	"time"
+)
+
+var ErrNotFound = errors.New("запись не найдена")
+
+type InventoryRow struct {
+	Type     string
+	Quantity int
+}
+
+// CoinTransfer — перевод с точки зрения одного сотрудника: Username и DisplayName — вторая сторона перевода
+type CoinTransfer struct {
+	Username    string
+	DisplayName string
+	Amount      int
+}
+
+// EmployeeSearch — поиск по справочнику активных сотрудников. Пустой Query возвращает всех по алфавиту.
+type EmployeeSearch struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+type EmployeeRepository interface {
+	FindByUsername(ctx context.Context, username string) (*model.Employee, error)
+	// FindByUsernameForUpdate блокирует строку сотрудника до конца транзакции
+	FindByUsernameForUpdate(ctx context.Context, username string) (*model.Employee, error)
+	FindByID(ctx context.Context, id uint) (*model.Employee, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*model.Employee, error)
+	// FindByExternalIdentity ищет сотрудника, связанного с учётной записью провайдера входа
+	FindByExternalIdentity(ctx context.Context, provider, subject string) (*model.Employee, error)
+	// LinkExternalIdentity связывает с учётной записью провайдера сотрудника без локального
+	// пароля и без другой связи; иначе возвращает ErrNotFound
+	LinkExternalIdentity(ctx context.Context, id uint, provider, subject string) error
+	FirstOrCreate(ctx context.Context, employee *model.Employee) (*model.Employee, error)
+	UpdateBalance(ctx context.Context, id uint, balance int) error
+	// SetDeactivatedAt деактивирует сотрудника, nil возвращает его в активное состояние
+	SetDeactivatedAt(ctx context.Context, id uint, deactivatedAt *time.Time) error
+	UpdateProfile(ctx context.Context, id uint, profile model.EmployeeProfile) error
+	// Search ищет активных сотрудников по началу или похожести логина и отображаемого имени
+	// и возвращает страницу результатов вместе с общим числом найденных
+	Search(ctx context.Context, search EmployeeSearch) ([]model.Employee, int64, error)
+	// UpdatePassword сохраняет новый хеш пароля и увеличивает TokenVersion, отзывая выданные токены
+	UpdatePassword(ctx context.Context, id uint, passwordHash string) error
+	// ReplacePasswordHash пересохраняет тот же пароль в новом хеше, не отзывая токены. Запись
+	// меняется, только если хеш всё ещё равен oldHash, чтобы не затереть параллельную смену пароля.
+	ReplacePasswordHash(ctx context.Context, id uint, oldHash, newHash string) error
+}
+
+type MerchRepository interface {
+	FindByName(ctx context.Context, name string) (*model.Merch, error)
+}
+
+type PurchaseRepository interface {
+	Create(ctx context.Context, purchase *model.Purchase) error
+	FindByIdempotencyKey(ctx context.Context, employeeID uint, key string) (*model.Purchase, error)
+	InventoryByEmployee(ctx context.Context, employeeID uint) ([]InventoryRow, error)
+}
+
+type TransactionRepository interface {
+	Create(ctx context.Context, transaction *model.Transaction) error
+	SentBy(ctx context.Context, employeeID uint) ([]CoinTransfer, error)
+	ReceivedBy(ctx context.Context, employeeID uint) ([]CoinTransfer, error)
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *model.PasswordResetToken) error
+	// FindByHashForUpdate блокирует токен, чтобы его нельзя было использовать дважды параллельно
+	FindByHashForUpdate(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	// RevokeByEmployee помечает все неиспользованные токены сотрудника использованными
+	RevokeByEmployee(ctx context.Context, employeeID uint, revokedAt time.Time) error
+}
+
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *model.AccessToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*model.AccessToken, error)
+	// ListActive возвращает неотозванные и не истёкшие к now токены сотрудника, новые первыми
+	ListActive(ctx context.Context, employeeID uint, now time.Time) ([]model.AccessToken, error)
+	CountActive(ctx context.Context, employeeID uint, now time.Time) (int64, error)
+	// Revoke отзывает токен сотрудника; чужой, уже отозванный или несуществующий токен — ErrNotFound
+	Revoke(ctx context.Context, employeeID, id uint, revokedAt time.Time) error
+	TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type LoginThrottleRepository interface {
+	Find(ctx context.Context, keys ...string) ([]model.LoginThrottle, error)
+	// Reserve одним запросом учитывает попытку входа до проверки пароля и возвращает новое
+	// значение счётчика. Пока ключ заблокирован, попытка не учитывается и reserved == false.
+	// Если прошлая попытка была раньше windowStart, счётчик начинается заново; когда он
+	// достигает limit, ключ сразу блокируется до lockUntil, пока не станет известен исход попытки
+	Reserve(ctx context.Context, key string, at, windowStart time.Time, limit int, lockUntil time.Time) (failures int, reserved bool, err error)
+	// Release отменяет попытку, учтённую Reserve с тем же lockUntil
+	Release(ctx context.Context, key string, lockUntil time.Time) error
+	LockUntil(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+// AuditSearch — фильтры журнала аудита; пустые поля выборку не ограничивают
+type AuditSearch struct {
+	Actor  string
+	Action string
+	Target string
+	// From включительно, To — нет
+	From time.Time
+	To   time.Time
+	// BeforeID продолжает выборку с записей старше последней записи предыдущей страницы
+	BeforeID uint
+	// Limit <= 0 — без ограничения
+	Limit int
+}
+
+type AuditLogRepository interface {
+	Append(ctx context.Context, event *model.AuditEvent) error
+	// Search возвращает записи от новых к старым
+	Search(ctx context.Context, search AuditSearch) ([]model.AuditEvent, error)
+}
+
+// Store объединяет репозитории и позволяет выполнить несколько операций в одной транзакции
+type Store interface {
+	Employees() EmployeeRepository
+	Merch() MerchRepository
+	Purchases() PurchaseRepository
+	Transactions() TransactionRepository
+	PasswordResets() PasswordResetRepository
+	LoginThrottles() LoginThrottleRepository
+	AccessTokens() AccessTokenRepository
+	AuditLog() AuditLogRepository
+	WithinTransaction(ctx context.Context, fn func(tx Store) error) error
+}
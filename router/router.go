@@ -3,33 +3,187 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"log/slog"
+	"merch-api/config"
 	handler2 "merch-api/handler"
+	"merch-api/httpx"
+	"merch-api/metrics"
 	middleware2 "merch-api/middleware"
+	"merch-api/migrations"
+	"merch-api/repository"
+	"merch-api/repository/postgres"
 	service2 "merch-api/service"
+	"strings"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
-	r := gin.Default()
+const (
+	healthPath    = "/healthz"
+	readinessPath = "/readyz"
+	jwksPath      = "/.well-known/jwks.json"
+)
+
+type handlers struct {
+	auth *handler2.AuthHandler
+	// oidc равен nil, если вход через SSO не настроен
+	oidc             *handler2.OIDCHandler
+	purchase         *handler2.PurchaseHandler
+	transaction      *handler2.TransactionHandler
+	userInfo         *handler2.UserInfoHandler
+	profile          *handler2.ProfileHandler
+	directory        *handler2.DirectoryHandler
+	password         *handler2.PasswordHandler
+	admin            *handler2.AdminHandler
+	accessToken      *handler2.AccessTokenHandler
+	audit            *handler2.AuditHandler
+	jwtKeys          *service2.JWTKeys
+	employees        middleware2.EmployeeFinder
+	accessTokens     middleware2.AccessTokenAuthenticator
+	legacyBuyEnabled bool
+}
+
+func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+	r := gin.New()
+	// Без явного списка gin доверяет X-Forwarded-For от кого угодно, и ограничение попыток входа по IP можно обойти
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		slog.Error("invalid trusted proxies", slog.String("error", err.Error()))
+	}
+	r.Use(
+		middleware2.RequestID(),
+		middleware2.Tracing(),
+		middleware2.RequestLogger(slog.Default(), healthPath, readinessPath),
+		middleware2.Metrics(),
+		gin.Recovery(),
+		middleware2.Timeout(cfg.HTTP.RequestTimeout),
+	)
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			slog.Error("failed to register db stats collector", slog.String("error", err.Error()))
+		}
+	}
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	expectedMigrationVersion, err := migrations.LatestVersion()
+	if err != nil {
+		slog.Error("failed to read migration versions", slog.String("error", err.Error()))
+	}
+	healthHandler := handler2.NewHealthHandler(db, expectedMigrationVersion)
+	r.GET(healthPath, healthHandler.Liveness)
+	r.GET(readinessPath, healthHandler.Readiness)
 
-	purchaseService := service2.NewPurchaseService()
+	jwtKeys := service2.NewJWTKeys(cfg.JWT, service2.SystemClock{})
+	r.GET(jwksPath, handler2.NewJWKSHandler(jwtKeys).Keys)
+
+	store := postgres.NewStore(db)
+
+	purchaseService := service2.NewPurchaseService(store)
 	purchaseHandler := handler2.NewPurchaseHandler(purchaseService)
 
-	transactionService := service2.NewTransactionService()
+	transactionService := service2.NewTransactionService(store)
 	transactionHandler := handler2.NewTransactionHandler(transactionService)
 
-	userInfoService := service2.NewUserInfoService()
+	userInfoService := service2.NewUserInfoService(store)
 	userInfoHandler := handler2.NewUserInfoHandler(userInfoService)
 
-	authService := service2.NewAuthService()
+	loginThrottle := service2.NewLoginThrottle(store, cfg.Auth.Throttle, service2.SystemClock{})
+	passwordHasher := service2.NewPasswordHasher(cfg.Auth.PasswordHash)
+	authService := service2.NewAuthService(store, jwtKeys, newAuthenticator(cfg.Auth, store, passwordHasher), loginThrottle)
 	authHandler := handler2.NewAuthHandler(authService)
 
-	r.Use(middleware2.DatabaseMiddleware(db))
-	r.POST("/api/auth", authHandler.Authenticate)
+	var oidcHandler *handler2.OIDCHandler
+	if cfg.Auth.OIDC.Enabled() {
+		oidcService := service2.NewOIDCService(cfg.Auth.OIDC, authService, service2.SystemClock{})
+		oidcHandler = handler2.NewOIDCHandler(oidcService, strings.HasPrefix(cfg.Auth.OIDC.RedirectURL, "https://"))
+	}
+
+	profileService := service2.NewProfileService(store)
+	profileHandler := handler2.NewProfileHandler(profileService)
+
+	passwordService := service2.NewPasswordService(store, authService, passwordHasher, service2.SystemClock{}, cfg.Auth.PasswordResetTTL)
+	passwordHandler := handler2.NewPasswordHandler(passwordService)
+
+	directoryService := service2.NewDirectoryService(store)
+	directoryHandler := handler2.NewDirectoryHandler(directoryService)
+
+	employeeService := service2.NewEmployeeService(store, service2.SystemClock{})
+	adminHandler := handler2.NewAdminHandler(employeeService, loginThrottle)
 
-	r.Use(middleware2.JWTMiddleware())
-	r.GET("/api/buy/:item", purchaseHandler.BuyItem)
-	r.POST("/api/sendCoin", transactionHandler.SendCoin)
-	r.GET("/api/info", userInfoHandler.InfoHandler)
+	accessTokenService := service2.NewAccessTokenService(store, service2.SystemClock{})
+	accessTokenHandler := handler2.NewAccessTokenHandler(accessTokenService)
+
+	auditHandler := handler2.NewAuditHandler(service2.NewAuditService(store))
+
+	h := handlers{
+		auth:             authHandler,
+		oidc:             oidcHandler,
+		purchase:         purchaseHandler,
+		transaction:      transactionHandler,
+		userInfo:         userInfoHandler,
+		profile:          profileHandler,
+		directory:        directoryHandler,
+		password:         passwordHandler,
+		admin:            adminHandler,
+		accessToken:      accessTokenHandler,
+		audit:            auditHandler,
+		jwtKeys:          jwtKeys,
+		employees:        store.Employees(),
+		accessTokens:     accessTokenService,
+		legacyBuyEnabled: cfg.Features.LegacyBuyGet,
+	}
+
+	// Пути без версии оставлены как псевдоним v1 для старых клиентов
+	registerRoutes(r.Group("/api", middleware2.APIVersion(httpx.APIVersionV1)), h)
+	registerRoutes(r.Group("/api/v1", middleware2.APIVersion(httpx.APIVersionV1)), h)
+	registerRoutes(r.Group("/api/v2", middleware2.APIVersion(httpx.APIVersionV2)), h)
 
 	return r
 }
+
+// newAuthenticator выбирает, чем проверять пароль на POST /auth
+func newAuthenticator(cfg config.AuthConfig, store repository.Store, hasher *service2.PasswordHasher) service2.Authenticator {
+	if cfg.Provider == config.ProviderLDAP {
+		return service2.NewLDAPAuthenticator(cfg.LDAP)
+	}
+	return service2.NewLocalAuthenticator(store, hasher)
+}
+
+func registerRoutes(api *gin.RouterGroup, h handlers) {
+	api.POST("/auth", h.auth.Authenticate)
+	if h.oidc != nil {
+		api.GET("/auth/oidc/login", h.oidc.Login)
+		api.GET("/auth/oidc/callback", h.oidc.Callback)
+	}
+	api.POST("/password/reset", h.password.ResetPassword)
+
+	protected := api.Group("", middleware2.JWTMiddleware(h.jwtKeys, h.employees, h.accessTokens))
+
+	purchases := protected.Group("", middleware2.RequireScope(service2.ScopePurchaseWrite))
+	purchases.POST("/purchases", h.purchase.CreatePurchase)
+	if h.legacyBuyEnabled {
+		purchases.GET("/buy/:item", h.purchase.BuyItem)
+	}
+	protected.POST("/sendCoin", middleware2.RequireScope(service2.ScopeTransferWrite), h.transaction.SendCoin)
+	protected.GET("/info", middleware2.RequireScope(service2.ScopeInfoRead), h.userInfo.InfoHandler)
+
+	// Управление учётной записью, справочник и администрирование — только после входа самого сотрудника
+	session := protected.Group("", middleware2.RejectAccessTokens())
+	session.GET("/me/profile", h.profile.GetProfile)
+	session.PATCH("/me/profile", h.profile.UpdateProfile)
+	session.POST("/me/password", h.password.ChangePassword)
+	session.GET("/me/tokens", h.accessToken.ListTokens)
+	session.POST("/me/tokens", h.accessToken.CreateToken)
+	session.DELETE("/me/tokens/:id", h.accessToken.RevokeToken)
+	session.GET("/employees", h.directory.SearchEmployees)
+
+	admin := session.Group("/admin", middleware2.RequireScope(service2.ScopeAdminEmployees))
+	// Деактивация может обнулить баланс или перевести его другому сотруднику
+	admin.POST("/employees/:username/deactivate", middleware2.RequireScope(service2.ScopeAdminBalances), h.admin.DeactivateEmployee)
+	admin.POST("/employees/:username/reactivate", h.admin.ReactivateEmployee)
+	admin.POST("/employees/:username/password-reset", h.password.IssueReset)
+	admin.POST("/employees/:username/unlock", h.admin.UnlockEmployeeLogin)
+	admin.POST("/ips/:ip/unlock", h.admin.UnlockIPLogin)
+
+	audit := session.Group("/admin/audit", middleware2.RequireScope(service2.ScopeAdminAudit))
+	audit.GET("", h.audit.SearchAudit)
+	audit.GET("/export", h.audit.ExportAudit)
+}
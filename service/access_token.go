@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"merch-api/model"
+	"merch-api/repository"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AccessTokenPrefix отличает токены доступа от JWT в заголовке Authorization
+const AccessTokenPrefix = "mpat_"
+
+// AccessTokenScopes — права, которые можно выдать токену доступа
+var AccessTokenScopes = []string{ScopeInfoRead, ScopeTransferWrite, ScopePurchaseWrite}
+
+const (
+	MaxAccessTokenNameLength   = 64
+	MaxAccessTokensPerEmployee = 20
+	// accessTokenDisplayLength — сколько символов токена показывать в списке, чтобы его можно было узнать
+	accessTokenDisplayLength = len(AccessTokenPrefix) + 6
+	// accessTokenTouchInterval ограничивает запись last_used_at: без него каждый запрос бота писал бы в БД
+	accessTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAccessTokenRequest = errors.New("некорректный запрос токена доступа")
+	ErrTooManyAccessTokens       = errors.New("слишком много токенов доступа")
+	ErrAccessTokenNotFound       = errors.New("токен доступа не найден")
+	ErrInvalidAccessToken        = errors.New("токен доступа недействителен, отозван или истёк")
+)
+
+type CreateAccessTokenRequest struct {
+	Name   string
+	Scopes []string
+	// ExpiresAt == nil — токен действует, пока его не отзовут
+	ExpiresAt *time.Time
+}
+
+type AccessTokenInfo struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// IssuedAccessToken — только что выпущенный токен; Token показывается один раз
+type IssuedAccessToken struct {
+	AccessTokenInfo
+	Token string `json:"token"`
+}
+
+type AccessTokenService interface {
+	CreateToken(ctx context.Context, username string, req CreateAccessTokenRequest) (*IssuedAccessToken, error)
+	ListTokens(ctx context.Context, username string) ([]AccessTokenInfo, error)
+	RevokeToken(ctx context.Context, username string, id uint) error
+}
+
+type AccessTokenServiceImpl struct {
+	store repository.Store
+	clock Clock
+}
+
+func NewAccessTokenService(store repository.Store, clock Clock) *AccessTokenServiceImpl {
+	return &AccessTokenServiceImpl{
+		store: store,
+		clock: clock,
+	}
+}
+
+func (s *AccessTokenServiceImpl) CreateToken(ctx context.Context, username string, req CreateAccessTokenRequest) (_ *IssuedAccessToken, err error) {
+	ctx, span := startSpan(ctx, "AccessTokenService.CreateToken")
+	defer func() { endSpan(span, err) }()
+
+	scopes, err := s.validate(&req)
+	if err != nil {
+		return nil, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	token = AccessTokenPrefix + token
+
+	accessToken := &model.AccessToken{
+		Name:      req.Name,
+		TokenHash: hashSecretToken(token),
+		Prefix:    token[:accessTokenDisplayLength],
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: s.clock.Now(),
+	}
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		// Блокировка сотрудника не даёт параллельным запросам обойти ограничение на число токенов
+		employee, err := tx.Employees().FindByUsernameForUpdate(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+		}
+		if err != nil {
+			return err
+		}
+
+		count, err := tx.AccessTokens().CountActive(ctx, employee.ID, s.clock.Now())
+		if err != nil {
+			return err
+		}
+		if count >= MaxAccessTokensPerEmployee {
+			return fmt.Errorf("%w: не больше %d, отзовите ненужные", ErrTooManyAccessTokens, MaxAccessTokensPerEmployee)
+		}
+
+		accessToken.EmployeeID = employee.ID
+		if err := tx.AccessTokens().Create(ctx, accessToken); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditEvent{
+			Actor:   employee.Username,
+			Action:  AuditAccessTokenCreate,
+			Target:  strconv.FormatUint(uint64(accessToken.ID), 10),
+			Details: fmt.Sprintf("%s: %s", accessToken.Name, accessToken.Scopes),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedAccessToken{AccessTokenInfo: newAccessTokenInfo(accessToken), Token: token}, nil
+}
+
+func (s *AccessTokenServiceImpl) ListTokens(ctx context.Context, username string) (_ []AccessTokenInfo, err error) {
+	ctx, span := startSpan(ctx, "AccessTokenService.ListTokens")
+	defer func() { endSpan(span, err) }()
+
+	employee, err := s.findEmployee(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.store.AccessTokens().ListActive(ctx, employee.ID, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]AccessTokenInfo, 0, len(tokens))
+	for _, token := range tokens {
+		infos = append(infos, newAccessTokenInfo(&token))
+	}
+	return infos, nil
+}
+
+// RevokeToken отзывает токен сотрудника; чужие токены для него не существуют
+func (s *AccessTokenServiceImpl) RevokeToken(ctx context.Context, username string, id uint) (err error) {
+	ctx, span := startSpan(ctx, "AccessTokenService.RevokeToken")
+	defer func() { endSpan(span, err) }()
+
+	employee, err := s.findEmployee(ctx, username)
+	if err != nil {
+		return err
+	}
+	return s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		err := tx.AccessTokens().Revoke(ctx, employee.ID, id, s.clock.Now())
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %d", ErrAccessTokenNotFound, id)
+		}
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditEvent{
+			Actor:  employee.Username,
+			Action: AuditAccessTokenRevoke,
+			Target: strconv.FormatUint(uint64(id), 10),
+		})
+	})
+}
+
+// AuthenticateToken находит сотрудника по токену доступа. Неизвестный, отозванный
+// и истёкший токены неразличимы для клиента: все дают ErrInvalidAccessToken
+func (s *AccessTokenServiceImpl) AuthenticateToken(ctx context.Context, token string) (_ *model.Employee, _ *model.AccessToken, err error) {
+	ctx, span := startSpan(ctx, "AccessTokenService.AuthenticateToken")
+	defer func() { endSpan(span, err) }()
+
+	accessToken, err := s.store.AccessTokens().FindByHash(ctx, hashSecretToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.clock.Now()
+	if !accessToken.Usable(now) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	employee, err := s.store.Employees().FindByID(ctx, accessToken.EmployeeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.touch(ctx, accessToken, now)
+	return employee, accessToken, nil
+}
+
+// touch отмечает использование токена; ошибка не мешает запросу
+func (s *AccessTokenServiceImpl) touch(ctx context.Context, token *model.AccessToken, now time.Time) {
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < accessTokenTouchInterval {
+		return
+	}
+	if err := s.store.AccessTokens().TouchLastUsed(ctx, token.ID, now); err != nil {
+		slog.WarnContext(ctx, "failed to record access token usage",
+			slog.Uint64("access_token_id", uint64(token.ID)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *AccessTokenServiceImpl) findEmployee(ctx context.Context, username string) (*model.Employee, error) {
+	employee, err := s.store.Employees().FindByUsername(ctx, username)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+	}
+	return employee, err
+}
+
+// validate проверяет имя, срок и права и возвращает права без повторов в стабильном порядке
+func (s *AccessTokenServiceImpl) validate(req *CreateAccessTokenRequest) ([]string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name обязателен", ErrInvalidAccessTokenRequest)
+	}
+	if utf8.RuneCountInString(req.Name) > MaxAccessTokenNameLength {
+		return nil, fmt.Errorf("%w: name длиннее %d символов", ErrInvalidAccessTokenRequest, MaxAccessTokenNameLength)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.clock.Now()) {
+		return nil, fmt.Errorf("%w: expiresAt должен быть в будущем", ErrInvalidAccessTokenRequest)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: нужно указать хотя бы одно право из %s", ErrInvalidAccessTokenRequest, strings.Join(AccessTokenScopes, ", "))
+	}
+
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(AccessTokenScopes, scope) {
+			return nil, fmt.Errorf("%w: неизвестное право %q", ErrInvalidAccessTokenRequest, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	slices.Sort(scopes)
+	return scopes, nil
+}
+
+func newAccessTokenInfo(token *model.AccessToken) AccessTokenInfo {
+	return AccessTokenInfo{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"merch-api/model"
+	"merch-api/repository"
+	"time"
+)
+
+// Действия журнала аудита
+const (
+	AuditLogin               = "auth.login"
+	AuditLoginFailed         = "auth.login_failed"
+	AuditLoginLocked         = "auth.login_locked"
+	AuditPasswordChange      = "password.change"
+	AuditPasswordReset       = "password.reset"
+	AuditProfileUpdate       = "profile.update"
+	AuditAccessTokenCreate   = "access_token.create"
+	AuditAccessTokenRevoke   = "access_token.revoke"
+	AuditPurchase            = "purchase.create"
+	AuditTransfer            = "transfer.create"
+	AuditEmployeeDeactivate  = "admin.employee.deactivate"
+	AuditEmployeeReactivate  = "admin.employee.reactivate"
+	AuditPasswordResetIssue  = "admin.password_reset.issue"
+	AuditLoginUnlockEmployee = "admin.login.unlock_employee"
+	AuditLoginUnlockIP       = "admin.login.unlock_ip"
+)
+
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 500
+	// auditExportBatch — сколько записей экспорт читает из БД за раз
+	auditExportBatch      = 500
+	maxAuditActorLength   = 64
+	maxAuditTargetLength  = 255
+	maxAuditDetailsLength = 255
+	maxAuditFilterLength  = 255
+)
+
+var ErrInvalidAuditQuery = errors.New("некорректный запрос к журналу аудита")
+
+// RequestMeta — сведения о HTTP-запросе, которые попадают в журнал аудита
+type RequestMeta struct {
+	IP        string
+	RequestID string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+// recordAudit пишет событие через store — как правило, транзакцию самого изменения, чтобы
+// журнал и данные не расходились. Исполнитель, если не задан, берётся из Principal, IP и
+// request ID — из контекста запроса
+func recordAudit(ctx context.Context, store repository.Store, event model.AuditEvent) error {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		if event.Actor == "" {
+			event.Actor = principal.Username
+		}
+		if principal.ViaAccessToken() {
+			id := principal.AccessTokenID
+			event.AccessTokenID = &id
+		}
+	}
+	meta := RequestMetaFromContext(ctx)
+	event.IP, event.RequestID = meta.IP, meta.RequestID
+	event.Actor = clip(event.Actor, maxAuditActorLength)
+	event.Target = clip(event.Target, maxAuditTargetLength)
+	event.Details = clip(event.Details, maxAuditDetailsLength)
+	return store.AuditLog().Append(ctx, &event)
+}
+
+// recordAuditBestEffort — для событий без транзакции, например входов: сбой журнала
+// не должен менять ответ клиенту, поэтому он только логируется
+func recordAuditBestEffort(ctx context.Context, store repository.Store, event model.AuditEvent) {
+	if err := recordAudit(ctx, store, event); err != nil {
+		slog.WarnContext(ctx, "failed to write audit event",
+			slog.String("action", event.Action),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func balanceRef(balance int) *int {
+	return &balance
+}
+
+// AuditQuery — фильтры журнала; Limit == 0 означает размер страницы по умолчанию,
+// Before — nextBefore предыдущей страницы
+type AuditQuery struct {
+	Actor  string
+	Action string
+	Target string
+	From   time.Time
+	To     time.Time
+	Before uint
+	Limit  int
+}
+
+type AuditEntry struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Actor         string    `json:"actor"`
+	AccessTokenID *uint     `json:"accessTokenId,omitempty"`
+	Action        string    `json:"action"`
+	Target        string    `json:"target"`
+	BalanceBefore *int      `json:"balanceBefore,omitempty"`
+	BalanceAfter  *int      `json:"balanceAfter,omitempty"`
+	Details       string    `json:"details,omitempty"`
+	IP            string    `json:"ip"`
+	RequestID     string    `json:"requestId"`
+}
+
+type AuditPage struct {
+	Events []AuditEntry `json:"events"`
+	// NextBefore передаётся в before, чтобы получить следующую страницу; 0 — страниц больше нет
+	NextBefore uint `json:"nextBefore,omitempty"`
+}
+
+type AuditService interface {
+	Search(ctx context.Context, query AuditQuery) (*AuditPage, error)
+	// Export передаёт в fn все записи под фильтр, от новых к старым, не загружая их в память разом
+	Export(ctx context.Context, query AuditQuery, fn func(AuditEntry) error) error
+}
+
+type AuditServiceImpl struct {
+	store repository.Store
+}
+
+func NewAuditService(store repository.Store) *AuditServiceImpl {
+	return &AuditServiceImpl{
+		store: store,
+	}
+}
+
+func (s *AuditServiceImpl) Search(ctx context.Context, query AuditQuery) (_ *AuditPage, err error) {
+	if query.Limit == 0 {
+		query.Limit = DefaultAuditLimit
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "AuditService.Search")
+	defer func() { endSpan(span, err) }()
+
+	// Лишняя запись показывает, есть ли следующая страница
+	events, err := s.store.AuditLog().Search(ctx, query.search(query.Limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Events: make([]AuditEntry, 0, min(len(events), query.Limit))}
+	if len(events) > query.Limit {
+		events = events[:query.Limit]
+		page.NextBefore = events[len(events)-1].ID
+	}
+	for _, event := range events {
+		page.Events = append(page.Events, newAuditEntry(event))
+	}
+	return page, nil
+}
+
+func (s *AuditServiceImpl) Export(ctx context.Context, query AuditQuery, fn func(AuditEntry) error) (err error) {
+	query.Limit = auditExportBatch
+	if err := query.validate(); err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(ctx, "AuditService.Export")
+	defer func() { endSpan(span, err) }()
+
+	for {
+		events, err := s.store.AuditLog().Search(ctx, query.search(auditExportBatch))
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(newAuditEntry(event)); err != nil {
+				return err
+			}
+		}
+		if len(events) < auditExportBatch {
+			return nil
+		}
+		query.Before = events[len(events)-1].ID
+	}
+}
+
+func (q AuditQuery) validate() error {
+	if q.Limit < 1 || q.Limit > MaxAuditLimit {
+		return fmt.Errorf("%w: limit должен быть от 1 до %d", ErrInvalidAuditQuery, MaxAuditLimit)
+	}
+	for _, filter := range []string{q.Actor, q.Action, q.Target} {
+		if len(filter) > maxAuditFilterLength {
+			return fmt.Errorf("%w: фильтр длиннее %d символов", ErrInvalidAuditQuery, maxAuditFilterLength)
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from должен быть раньше to", ErrInvalidAuditQuery)
+	}
+	return nil
+}
+
+func (q AuditQuery) search(limit int) repository.AuditSearch {
+	return repository.AuditSearch{
+		Actor:    q.Actor,
+		Action:   q.Action,
+		Target:   q.Target,
+		From:     q.From,
+		To:       q.To,
+		BeforeID: q.Before,
+		Limit:    limit,
+	}
+}
+
+func newAuditEntry(event model.AuditEvent) AuditEntry {
+	return AuditEntry{
+		ID:            event.ID,
+		CreatedAt:     event.CreatedAt,
+		Actor:         event.Actor,
+		AccessTokenID: event.AccessTokenID,
+		Action:        event.Action,
+		Target:        event.Target,
+		BalanceBefore: event.BalanceBefore,
+		BalanceAfter:  event.BalanceAfter,
+		Details:       event.Details,
+		IP:            event.IP,
+		RequestID:     event.RequestID,
+	}
+}
@@ -1,50 +1,71 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
+	"log/slog"
 	"merch-api/model"
-	"os"
-	"time"
+	"merch-api/repository"
+	"strings"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_KEY"))
 var (
 	ErrInvalidInput          = fmt.Errorf("invalid input")
 	ErrPasswordMismatch      = fmt.Errorf("invalid password")
 	ErrFailedToCreateUser    = fmt.Errorf("failed to find or create employee")
 	ErrFailedToGenerateToken = fmt.Errorf("failed to generate token")
+	ErrInvalidTokenClaims    = fmt.Errorf("invalid token claims")
 )
 
 type Claims struct {
 	Username string `json:"username"`
+	// TokenVersion должна совпадать с Employee.TokenVersion, иначе токен считается отозванным
+	TokenVersion int `json:"tokenVersion,omitempty"`
+	// Scope — права через пробел, как в RFC 9068
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type LoginRequest struct {
+	Username string
+	Password string
+	// ClientIP учитывается при ограничении попыток входа; пустой адрес не ограничивается
+	ClientIP string
+}
+
 type AuthService interface {
-	AuthenticateUser(db *gorm.DB, username, password string) (string, error)
+	AuthenticateUser(ctx context.Context, req LoginRequest) (string, error)
 }
 
-type AuthServiceImpl struct{}
+type AuthServiceImpl struct {
+	store         repository.Store
+	keys          *JWTKeys
+	authenticator Authenticator
+	throttle      *LoginThrottle
+}
 
-func NewAuthService() *AuthServiceImpl {
-	return &AuthServiceImpl{}
+// NewAuthService создаёт сервис входа; throttle == nil отключает ограничение попыток
+func NewAuthService(store repository.Store, keys *JWTKeys, authenticator Authenticator, throttle *LoginThrottle) *AuthServiceImpl {
+	return &AuthServiceImpl{
+		store:         store,
+		keys:          keys,
+		authenticator: authenticator,
+		throttle:      throttle,
+	}
 }
 
-func GenerateJWT(username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// IssueToken выпускает JWT для сотрудника с его текущей версией токенов
+func (s *AuthServiceImpl) IssueToken(employee *model.Employee) (string, error) {
 	claims := &Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		Username:         employee.Username,
+		TokenVersion:     employee.TokenVersion,
+		Scope:            strings.Join(ScopesForRole(employee.Role), " "),
+		RegisteredClaims: s.keys.RegisteredClaims(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("ошибка при подписании токена: %v", err)
 	}
@@ -52,35 +73,183 @@ func GenerateJWT(username string) (string, error) {
 	return tokenString, nil
 }
 
-func (s *AuthServiceImpl) AuthenticateUser(db *gorm.DB, username, password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, req LoginRequest) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AuthService.AuthenticateUser")
+	defer func() { endSpan(span, err) }()
+
+	if req.Username == "" || req.Password == "" {
+		return "", ErrInvalidInput
+	}
+	var attempt *LoginAttempt
+	if s.throttle != nil {
+		// Отказы во время блокировки в журнал не пишутся: саму блокировку записывает LoginThrottle
+		if attempt, err = s.throttle.Reserve(ctx, req.Username, req.ClientIP); err != nil {
+			return "", err
+		}
+	}
+
+	identity, err := s.authenticator.Authenticate(ctx, req.Username, req.Password)
 	if err != nil {
+		if settleErr := s.settleAttempt(ctx, attempt, err); settleErr != nil {
+			return "", settleErr
+		}
+		s.auditLoginFailure(ctx, req.Username, err)
 		return "", err
 	}
 
-	employee, err := findOrCreateEmployee(db, username, string(hashedPassword))
+	employee, err := s.provision(ctx, identity)
+	if settleErr := s.settleAttempt(ctx, attempt, err); settleErr != nil {
+		return "", settleErr
+	}
 	if err != nil {
-		return "", ErrFailedToCreateUser
+		s.auditLoginFailure(ctx, identity.Username, err)
+		return "", err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte(password)); err != nil {
-		return "", ErrPasswordMismatch
+	token, err := s.IssueToken(employee)
+	if err != nil {
+		return "", ErrFailedToGenerateToken
+	}
+	s.auditLogin(ctx, employee, "пароль")
+
+	return token, nil
+}
+
+// settleAttempt завершает попытку, учтённую LoginThrottle: неверный пароль остаётся в
+// счётчиках, успешный вход их сбрасывает, а прочие ошибки попытку отменяют. Завершение не
+// зависит от отмены запроса, иначе оборванный клиентом вход остался бы в счётчиках
+func (s *AuthServiceImpl) settleAttempt(ctx context.Context, attempt *LoginAttempt, err error) error {
+	if attempt == nil {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		return s.throttle.RecordSuccess(ctx, attempt)
+	case errors.Is(err, ErrPasswordMismatch):
+		return s.throttle.RecordFailure(ctx, attempt)
+	default:
+		return s.throttle.Release(ctx, attempt)
+	}
+}
+
+// LoginWithIdentity выпускает токен сотруднику, которого уже подтвердил внешний провайдер
+// (например, OIDC), и создаёт его запись при первом входе
+func (s *AuthServiceImpl) LoginWithIdentity(ctx context.Context, identity *Identity) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AuthService.LoginWithIdentity")
+	defer func() { endSpan(span, err) }()
+
+	employee, err := s.provision(ctx, identity)
+	if err != nil {
+		s.auditLoginFailure(ctx, identity.Username, err)
+		return "", err
 	}
 
-	token, err := GenerateJWT(employee.Username)
+	token, err := s.IssueToken(employee)
 	if err != nil {
 		return "", ErrFailedToGenerateToken
 	}
+	s.auditLogin(ctx, employee, "SSO")
 
 	return token, nil
 }
 
-func findOrCreateEmployee(db *gorm.DB, username, hashedPassword string) (*model.Employee, error) {
-	var employee model.Employee
-	if err := db.Where("username = ?", username).
-		Attrs(model.Employee{Username: username, Password: hashedPassword, Balance: 1000}).
-		FirstOrCreate(&employee).Error; err != nil {
+func (s *AuthServiceImpl) auditLogin(ctx context.Context, employee *model.Employee, method string) {
+	recordAuditBestEffort(ctx, s.store, model.AuditEvent{
+		Actor:   employee.Username,
+		Action:  AuditLogin,
+		Target:  employee.Username,
+		Details: method,
+	})
+}
+
+// auditLoginFailure записывает отказы во входе; сбои БД и каталога журнал не засоряют
+func (s *AuthServiceImpl) auditLoginFailure(ctx context.Context, username string, err error) {
+	var reason string
+	switch {
+	case errors.Is(err, ErrPasswordMismatch):
+		reason = "неверный логин или пароль"
+	case errors.Is(err, ErrEmployeeDeactivated):
+		reason = "учётная запись деактивирована"
+	case errors.Is(err, ErrExternalIdentityConflict):
+		reason = "логин занят учётной записью, не связанной с провайдером входа"
+	default:
+		return
+	}
+	recordAuditBestEffort(ctx, s.store, model.AuditEvent{
+		Actor:   username,
+		Action:  AuditLoginFailed,
+		Target:  username,
+		Details: reason,
+	})
+}
+
+// provision находит сотрудника подтверждённой личности или создаёт его со стартовым балансом.
+// Сотрудники из внешних провайдеров создаются без локального пароля: пустой хеш не подходит
+// ни к одному паролю, поэтому войти через POST /auth с провайдером local они не смогут
+func (s *AuthServiceImpl) provision(ctx context.Context, identity *Identity) (*model.Employee, error) {
+	if identity.Username == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var employee *model.Employee
+	var err error
+	if identity.External() {
+		employee, err = s.provisionExternal(ctx, identity)
+	} else {
+		employee, err = s.store.Employees().FindByUsername(ctx, identity.Username)
+	}
+	if errors.Is(err, ErrExternalIdentityConflict) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrFailedToCreateUser
+	}
+	if !employee.Active() {
+		return nil, ErrEmployeeDeactivated
+	}
+	return employee, nil
+}
+
+// provisionExternal ищет сотрудника по ключу провайдера, а не по логину. Сотрудник с тем же
+// логином связывается с провайдером, только если у него нет локального пароля: иначе логин
+// коллеги мог заранее занять кто угодно через POST /auth и войти потом в его учётную запись
+func (s *AuthServiceImpl) provisionExternal(ctx context.Context, identity *Identity) (*model.Employee, error) {
+	if identity.Subject == "" {
+		return nil, ErrInvalidInput
+	}
+	employees := s.store.Employees()
+	employee, err := employees.FindByExternalIdentity(ctx, identity.Provider, identity.Subject)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return employee, err
+	}
+
+	employee, err = employees.FirstOrCreate(ctx, &model.Employee{
+		Username:     identity.Username,
+		Balance:      1000,
+		AuthProvider: identity.Provider,
+		AuthSubject:  identity.Subject,
+		Profile:      clippedProfile(identity.Profile),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if employee.AuthProvider == identity.Provider && employee.AuthSubject == identity.Subject {
+		return employee, nil
+	}
+
+	// Сотрудник без пароля и без связи создан входом через провайдер до того, как связь стала сохраняться
+	err = employees.LinkExternalIdentity(ctx, employee.ID, identity.Provider, identity.Subject)
+	if errors.Is(err, repository.ErrNotFound) {
+		slog.WarnContext(ctx, "external identity not linked to existing employee",
+			slog.String("username", employee.Username),
+			slog.String("provider", identity.Provider),
+		)
+		return nil, ErrExternalIdentityConflict
+	}
+	if err != nil {
 		return nil, err
 	}
-	return &employee, nil
+	employee.AuthProvider, employee.AuthSubject = identity.Provider, identity.Subject
+	return employee, nil
 }
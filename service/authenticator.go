@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"merch-api/model"
+	"merch-api/repository"
+	"unicode/utf8"
+)
+
+// Identity — сотрудник, подтверждённый провайдером входа. Профиль заполняется только при
+// первом входе: дальше сотрудник правит его сам через /me/profile
+type Identity struct {
+	Username string
+	// Provider и Subject — неизменяемый ключ учётной записи у внешнего провайдера (iss и sub
+	// для OIDC, ldap и DN для LDAP), по нему сотрудник находится при следующих входах.
+	// У LocalAuthenticator они пустые
+	Provider string
+	Subject  string
+	Profile  model.EmployeeProfile
+}
+
+// ErrExternalIdentityConflict — логин из провайдера занят сотрудником, которого нельзя с ним
+// связать: у него есть локальный пароль или он уже связан с другой учётной записью
+var ErrExternalIdentityConflict = errors.New("логин занят учётной записью, не связанной с этим провайдером входа")
+
+func (i *Identity) External() bool {
+	return i.Provider != ""
+}
+
+// Authenticator проверяет логин и пароль. Неверные учётные данные, в том числе неизвестный
+// логин, возвращаются как ErrPasswordMismatch, чтобы по ответу нельзя было перебирать логины
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// LocalAuthenticator проверяет пароль по хешу из таблицы employee. Неизвестный сотрудник
+// создаётся с переданным паролем — так вход работал в сервисе с самого начала
+type LocalAuthenticator struct {
+	store  repository.Store
+	hasher *PasswordHasher
+}
+
+func NewLocalAuthenticator(store repository.Store, hasher *PasswordHasher) *LocalAuthenticator {
+	return &LocalAuthenticator{
+		store:  store,
+		hasher: hasher,
+	}
+}
+
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	employee, err := a.findOrCreateEmployee(ctx, username, password)
+	if err != nil {
+		return nil, ErrFailedToCreateUser
+	}
+
+	match, err := a.hasher.Verify(employee.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, ErrPasswordMismatch
+	}
+	a.upgradeHash(ctx, employee, password)
+
+	return &Identity{Username: employee.Username}, nil
+}
+
+// findOrCreateEmployee хеширует пароль только для нового сотрудника: хеширование дорогое,
+// и считать его на каждую попытку входа существующего сотрудника незачем
+func (a *LocalAuthenticator) findOrCreateEmployee(ctx context.Context, username, password string) (*model.Employee, error) {
+	employee, err := a.store.Employees().FindByUsername(ctx, username)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return employee, err
+	}
+
+	hashedPassword, err := a.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	return a.store.Employees().FirstOrCreate(ctx, &model.Employee{
+		Username: username,
+		Password: hashedPassword,
+		Balance:  1000,
+	})
+}
+
+// upgradeHash пересчитывает хеш, сделанный устаревшим алгоритмом или с меньшей стоимостью.
+// Пароль в открытом виде есть только в момент входа, поэтому обновить хеш можно лишь здесь.
+// Ошибка не мешает входу: хеш обновится при следующем.
+func (a *LocalAuthenticator) upgradeHash(ctx context.Context, employee *model.Employee, password string) {
+	if !a.hasher.NeedsRehash(employee.Password) {
+		return
+	}
+	hash, err := a.hasher.Hash(password)
+	if err == nil {
+		err = a.store.Employees().ReplacePasswordHash(ctx, employee.ID, employee.Password, hash)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to upgrade password hash",
+			slog.String("username", employee.Username),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// clippedProfile обрезает поля, пришедшие от внешнего провайдера, до размеров колонок:
+// длинное имя в каталоге не должно мешать входу
+func clippedProfile(profile model.EmployeeProfile) model.EmployeeProfile {
+	profile.DisplayName = clip(profile.DisplayName, MaxDisplayNameLength)
+	if utf8.RuneCountInString(profile.Email) > MaxEmailLength {
+		profile.Email = ""
+	}
+	profile.Department = clip(profile.Department, MaxDepartmentLength)
+	profile.Team = clip(profile.Team, MaxTeamLength)
+	if utf8.RuneCountInString(profile.AvatarURL) > MaxAvatarURLLength {
+		profile.AvatarURL = ""
+	}
+	return profile
+}
+
+func clip(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch-api/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultDirectoryLimit = 20
+	MaxDirectoryLimit     = 100
+	MaxDirectoryQuery     = 64
+)
+
+var ErrInvalidDirectorySearch = errors.New("некорректный поисковый запрос")
+
+// DirectorySearch задаёт поиск по справочнику: Limit == 0 означает размер страницы по умолчанию
+type DirectorySearch struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+type DirectoryEntry struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Department  string `json:"department"`
+	Team        string `json:"team"`
+	AvatarURL   string `json:"avatarUrl"`
+}
+
+type DirectoryPage struct {
+	Employees []DirectoryEntry `json:"employees"`
+	Limit     int              `json:"limit"`
+	Offset    int              `json:"offset"`
+	Total     int64            `json:"total"`
+}
+
+type DirectoryService interface {
+	SearchEmployees(ctx context.Context, search DirectorySearch) (*DirectoryPage, error)
+}
+
+type DirectoryServiceImpl struct {
+	store repository.Store
+}
+
+func NewDirectoryService(store repository.Store) *DirectoryServiceImpl {
+	return &DirectoryServiceImpl{
+		store: store,
+	}
+}
+
+func (s *DirectoryServiceImpl) SearchEmployees(ctx context.Context, search DirectorySearch) (_ *DirectoryPage, err error) {
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Limit == 0 {
+		search.Limit = DefaultDirectoryLimit
+	}
+	if err := search.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "DirectoryService.SearchEmployees")
+	defer func() { endSpan(span, err) }()
+
+	employees, total, err := s.store.Employees().Search(ctx, repository.EmployeeSearch{
+		Query:  search.Query,
+		Limit:  search.Limit,
+		Offset: search.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DirectoryPage{
+		Employees: make([]DirectoryEntry, 0, len(employees)),
+		Limit:     search.Limit,
+		Offset:    search.Offset,
+		Total:     total,
+	}
+	for _, employee := range employees {
+		page.Employees = append(page.Employees, DirectoryEntry{
+			Username:    employee.Username,
+			DisplayName: employee.Name(),
+			Department:  employee.Profile.Department,
+			Team:        employee.Profile.Team,
+			AvatarURL:   employee.Profile.AvatarURL,
+		})
+	}
+	return page, nil
+}
+
+func (s DirectorySearch) validate() error {
+	if s.Limit < 1 || s.Limit > MaxDirectoryLimit {
+		return fmt.Errorf("%w: limit должен быть от 1 до %d", ErrInvalidDirectorySearch, MaxDirectoryLimit)
+	}
+	if s.Offset < 0 {
+		return fmt.Errorf("%w: offset не может быть отрицательным", ErrInvalidDirectorySearch)
+	}
+	if utf8.RuneCountInString(s.Query) > MaxDirectoryQuery {
+		return fmt.Errorf("%w: q длиннее %d символов", ErrInvalidDirectorySearch, MaxDirectoryQuery)
+	}
+	return nil
+}
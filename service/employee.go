@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch-api/model"
+	"merch-api/repository"
+	"sort"
+	"time"
+)
+
+var (
+	ErrEmployeeDeactivated       = errors.New("учётная запись деактивирована")
+	ErrEmployeeAlreadyActive     = errors.New("сотрудник уже активен")
+	ErrInvalidBalanceAction      = errors.New("баланс можно либо обнулить, либо передать другому сотруднику")
+	ErrTransferTargetDeactivated = errors.New("получатель остатка деактивирован")
+)
+
+type DeactivateRequest struct {
+	Username string
+	// ForfeitBalance обнуляет оставшийся баланс
+	ForfeitBalance bool
+	// TransferTo передаёт оставшийся баланс указанному сотруднику отдельным переводом
+	TransferTo string
+}
+
+type EmployeeStatus struct {
+	Username      string     `json:"username"`
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivatedAt,omitempty"`
+	Balance       int        `json:"balance"`
+	Forfeited     int        `json:"forfeited,omitempty"`
+	TransferredTo string     `json:"transferredTo,omitempty"`
+	Transferred   int        `json:"transferred,omitempty"`
+}
+
+type EmployeeService interface {
+	Deactivate(ctx context.Context, req DeactivateRequest) (*EmployeeStatus, error)
+	Reactivate(ctx context.Context, username string) (*EmployeeStatus, error)
+}
+
+type EmployeeServiceImpl struct {
+	store repository.Store
+	clock Clock
+}
+
+func NewEmployeeService(store repository.Store, clock Clock) *EmployeeServiceImpl {
+	return &EmployeeServiceImpl{
+		store: store,
+		clock: clock,
+	}
+}
+
+func (s *EmployeeServiceImpl) Deactivate(ctx context.Context, req DeactivateRequest) (status *EmployeeStatus, err error) {
+	if req.TransferTo != "" && (req.ForfeitBalance || req.TransferTo == req.Username) {
+		return nil, ErrInvalidBalanceAction
+	}
+
+	ctx, span := startSpan(ctx, "EmployeeService.Deactivate")
+	defer func() { endSpan(span, err) }()
+
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		usernames := []string{req.Username}
+		if req.TransferTo != "" {
+			usernames = append(usernames, req.TransferTo)
+		}
+		locked, err := lockEmployees(ctx, tx.Employees(), usernames...)
+		if err != nil {
+			return err
+		}
+
+		employee := locked[req.Username]
+		if !employee.Active() {
+			return ErrEmployeeDeactivated
+		}
+
+		deactivatedAt := s.clock.Now()
+		if err := tx.Employees().SetDeactivatedAt(ctx, employee.ID, &deactivatedAt); err != nil {
+			return err
+		}
+		status = &EmployeeStatus{Username: employee.Username, DeactivatedAt: &deactivatedAt, Balance: employee.Balance}
+
+		switch {
+		case req.ForfeitBalance && employee.Balance > 0:
+			if err := tx.Employees().UpdateBalance(ctx, employee.ID, 0); err != nil {
+				return err
+			}
+			status.Forfeited, status.Balance = employee.Balance, 0
+		case req.TransferTo != "":
+			target := locked[req.TransferTo]
+			if !target.Active() {
+				return fmt.Errorf("%w: %s", ErrTransferTargetDeactivated, target.Username)
+			}
+			status.TransferredTo = target.Username
+			if employee.Balance == 0 {
+				break
+			}
+			if err := tx.Employees().UpdateBalance(ctx, employee.ID, 0); err != nil {
+				return err
+			}
+			if err := tx.Employees().UpdateBalance(ctx, target.ID, target.Balance+employee.Balance); err != nil {
+				return err
+			}
+			if err := tx.Transactions().Create(ctx, &model.Transaction{
+				SenderID:   employee.ID,
+				ReceiverID: target.ID,
+				Amount:     employee.Balance,
+			}); err != nil {
+				return err
+			}
+			status.Transferred, status.Balance = employee.Balance, 0
+		}
+
+		var details string
+		switch {
+		case status.Forfeited > 0:
+			details = fmt.Sprintf("баланс %d обнулён", status.Forfeited)
+		case status.TransferredTo != "":
+			details = fmt.Sprintf("остаток %d передан %s", status.Transferred, status.TransferredTo)
+		}
+		return recordAudit(ctx, tx, model.AuditEvent{
+			Action:        AuditEmployeeDeactivate,
+			Target:        employee.Username,
+			BalanceBefore: balanceRef(employee.Balance),
+			BalanceAfter:  balanceRef(status.Balance),
+			Details:       details,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *EmployeeServiceImpl) Reactivate(ctx context.Context, username string) (status *EmployeeStatus, err error) {
+	ctx, span := startSpan(ctx, "EmployeeService.Reactivate")
+	defer func() { endSpan(span, err) }()
+
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		locked, err := lockEmployees(ctx, tx.Employees(), username)
+		if err != nil {
+			return err
+		}
+
+		employee := locked[username]
+		if employee.Active() {
+			return ErrEmployeeAlreadyActive
+		}
+		if err := tx.Employees().SetDeactivatedAt(ctx, employee.ID, nil); err != nil {
+			return err
+		}
+		status = &EmployeeStatus{Username: employee.Username, Active: true, Balance: employee.Balance}
+		return recordAudit(ctx, tx, model.AuditEvent{Action: AuditEmployeeReactivate, Target: employee.Username})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// employeeNotFoundError сохраняет имя ненайденного сотрудника и сводится к ErrEmployeeNotFound
+type employeeNotFoundError struct {
+	username string
+}
+
+func (e *employeeNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrEmployeeNotFound, e.username)
+}
+
+func (e *employeeNotFoundError) Unwrap() error {
+	return ErrEmployeeNotFound
+}
+
+// lockEmployees блокирует строки сотрудников в порядке имён,
+// чтобы встречные операции над одной парой не приводили к взаимной блокировке
+func lockEmployees(ctx context.Context, employees repository.EmployeeRepository, usernames ...string) (map[string]*model.Employee, error) {
+	order := append([]string(nil), usernames...)
+	sort.Strings(order)
+
+	locked := make(map[string]*model.Employee, len(order))
+	for _, username := range order {
+		if _, ok := locked[username]; ok {
+			continue
+		}
+		employee, err := employees.FindByUsernameForUpdate(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, &employeeNotFoundError{username: username}
+		}
+		if err != nil {
+			return nil, err
+		}
+		locked[username] = employee
+	}
+	return locked, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"merch-api/config"
+	"strings"
+)
+
+var errUnknownHashFormat = errors.New("неизвестный формат хеша пароля")
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// PasswordHasher хеширует пароли настроенным алгоритмом и проверяет хеши любого
+// поддерживаемого формата, чтобы старые хеши продолжали работать после смены настроек
+type PasswordHasher struct {
+	cfg config.PasswordHashConfig
+}
+
+func NewPasswordHasher(cfg config.PasswordHashConfig) *PasswordHasher {
+	return &PasswordHasher{
+		cfg: cfg,
+	}
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == config.HashArgon2id {
+		return h.hashArgon2id(password)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify сообщает, подходит ли пароль к хешу; ошибка означает повреждённый или неизвестный хеш.
+// Пустой хеш бывает у сотрудников, созданных внешним провайдером входа, и не подходит ни к чему
+func (h *PasswordHasher) Verify(hash, password string) (bool, error) {
+	if hash == "" {
+		return false, nil
+	}
+	if params, ok := parseArgon2id(hash); ok {
+		key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %v", errUnknownHashFormat, err)
+	}
+}
+
+// NeedsRehash сообщает, что хеш сделан другим алгоритмом или с параметрами слабее настроенных
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if params, ok := parseArgon2id(hash); ok {
+		want := h.argon2Params()
+		return h.cfg.Algorithm != config.HashArgon2id ||
+			params.memory < want.memory ||
+			params.iterations < want.iterations ||
+			params.parallelism < want.parallelism
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return h.cfg.Algorithm != config.HashBcrypt || cost < h.cfg.BcryptCost
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// argon2Params переводит проверенную конфигурацию в типы, которые принимает argon2.IDKey
+func (h *PasswordHasher) argon2Params() argon2idParams {
+	return argon2idParams{
+		memory:      uint32(h.cfg.Argon2.Memory),
+		iterations:  uint32(h.cfg.Argon2.Iterations),
+		parallelism: uint8(h.cfg.Argon2.Parallelism),
+	}
+}
+
+// hashArgon2id кодирует хеш в формате PHC: $argon2id$v=19$m=...,t=...,p=...$соль$ключ
+func (h *PasswordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать соль: %w", err)
+	}
+	p := h.argon2Params()
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func parseArgon2id(hash string) (argon2idParams, bool) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2idParams{}, false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2idParams{}, false
+	}
+	var params argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return argon2idParams{}, false
+	}
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2idParams{}, false
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return argon2idParams{}, false
+	}
+	return params, true
+}
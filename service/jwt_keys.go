@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"merch-api/config"
+	"slices"
+	"time"
+)
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N и E заполняются для RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve и X заполняются для Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWTKeys подписывает токены текущим ключом и по kid находит ключ для проверки подписи.
+// Ключ сменяется в ActiveFrom следующего и принимается ещё TTL после этого, пока не истекут
+// подписанные им токены. Ключи с будущим ActiveFrom публикуются в JWKS заранее, чтобы
+// другие сервисы успели их получить до начала ротации. Токены HS256 без kid проверяются
+// секретом до SecretAcceptUntil, если он задан
+type JWTKeys struct {
+	secret      []byte
+	secretUntil time.Time
+	keys        []config.SigningKey
+	ttl         time.Duration
+	issuer      string
+	audience    string
+	skew        time.Duration
+	clock       Clock
+}
+
+func NewJWTKeys(cfg config.JWTConfig, clock Clock) *JWTKeys {
+	return &JWTKeys{
+		secret:      cfg.Secret,
+		secretUntil: cfg.SecretAcceptUntil,
+		keys:        cfg.SigningKeys,
+		ttl:         cfg.TTL,
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		skew:        cfg.ClockSkew,
+		clock:       clock,
+	}
+}
+
+// ParseToken проверяет подпись и стандартные поля токена: iss и aud должны совпадать
+// с настройками, exp обязателен, nbf и iat не должны быть в будущем с учётом ClockSkew.
+// Токен без username или iat отклоняется с ErrInvalidTokenClaims
+func (k *JWTKeys) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, k.Keyfunc,
+		jwt.WithValidMethods(k.Algorithms()),
+		jwt.WithIssuer(k.issuer),
+		jwt.WithAudience(k.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(k.skew),
+		jwt.WithTimeFunc(k.clock.Now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Username == "" || claims.IssuedAt == nil {
+		return nil, ErrInvalidTokenClaims
+	}
+	return claims, nil
+}
+
+// RegisteredClaims заполняет стандартные поля нового токена: iss, aud, iat, nbf и exp через TTL
+func (k *JWTKeys) RegisteredClaims() jwt.RegisteredClaims {
+	now := k.clock.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    k.issuer,
+		Audience:  jwt.ClaimStrings{k.audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(k.ttl)),
+	}
+}
+
+// Sign подписывает claims текущим ключом, а без асимметричных ключей — секретом HS256
+func (k *JWTKeys) Sign(claims jwt.Claims) (string, error) {
+	key, ok := k.current()
+	if !ok {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc подходит для jwt.Parse: токены без kid проверяются секретом HS256, пока он
+// принимается, с kid — открытым ключом с этим kid, если алгоритм токена совпадает с алгоритмом ключа
+func (k *JWTKeys) Keyfunc(token *jwt.Token) (interface{}, error) {
+	now := k.clock.Now()
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && k.secretAccepted(now) {
+			return k.secret, nil
+		}
+		return nil, errors.New("в заголовке токена нет kid")
+	}
+
+	for i, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if k.retired(i, now) {
+			return nil, fmt.Errorf("ключ %s выведен из оборота", kid)
+		}
+		if token.Method.Alg() != signingMethod(key).Alg() {
+			return nil, fmt.Errorf("ключ %s не подписывает %s", kid, token.Method.Alg())
+		}
+		return key.Private.Public(), nil
+	}
+	return nil, fmt.Errorf("неизвестный ключ %s", kid)
+}
+
+// Algorithms перечисляет алгоритмы, которые принимает Keyfunc, для jwt.WithValidMethods
+func (k *JWTKeys) Algorithms() []string {
+	var algorithms []string
+	if k.secretAccepted(k.clock.Now()) {
+		algorithms = append(algorithms, jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range k.keys {
+		if alg := signingMethod(key).Alg(); !slices.Contains(algorithms, alg) {
+			algorithms = append(algorithms, alg)
+		}
+	}
+	return algorithms
+}
+
+// JWKS возвращает открытые ключи, которые сейчас принимаются или вступят в действие позже
+func (k *JWTKeys) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	now := k.clock.Now()
+	for i, key := range k.keys {
+		if k.retired(i, now) {
+			continue
+		}
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: signingMethod(key).Alg()}
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// current возвращает ключ с самым поздним наступившим ActiveFrom. Если ни один ключ ещё
+// не действует, подписывает секрет, а без него или после его срока — самый ранний ключ
+func (k *JWTKeys) current() (config.SigningKey, bool) {
+	if len(k.keys) == 0 {
+		return config.SigningKey{}, false
+	}
+	now := k.clock.Now()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].ActiveFrom.After(now) {
+			return k.keys[i], true
+		}
+	}
+	if k.secretAccepted(now) {
+		return config.SigningKey{}, false
+	}
+	return k.keys[0], true
+}
+
+// secretAccepted сообщает, что секрет задан и его срок SecretAcceptUntil не наступил
+func (k *JWTKeys) secretAccepted(now time.Time) bool {
+	return len(k.secret) > 0 && (k.secretUntil.IsZero() || now.Before(k.secretUntil))
+}
+
+// retired сообщает, что ключ сменён следующим больше TTL назад и подписанных им живых токенов нет
+func (k *JWTKeys) retired(i int, now time.Time) bool {
+	if i+1 >= len(k.keys) {
+		return false
+	}
+	return !now.Before(k.keys[i+1].ActiveFrom.Add(k.ttl))
+}
+
+func signingMethod(key config.SigningKey) jwt.SigningMethod {
+	if _, ok := key.Private.(ed25519.PrivateKey); ok {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"merch-api/config"
+	"merch-api/model"
+	"net"
+	"net/url"
+	"time"
+)
+
+// LDAPAuthenticator проверяет пароль bind'ом в корпоративный каталог: сначала находит запись
+// сотрудника по логину, затем подключается от её имени с введённым паролем
+type LDAPAuthenticator struct {
+	cfg config.LDAPConfig
+}
+
+// LDAPProvider — Identity.Provider сотрудников из LDAP
+const LDAPProvider = "ldap"
+
+func NewLDAPAuthenticator(cfg config.LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		cfg: cfg,
+	}
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (_ *Identity, err error) {
+	_, span := startSpan(ctx, "LDAPAuthenticator.Authenticate")
+	defer func() { endSpan(span, err) }()
+
+	// Bind с пустым паролем по RFC 4513 — анонимный вход, и многие серверы отвечают на него успехом
+	if password == "" {
+		return nil, ErrPasswordMismatch
+	}
+
+	timeout := a.cfg.Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	conn, err := a.dial(timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("не удалось подключиться к LDAP сервисной учётной записью: %w", err)
+		}
+	}
+
+	entry, err := a.findEntry(conn, username, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrPasswordMismatch
+		}
+		return nil, fmt.Errorf("не удалось проверить пароль в LDAP: %w", err)
+	}
+
+	identity := &Identity{
+		Username: entry.GetAttributeValue(a.cfg.UsernameAttribute),
+		Provider: LDAPProvider,
+		Subject:  entry.DN,
+		Profile: model.EmployeeProfile{
+			DisplayName: a.attribute(entry, a.cfg.DisplayNameAttribute),
+			Email:       a.attribute(entry, a.cfg.EmailAttribute),
+		},
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	return identity, nil
+}
+
+func (a *LDAPAuthenticator) dial(timeout time.Duration) (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к LDAP: %w", err)
+	}
+	conn.SetTimeout(timeout)
+
+	if a.cfg.StartTLS {
+		u, err := url.Parse(a.cfg.URL)
+		if err == nil {
+			err = conn.StartTLS(&tls.Config{ServerName: u.Hostname()})
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("не удалось включить StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// findEntry ищет ровно одну запись по логину; если записей нет или их несколько,
+// вход отклоняется как неверный пароль, чтобы не раскрывать, какие логины существуют
+func (a *LDAPAuthenticator) findEntry(conn *ldap.Conn, username string, timeout time.Duration) (*ldap.Entry, error) {
+	attributes := []string{a.cfg.UsernameAttribute}
+	for _, attr := range []string{a.cfg.DisplayNameAttribute, a.cfg.EmailAttribute} {
+		if attr != "" {
+			attributes = append(attributes, attr)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		attributes,
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("не удалось найти сотрудника в LDAP: %w", err)
+	}
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrPasswordMismatch
+	}
+	return result.Entries[0], nil
+}
+
+func (a *LDAPAuthenticator) attribute(entry *ldap.Entry, name string) string {
+	if name == "" {
+		return ""
+	}
+	return entry.GetAttributeValue(name)
+}
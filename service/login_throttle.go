@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch-api/config"
+	"merch-api/model"
+	"merch-api/repository"
+	"time"
+)
+
+var ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа")
+
+// TooManyAttemptsError сообщает, через сколько можно повторить вход
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("%s, повторите через %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginUnlocker снимает блокировку входа; используется администраторами
+type LoginUnlocker interface {
+	UnlockUser(ctx context.Context, username string) error
+	UnlockIP(ctx context.Context, ip string) error
+}
+
+// LoginThrottle считает неудачные входы подряд отдельно по логину и по IP-адресу и после
+// порога блокирует вход с экспоненциально растущей задержкой. Состояние хранится в БД,
+// поэтому блокировка действует на всех экземплярах сервиса.
+type LoginThrottle struct {
+	store repository.Store
+	cfg   config.LoginThrottleConfig
+	clock Clock
+}
+
+func NewLoginThrottle(store repository.Store, cfg config.LoginThrottleConfig, clock Clock) *LoginThrottle {
+	return &LoginThrottle{
+		store: store,
+		cfg:   cfg,
+		clock: clock,
+	}
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + username
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginAttempt — попытка входа, учтённая в счётчиках логина и IP до проверки пароля.
+// Её нужно завершить одним из RecordFailure, RecordSuccess или Release
+type LoginAttempt struct {
+	username string
+	// lockUntil — предварительная блокировка, которую Reserve ставит на время проверки
+	// попытки, достигшей порога; по нему же Release находит свою блокировку
+	lockUntil    time.Time
+	reservations []throttleReservation
+}
+
+type throttleReservation struct {
+	key      string
+	limit    int
+	failures int
+}
+
+type throttleLimit struct {
+	key   string
+	limit int
+}
+
+// Reserve учитывает попытку до проверки пароля: счётчик растёт в том же запросе, что
+// проверяет блокировку, поэтому параллельные запросы проверят не больше паролей, чем
+// позволяет порог. Если логин или IP заблокирован, возвращает TooManyAttemptsError
+func (t *LoginThrottle) Reserve(ctx context.Context, username, ip string) (*LoginAttempt, error) {
+	now := t.clock.Now()
+	// Postgres хранит микросекунды, а Release сравнивает блокировку на равенство
+	attempt := &LoginAttempt{username: username, lockUntil: now.Add(t.cfg.LockoutMax).Truncate(time.Microsecond)}
+	for _, limit := range t.limits(username, ip) {
+		failures, reserved, err := t.store.LoginThrottles().Reserve(ctx, limit.key, now, now.Add(-t.cfg.FailureWindow), limit.limit, attempt.lockUntil)
+		if err == nil && !reserved {
+			err = t.lockedError(ctx, limit.key, now)
+		}
+		if err != nil {
+			if releaseErr := t.Release(ctx, attempt); releaseErr != nil {
+				return nil, errors.Join(err, releaseErr)
+			}
+			return nil, err
+		}
+		attempt.reservations = append(attempt.reservations, throttleReservation{key: limit.key, limit: limit.limit, failures: failures})
+	}
+	return attempt, nil
+}
+
+// RecordFailure оставляет попытку в счётчиках и, если она достигла порога, заменяет
+// предварительную блокировку настоящей. Каждая блокировка попадает в журнал аудита один
+// раз, попытки во время неё — нет
+func (t *LoginThrottle) RecordFailure(ctx context.Context, attempt *LoginAttempt) error {
+	now := t.clock.Now()
+	for _, reservation := range attempt.reservations {
+		if reservation.failures < reservation.limit {
+			continue
+		}
+		lockout := t.lockout(reservation.failures - reservation.limit)
+		if err := t.store.LoginThrottles().LockUntil(ctx, reservation.key, now.Add(lockout)); err != nil {
+			return err
+		}
+		recordAuditBestEffort(ctx, t.store, model.AuditEvent{
+			Actor:   attempt.username,
+			Action:  AuditLoginLocked,
+			Target:  reservation.key,
+			Details: fmt.Sprintf("вход заблокирован на %s после %d неудачных попыток", lockout, reservation.failures),
+		})
+	}
+	return nil
+}
+
+// RecordSuccess сбрасывает счётчик логина, а в счётчике IP отменяет только эту попытку:
+// иначе перебор чужих паролей можно было бы разбавлять успешными входами в свою учётную запись.
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, attempt *LoginAttempt) error {
+	for _, reservation := range attempt.reservations {
+		var err error
+		if reservation.key == userThrottleKey(attempt.username) {
+			err = t.store.LoginThrottles().Delete(ctx, reservation.key)
+		} else {
+			err = t.store.LoginThrottles().Release(ctx, reservation.key, attempt.lockUntil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release отменяет попытку, исход которой не говорит о подборе пароля, например при
+// недоступном каталоге
+func (t *LoginThrottle) Release(ctx context.Context, attempt *LoginAttempt) error {
+	for _, reservation := range attempt.reservations {
+		if err := t.store.LoginThrottles().Release(ctx, reservation.key, attempt.lockUntil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockedError сообщает, через сколько снимется блокировка, из-за которой Reserve отказал
+func (t *LoginThrottle) lockedError(ctx context.Context, key string, now time.Time) error {
+	throttles, err := t.store.LoginThrottles().Find(ctx, key)
+	if err != nil {
+		return err
+	}
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil {
+			retryAfter = max(retryAfter, throttle.LockedUntil.Sub(now))
+		}
+	}
+	return &TooManyAttemptsError{RetryAfter: retryAfter}
+}
+
+func (t *LoginThrottle) UnlockUser(ctx context.Context, username string) error {
+	return t.unlock(ctx, userThrottleKey(username), model.AuditEvent{Action: AuditLoginUnlockEmployee, Target: username})
+}
+
+func (t *LoginThrottle) UnlockIP(ctx context.Context, ip string) error {
+	return t.unlock(ctx, ipThrottleKey(ip), model.AuditEvent{Action: AuditLoginUnlockIP, Target: ip})
+}
+
+func (t *LoginThrottle) unlock(ctx context.Context, key string, event model.AuditEvent) error {
+	return t.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		if err := tx.LoginThrottles().Delete(ctx, key); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, event)
+	})
+}
+
+// lockout удваивает блокировку за каждую неудачу сверх порога, но не больше LockoutMax
+func (t *LoginThrottle) lockout(overLimit int) time.Duration {
+	delay := t.cfg.LockoutBase
+	for i := 0; i < overLimit && delay < t.cfg.LockoutMax; i++ {
+		delay *= 2
+	}
+	return min(delay, t.cfg.LockoutMax)
+}
+
+func (t *LoginThrottle) limits(username, ip string) []throttleLimit {
+	limits := []throttleLimit{{key: userThrottleKey(username), limit: t.cfg.MaxFailuresPerUser}}
+	if ip != "" {
+		limits = append(limits, throttleLimit{key: ipThrottleKey(ip), limit: t.cfg.MaxFailuresPerIP})
+	}
+	return limits
+}
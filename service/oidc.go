@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"merch-api/config"
+	"merch-api/model"
+	"sync"
+)
+
+var (
+	ErrOIDCUnavailable  = errors.New("провайдер SSO недоступен")
+	ErrInvalidOIDCLogin = errors.New("вход через SSO не подтверждён")
+)
+
+// IdentityLogin выпускает токен сотруднику, подтверждённому внешним провайдером
+type IdentityLogin interface {
+	LoginWithIdentity(ctx context.Context, identity *Identity) (string, error)
+}
+
+// OIDCAuth — вход через SSO в два шага: редирект к провайдеру и обработка возврата с кодом
+type OIDCAuth interface {
+	BeginLogin(ctx context.Context) (*OIDCLogin, error)
+	CompleteLogin(ctx context.Context, code, verifier, nonce string) (string, error)
+}
+
+// OIDCLogin — начало входа через SSO: пользователя отправляют на URL, а State, Nonce и
+// Verifier нужно сохранить до возврата на callback, чтобы проверить ответ провайдера
+type OIDCLogin struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// OIDCService ведёт вход по authorization code flow с PKCE. Настройки провайдера
+// загружаются при первом входе, чтобы недоступный SSO не мешал запуску сервиса
+type OIDCService struct {
+	cfg    config.OIDCConfig
+	logins IdentityLogin
+	clock  Clock
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCService(cfg config.OIDCConfig, logins IdentityLogin, clock Clock) *OIDCService {
+	return &OIDCService{
+		cfg:    cfg,
+		logins: logins,
+		clock:  clock,
+	}
+}
+
+func (s *OIDCService) BeginLogin(ctx context.Context) (_ *OIDCLogin, err error) {
+	ctx, span := startSpan(ctx, "OIDCService.BeginLogin")
+	defer func() { endSpan(span, err) }()
+
+	oauth, _, err := s.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	login := &OIDCLogin{Verifier: oauth2.GenerateVerifier()}
+	if login.State, err = randomToken(); err != nil {
+		return nil, err
+	}
+	if login.Nonce, err = randomToken(); err != nil {
+		return nil, err
+	}
+	login.URL = oauth.AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier))
+	return login, nil
+}
+
+// CompleteLogin обменивает код на ID-токен, проверяет его подпись, аудиторию и nonce
+// и выпускает токен сервиса; сотрудник создаётся при первом входе
+func (s *OIDCService) CompleteLogin(ctx context.Context, code, verifier, nonce string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "OIDCService.CompleteLogin")
+	defer func() { endSpan(span, err) }()
+
+	oauth, provider, err := s.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return "", fmt.Errorf("%w: не удалось обменять код: %v", ErrInvalidOIDCLogin, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", fmt.Errorf("%w: в ответе нет id_token", ErrInvalidOIDCLogin)
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID, Now: s.clock.Now}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidOIDCLogin, err)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return "", fmt.Errorf("%w: nonce не совпадает", ErrInvalidOIDCLogin)
+	}
+
+	identity, err := s.identity(idToken)
+	if err != nil {
+		return "", err
+	}
+	return s.logins.LoginWithIdentity(ctx, identity)
+}
+
+func (s *OIDCService) identity(idToken *oidc.IDToken) (*Identity, error) {
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOIDCLogin, err)
+	}
+
+	username, _ := claims[s.cfg.UsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("%w: в токене нет %s", ErrInvalidOIDCLogin, s.cfg.UsernameClaim)
+	}
+	var profile model.EmployeeProfile
+	profile.DisplayName, _ = claims["name"].(string)
+	// Неподтверждённый адрес мог ввести кто угодно, поэтому в профиль он не попадает
+	if verified, _ := claims["email_verified"].(bool); verified {
+		profile.Email, _ = claims["email"].(string)
+	}
+	// Логин может меняться у провайдера, а sub в пределах iss — нет
+	return &Identity{Username: username, Provider: idToken.Issuer, Subject: idToken.Subject, Profile: profile}, nil
+}
+
+// oauthConfig загружает настройки провайдера один раз; при ошибке следующий вход попробует снова
+func (s *OIDCService) oauthConfig(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider == nil {
+		provider, err := oidc.NewProvider(ctx, s.cfg.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+		}
+		s.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     s.provider.Endpoint(),
+		Scopes:       s.cfg.Scopes,
+	}, s.provider, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать случайное значение: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
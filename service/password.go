@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"merch-api/model"
+	"merch-api/repository"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength — предел bcrypt: всё, что длиннее 72 байт, отбрасывается при хешировании
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrInvalidPassword   = errors.New("некорректный пароль")
+	ErrInvalidResetToken = errors.New("токен сброса пароля недействителен или истёк")
+)
+
+// TokenIssuer выпускает JWT для сотрудника; после смены пароля клиент получает новый токен взамен отозванных
+type TokenIssuer interface {
+	IssueToken(employee *model.Employee) (string, error)
+}
+
+type PasswordReset struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type PasswordService interface {
+	ChangePassword(ctx context.Context, username, oldPassword, newPassword string) (string, error)
+	IssueReset(ctx context.Context, username string) (*PasswordReset, error)
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type PasswordServiceImpl struct {
+	store    repository.Store
+	tokens   TokenIssuer
+	hasher   *PasswordHasher
+	clock    Clock
+	resetTTL time.Duration
+}
+
+func NewPasswordService(store repository.Store, tokens TokenIssuer, hasher *PasswordHasher, clock Clock, resetTTL time.Duration) *PasswordServiceImpl {
+	return &PasswordServiceImpl{
+		store:    store,
+		tokens:   tokens,
+		hasher:   hasher,
+		clock:    clock,
+		resetTTL: resetTTL,
+	}
+}
+
+// ChangePassword меняет пароль по старому паролю, отзывает все выданные токены и возвращает новый
+func (s *PasswordServiceImpl) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) (_ string, err error) {
+	if err := validatePassword(newPassword); err != nil {
+		return "", err
+	}
+
+	ctx, span := startSpan(ctx, "PasswordService.ChangePassword")
+	defer func() { endSpan(span, err) }()
+
+	var employee *model.Employee
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		employee, err = tx.Employees().FindByUsernameForUpdate(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+		}
+		if err != nil {
+			return err
+		}
+		match, err := s.hasher.Verify(employee.Password, oldPassword)
+		if err != nil {
+			return err
+		}
+		if !match {
+			return ErrPasswordMismatch
+		}
+		if err := s.setPassword(ctx, tx, employee, newPassword); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditEvent{Actor: employee.Username, Action: AuditPasswordChange, Target: employee.Username})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.tokens.IssueToken(employee)
+	if err != nil {
+		return "", ErrFailedToGenerateToken
+	}
+	return token, nil
+}
+
+// IssueReset выдаёт одноразовый токен сброса пароля; ранее выданные неиспользованные токены отзываются
+func (s *PasswordServiceImpl) IssueReset(ctx context.Context, username string) (reset *PasswordReset, err error) {
+	ctx, span := startSpan(ctx, "PasswordService.IssueReset")
+	defer func() { endSpan(span, err) }()
+
+	token, err := newResetToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		employee, err := tx.Employees().FindByUsernameForUpdate(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+		}
+		if err != nil {
+			return err
+		}
+		if !employee.Active() {
+			return ErrEmployeeDeactivated
+		}
+
+		now := s.clock.Now()
+		if err := tx.PasswordResets().RevokeByEmployee(ctx, employee.ID, now); err != nil {
+			return err
+		}
+		record := &model.PasswordResetToken{
+			EmployeeID: employee.ID,
+			TokenHash:  hashSecretToken(token),
+			ExpiresAt:  now.Add(s.resetTTL),
+		}
+		if err := tx.PasswordResets().Create(ctx, record); err != nil {
+			return err
+		}
+		reset = &PasswordReset{Token: token, ExpiresAt: record.ExpiresAt}
+		return recordAudit(ctx, tx, model.AuditEvent{Action: AuditPasswordResetIssue, Target: employee.Username})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+// ResetPassword задаёт новый пароль по токену сброса. Неизвестный, истёкший и уже использованный
+// токены неразличимы для клиента, чтобы по ответу нельзя было подбирать токены.
+func (s *PasswordServiceImpl) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(ctx, "PasswordService.ResetPassword")
+	defer func() { endSpan(span, err) }()
+
+	return s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		reset, err := tx.PasswordResets().FindByHashForUpdate(ctx, hashSecretToken(token))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if !reset.Usable(s.clock.Now()) {
+			return ErrInvalidResetToken
+		}
+
+		employee, err := tx.Employees().FindByIDForUpdate(ctx, reset.EmployeeID)
+		if err != nil {
+			return err
+		}
+		if !employee.Active() {
+			return ErrInvalidResetToken
+		}
+		if err := s.setPassword(ctx, tx, employee, newPassword); err != nil {
+			return err
+		}
+		// Сброс выполняется без входа, поэтому исполнитель — владелец токена сброса
+		return recordAudit(ctx, tx, model.AuditEvent{Actor: employee.Username, Action: AuditPasswordReset, Target: employee.Username})
+	})
+}
+
+// setPassword сохраняет новый хеш, отзывает токены сброса и обновляет версию токенов в employee
+func (s *PasswordServiceImpl) setPassword(ctx context.Context, tx repository.Store, employee *model.Employee, password string) error {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := tx.Employees().UpdatePassword(ctx, employee.ID, hash); err != nil {
+		return err
+	}
+	if err := tx.PasswordResets().RevokeByEmployee(ctx, employee.ID, s.clock.Now()); err != nil {
+		return err
+	}
+	employee.Password = hash
+	employee.TokenVersion++
+	return nil
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Errorf("%w: пароль короче %d символов", ErrInvalidPassword, MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("%w: пароль длиннее %d байт", ErrInvalidPassword, MaxPasswordLength)
+	}
+	return nil
+}
+
+func newResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать токен сброса пароля: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
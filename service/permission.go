@@ -0,0 +1,51 @@
+package service
+
+import (
+	"merch-api/model"
+	"slices"
+	"strings"
+)
+
+// Права (scope) на группы маршрутов. Их несут JWT в claim'е scope и токены доступа
+const (
+	ScopeInfoRead      = "info:read"
+	ScopeTransferWrite = "transfer:write"
+	ScopePurchaseWrite = "purchase:write"
+	// ScopeAdminEmployees — деактивация, сброс пароля и снятие блокировки входа
+	ScopeAdminEmployees = "admin:employees"
+	// ScopeAdminBalances — операции, которые списывают или переводят чужие монеты
+	ScopeAdminBalances = "admin:balances"
+	// ScopeAdminCatalog — управление каталогом мерча; маршрутов под него пока нет,
+	// но администраторы получают его заранее, чтобы не перевыпускать токены
+	ScopeAdminCatalog = "admin:catalog"
+	// ScopeAdminAudit — чтение и выгрузка журнала аудита
+	ScopeAdminAudit = "admin:audit"
+)
+
+var employeeScopes = []string{ScopeInfoRead, ScopePurchaseWrite, ScopeTransferWrite}
+
+var roleScopes = map[string][]string{
+	model.RoleEmployee: employeeScopes,
+	model.RoleAdmin:    append(slices.Clone(employeeScopes), ScopeAdminAudit, ScopeAdminBalances, ScopeAdminCatalog, ScopeAdminEmployees),
+}
+
+// ScopesForRole возвращает права, которые даёт роль; у неизвестной роли прав нет
+func ScopesForRole(role string) []string {
+	return slices.Clone(roleScopes[role])
+}
+
+// EffectiveScopes пересекает права из токена с правами текущей роли сотрудника: если роль
+// отобрали, уже выданный токен не сохраняет её права
+func EffectiveScopes(role string, tokenScopes []string) []string {
+	return slices.DeleteFunc(ScopesForRole(role), func(scope string) bool {
+		return !slices.Contains(tokenScopes, scope)
+	})
+}
+
+// ScopeList разбирает claim scope. JWT без scope выдан до появления прав и получает все права роли
+func (c *Claims) ScopeList(role string) []string {
+	if c.Scope == "" {
+		return ScopesForRole(role)
+	}
+	return strings.Fields(c.Scope)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal — сотрудник, от имени которого выполняется запрос; его кладёт в контекст JWTMiddleware
+type Principal struct {
+	EmployeeID uint
+	Username   string
+	Roles      []string
+	Scopes     []string
+	// AccessTokenID заполняется, если запрос пришёл с токеном доступа
+	AccessTokenID uint
+}
+
+// ViaAccessToken сообщает, что запрос выполняет бот или интеграция, а не сам сотрудник
+func (p Principal) ViaAccessToken() bool {
+	return p.AccessTokenID != 0
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// MissingScopes возвращает права из required, которых у сотрудника нет
+func (p Principal) MissingScopes(required ...string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !p.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext возвращает false для запросов без аутентификации
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
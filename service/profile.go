@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch-api/model"
+	"merch-api/repository"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxDisplayNameLength = 64
+	MaxEmailLength       = 254
+	MaxDepartmentLength  = 64
+	MaxTeamLength        = 64
+	MaxAvatarURLLength   = 512
+)
+
+var ErrInvalidProfile = errors.New("некорректный профиль")
+
+type Profile struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Email       string `json:"email"`
+	Department  string `json:"department"`
+	Team        string `json:"team"`
+	AvatarURL   string `json:"avatarUrl"`
+}
+
+// ProfileUpdate описывает частичное изменение: nil оставляет поле как есть, пустая строка очищает его
+type ProfileUpdate struct {
+	DisplayName *string
+	Email       *string
+	Department  *string
+	Team        *string
+	AvatarURL   *string
+}
+
+type ProfileService interface {
+	GetProfile(ctx context.Context, username string) (*Profile, error)
+	UpdateProfile(ctx context.Context, username string, update ProfileUpdate) (*Profile, error)
+}
+
+type ProfileServiceImpl struct {
+	store repository.Store
+}
+
+func NewProfileService(store repository.Store) *ProfileServiceImpl {
+	return &ProfileServiceImpl{
+		store: store,
+	}
+}
+
+func (s *ProfileServiceImpl) GetProfile(ctx context.Context, username string) (_ *Profile, err error) {
+	ctx, span := startSpan(ctx, "ProfileService.GetProfile")
+	defer func() { endSpan(span, err) }()
+
+	employee, err := s.store.Employees().FindByUsername(ctx, username)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newProfile(employee), nil
+}
+
+func (s *ProfileServiceImpl) UpdateProfile(ctx context.Context, username string, update ProfileUpdate) (profile *Profile, err error) {
+	if err := update.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "ProfileService.UpdateProfile")
+	defer func() { endSpan(span, err) }()
+
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		employee, err := tx.Employees().FindByUsernameForUpdate(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrEmployeeNotFound, username)
+		}
+		if err != nil {
+			return err
+		}
+
+		update.apply(&employee.Profile)
+		if err := tx.Employees().UpdateProfile(ctx, employee.ID, employee.Profile); err != nil {
+			return err
+		}
+		profile = newProfile(employee)
+		return recordAudit(ctx, tx, model.AuditEvent{
+			Actor:   employee.Username,
+			Action:  AuditProfileUpdate,
+			Target:  employee.Username,
+			Details: "поля: " + strings.Join(update.fields(), ", "),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func newProfile(employee *model.Employee) *Profile {
+	return &Profile{
+		Username:    employee.Username,
+		DisplayName: employee.Profile.DisplayName,
+		Email:       employee.Profile.Email,
+		Department:  employee.Profile.Department,
+		Team:        employee.Profile.Team,
+		AvatarURL:   employee.Profile.AvatarURL,
+	}
+}
+
+func (u *ProfileUpdate) apply(profile *model.EmployeeProfile) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = strings.TrimSpace(*src)
+		}
+	}
+	set(&profile.DisplayName, u.DisplayName)
+	set(&profile.Email, u.Email)
+	set(&profile.Department, u.Department)
+	set(&profile.Team, u.Team)
+	set(&profile.AvatarURL, u.AvatarURL)
+}
+
+// fields перечисляет переданные поля для журнала аудита; сами значения туда не пишутся
+func (u *ProfileUpdate) fields() []string {
+	var fields []string
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"displayName", u.DisplayName},
+		{"email", u.Email},
+		{"department", u.Department},
+		{"team", u.Team},
+		{"avatarUrl", u.AvatarURL},
+	} {
+		if field.value != nil {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+func (u *ProfileUpdate) validate() error {
+	checks := []struct {
+		field  string
+		value  *string
+		maxLen int
+	}{
+		{"displayName", u.DisplayName, MaxDisplayNameLength},
+		{"email", u.Email, MaxEmailLength},
+		{"department", u.Department, MaxDepartmentLength},
+		{"team", u.Team, MaxTeamLength},
+		{"avatarUrl", u.AvatarURL, MaxAvatarURLLength},
+	}
+	for _, check := range checks {
+		if check.value != nil && utf8.RuneCountInString(strings.TrimSpace(*check.value)) > check.maxLen {
+			return fmt.Errorf("%w: %s длиннее %d символов", ErrInvalidProfile, check.field, check.maxLen)
+		}
+	}
+
+	if u.Email != nil {
+		if email := strings.TrimSpace(*u.Email); email != "" {
+			address, err := mail.ParseAddress(email)
+			if err != nil || address.Address != email {
+				return fmt.Errorf("%w: некорректный email", ErrInvalidProfile)
+			}
+		}
+	}
+	if u.AvatarURL != nil {
+		if avatar := strings.TrimSpace(*u.AvatarURL); avatar != "" {
+			parsed, err := url.Parse(avatar)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("%w: avatarUrl должен быть http(s)-ссылкой", ErrInvalidProfile)
+			}
+		}
+	}
+	return nil
+}
@@ -1,63 +1,207 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"gorm.io/gorm"
+	"merch-api/metrics"
 	"merch-api/model"
+	"merch-api/repository"
+	"time"
+)
+
+const (
+	MaxPurchaseQuantity     = 100
+	MaxIdempotencyKeyLength = 64
+)
+
+var (
+	ErrMerchNotFound         = errors.New("товар не найден")
+	ErrEmployeeNotFound      = errors.New("пользователь не найден")
+	ErrInsufficientFunds     = errors.New("недостаточно монет для покупки")
+	ErrInvalidQuantity       = fmt.Errorf("количество должно быть от 1 до %d", MaxPurchaseQuantity)
+	ErrInvalidIdempotencyKey = fmt.Errorf("ключ идемпотентности длиннее %d символов", MaxIdempotencyKeyLength)
+	ErrIdempotencyKeyReused  = errors.New("ключ идемпотентности уже использован для другой покупки")
 )
 
 type PurchaseService interface {
-	PurchaseMerch(db *gorm.DB, username string, itemName string) (string, error)
+	PurchaseMerch(ctx context.Context, username string, itemName string) (string, error)
+	CreatePurchase(ctx context.Context, req PurchaseRequest) (*PurchaseResult, error)
 }
 
-type PurchaseServiceImpl struct{}
+type PurchaseServiceImpl struct {
+	store repository.Store
+}
 
-func NewPurchaseService() *PurchaseServiceImpl {
-	return &PurchaseServiceImpl{}
+func NewPurchaseService(store repository.Store) *PurchaseServiceImpl {
+	return &PurchaseServiceImpl{
+		store: store,
+	}
 }
 
-func (s *PurchaseServiceImpl) PurchaseMerch(db *gorm.DB, username string, itemName string) (string, error) {
-	var merch model.Merch
-	var employee model.Employee
+func (s *PurchaseServiceImpl) PurchaseMerch(ctx context.Context, username string, itemName string) (message string, err error) {
+	ctx, span := startSpan(ctx, "PurchaseService.PurchaseMerch")
+	defer func() { endSpan(span, err) }()
 
-	if err := db.Where("name = ?", itemName).First(&merch).Error; err != nil {
+	merch, err := s.store.Merch().FindByName(ctx, itemName)
+	if err != nil {
 		return "", fmt.Errorf("товар %s не найден", itemName)
 	}
 
-	tx := db.Begin()
-	if tx.Error != nil {
-		return "", tx.Error
-	}
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		employee, err := tx.Employees().FindByUsernameForUpdate(ctx, username)
+		if err != nil {
+			return fmt.Errorf("пользователь %s не найден", username)
+		}
 
-	if err := tx.Where("username = ?", username).First(&employee).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("пользователь %s не найден", username)
-	}
+		if employee.Balance < merch.Price {
+			return fmt.Errorf("недостаточно монет для покупки товара %s", itemName)
+		}
+
+		newBalance := employee.Balance - merch.Price
+		if err := tx.Employees().UpdateBalance(ctx, employee.ID, newBalance); err != nil {
+			return fmt.Errorf("не удалось обновить баланс сотрудника")
+		}
 
-	if employee.Balance < merch.Price {
-		tx.Rollback()
-		return "", fmt.Errorf("недостаточно монет для покупки товара %s", itemName)
+		purchase := model.Purchase{
+			EmployeeID: employee.ID,
+			MerchID:    merch.ID,
+			Quantity:   1,
+		}
+		if err := tx.Purchases().Create(ctx, &purchase); err != nil {
+			return fmt.Errorf("не удалось сохранить покупку")
+		}
+		return auditPurchase(ctx, tx, employee, purchase, merch.Name, newBalance)
+	})
+	if err != nil {
+		return "", err
 	}
+	metrics.PurchasesTotal.WithLabelValues(merch.Name).Inc()
 
-	newBalance := employee.Balance - merch.Price
-	employee.Balance = newBalance
-	if err := tx.Save(&employee).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось обновить баланс сотрудника")
+	return "Покупка успешна", nil
+}
+
+type PurchaseRequest struct {
+	Username       string
+	ItemName       string
+	Quantity       int
+	IdempotencyKey string
+}
+
+type PurchaseResource struct {
+	ID             uint      `json:"id"`
+	Item           string    `json:"item"`
+	Quantity       int       `json:"quantity"`
+	Price          int       `json:"price"`
+	Total          int       `json:"total"`
+	IdempotencyKey string    `json:"idempotencyKey,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type PurchaseResult struct {
+	Purchase PurchaseResource `json:"purchase"`
+	Balance  int              `json:"balance"`
+	// Replayed выставляется, когда покупка с таким ключом идемпотентности уже была
+	Replayed bool `json:"-"`
+}
+
+func (s *PurchaseServiceImpl) CreatePurchase(ctx context.Context, req PurchaseRequest) (result *PurchaseResult, err error) {
+	if req.Quantity < 1 || req.Quantity > MaxPurchaseQuantity {
+		return nil, ErrInvalidQuantity
+	}
+	if len(req.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
 	}
 
-	purchase := model.Purchase{
-		EmployeeID: employee.ID,
-		MerchID:    merch.ID,
+	ctx, span := startSpan(ctx, "PurchaseService.CreatePurchase")
+	defer func() { endSpan(span, err) }()
+
+	merch, err := s.store.Merch().FindByName(ctx, req.ItemName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMerchNotFound, req.ItemName)
 	}
-	if err := tx.Create(&purchase).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось сохранить покупку")
+
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		employee, err := tx.Employees().FindByUsernameForUpdate(ctx, req.Username)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrEmployeeNotFound, req.Username)
+		}
+
+		if req.IdempotencyKey != "" {
+			existing, err := tx.Purchases().FindByIdempotencyKey(ctx, employee.ID, req.IdempotencyKey)
+			if err == nil {
+				if existing.MerchID != merch.ID || existing.Quantity != req.Quantity {
+					return ErrIdempotencyKeyReused
+				}
+				result = newPurchaseResult(*existing, *merch, employee.Balance)
+				result.Replayed = true
+				return nil
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+		}
+
+		total := merch.Price * req.Quantity
+		if employee.Balance < total {
+			return fmt.Errorf("%w: %s", ErrInsufficientFunds, req.ItemName)
+		}
+
+		newBalance := employee.Balance - total
+		if err := tx.Employees().UpdateBalance(ctx, employee.ID, newBalance); err != nil {
+			return fmt.Errorf("не удалось обновить баланс сотрудника")
+		}
+
+		purchase := model.Purchase{
+			EmployeeID: employee.ID,
+			MerchID:    merch.ID,
+			Quantity:   req.Quantity,
+		}
+		if req.IdempotencyKey != "" {
+			purchase.IdempotencyKey = &req.IdempotencyKey
+		}
+		if err := tx.Purchases().Create(ctx, &purchase); err != nil {
+			return fmt.Errorf("не удалось сохранить покупку")
+		}
+		if err := auditPurchase(ctx, tx, employee, purchase, merch.Name, newBalance); err != nil {
+			return err
+		}
+
+		result = newPurchaseResult(purchase, *merch, newBalance)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось зафиксировать транзакцию: %v", err)
+	if !result.Replayed {
+		metrics.PurchasesTotal.WithLabelValues(merch.Name).Add(float64(result.Purchase.Quantity))
 	}
 
-	return "Покупка успешна", nil
+	return result, nil
+}
+
+func auditPurchase(ctx context.Context, tx repository.Store, employee *model.Employee, purchase model.Purchase, item string, newBalance int) error {
+	return recordAudit(ctx, tx, model.AuditEvent{
+		Actor:         employee.Username,
+		Action:        AuditPurchase,
+		Target:        item,
+		BalanceBefore: balanceRef(employee.Balance),
+		BalanceAfter:  balanceRef(newBalance),
+		Details:       fmt.Sprintf("покупка %d, количество %d", purchase.ID, purchase.Quantity),
+	})
+}
+
+func newPurchaseResult(purchase model.Purchase, merch model.Merch, balance int) *PurchaseResult {
+	resource := PurchaseResource{
+		ID:        purchase.ID,
+		Item:      merch.Name,
+		Quantity:  purchase.Quantity,
+		Price:     merch.Price,
+		Total:     merch.Price * purchase.Quantity,
+		CreatedAt: purchase.CreatedAt,
+	}
+	if purchase.IdempotencyKey != nil {
+		resource.IdempotencyKey = *purchase.IdempotencyKey
+	}
+	return &PurchaseResult{Purchase: resource, Balance: balance}
 }
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"merch-api/tracing"
+)
+
+// startSpan открывает span метода сервиса; запросы репозиториев с возвращённым контекстом
+// попадают в трассировку дочерними span'ами и отменяются вместе с запросом
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,69 +1,135 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"gorm.io/gorm"
+	"merch-api/metrics"
 	"merch-api/model"
+	"merch-api/repository"
 )
 
 type TransactionService interface {
-	SendCoins(db *gorm.DB, fromUsername, toUsername string, amount int) (string, error)
+	SendCoins(ctx context.Context, fromUsername, toUsername string, amount int) (string, error)
 }
 
-type TransactionServiceImpl struct{}
+type TransactionServiceImpl struct {
+	store repository.Store
+}
 
-func NewTransactionService() *TransactionServiceImpl {
-	return &TransactionServiceImpl{}
+func NewTransactionService(store repository.Store) *TransactionServiceImpl {
+	return &TransactionServiceImpl{
+		store: store,
+	}
 }
 
-func (s *TransactionServiceImpl) SendCoins(db *gorm.DB, fromUsername, toUsername string, amount int) (string, error) {
-	var fromEmployee model.Employee
-	var toEmployee model.Employee
+// transferError несёт причину отказа для метрики failed_transfers_total
+type transferError struct {
+	reason string
+	err    error
+}
 
-	if err := db.Where("username = ?", fromUsername).First(&fromEmployee).Error; err != nil {
-		return "", fmt.Errorf("пользователь %s не найден", fromUsername)
-	}
+func (e *transferError) Error() string {
+	return e.err.Error()
+}
 
-	if err := db.Where("username = ?", toUsername).First(&toEmployee).Error; err != nil {
-		return "", fmt.Errorf("пользователь %s не найден", toUsername)
-	}
+func (e *transferError) Unwrap() error {
+	return e.err
+}
 
-	if fromEmployee.Balance < amount {
-		return "", fmt.Errorf("недостаточно монет на балансе пользователя %s", fromUsername)
-	}
+func (s *TransactionServiceImpl) SendCoins(ctx context.Context, fromUsername, toUsername string, amount int) (message string, err error) {
+	ctx, span := startSpan(ctx, "TransactionService.SendCoins")
+	defer func() { endSpan(span, err) }()
 
-	tx := db.Begin()
-	if tx.Error != nil {
-		return "", tx.Error
+	// CHECK (amount > 0) в БД остаётся страховкой, но до неё такой запрос доходить не должен
+	if amount <= 0 {
+		return transferFailed(metrics.TransferFailureInvalidRequest, fmt.Errorf("%w: сумма перевода должна быть больше нуля", ErrInvalidInput))
 	}
-
-	newFromBalance := fromEmployee.Balance - amount
-	if err := tx.Model(&fromEmployee).Update("balance", newFromBalance).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось обновить баланс отправителя")
+	// lockEmployees заблокирует одну строку, и списание перезапишется зачислением со старым балансом
+	if fromUsername == toUsername {
+		err := &transferError{metrics.TransferFailureSelfTransfer, fmt.Errorf("%w: нельзя отправить монеты самому себе", ErrInvalidInput)}
+		return transferFailed(err.reason, err)
 	}
 
-	newToBalance := toEmployee.Balance + amount
-	if err := tx.Model(&toEmployee).Update("balance", newToBalance).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось обновить баланс получателя")
-	}
+	var newFromBalance, newToBalance int
+	err = s.store.WithinTransaction(ctx, func(tx repository.Store) error {
+		fromEmployee, toEmployee, err := lockTransferParties(ctx, tx.Employees(), fromUsername, toUsername)
+		if err != nil {
+			return err
+		}
 
-	transaction := model.Transaction{
-		SenderID:   fromEmployee.ID,
-		ReceiverID: toEmployee.ID,
-		Amount:     amount,
+		if fromEmployee.Balance < amount {
+			return &transferError{metrics.TransferFailureInsufficientFunds, fmt.Errorf("недостаточно монет на балансе пользователя %s", fromUsername)}
+		}
+
+		newFromBalance = fromEmployee.Balance - amount
+		if err := tx.Employees().UpdateBalance(ctx, fromEmployee.ID, newFromBalance); err != nil {
+			return &transferError{metrics.TransferFailureDatabase, fmt.Errorf("не удалось обновить баланс отправителя")}
+		}
+
+		newToBalance = toEmployee.Balance + amount
+		if err := tx.Employees().UpdateBalance(ctx, toEmployee.ID, newToBalance); err != nil {
+			return &transferError{metrics.TransferFailureDatabase, fmt.Errorf("не удалось обновить баланс получателя")}
+		}
+
+		transaction := model.Transaction{
+			SenderID:   fromEmployee.ID,
+			ReceiverID: toEmployee.ID,
+			Amount:     amount,
+		}
+		if err := tx.Transactions().Create(ctx, &transaction); err != nil {
+			return &transferError{metrics.TransferFailureDatabase, fmt.Errorf("не удалось создать запись о переводе")}
+		}
+		if err := recordAudit(ctx, tx, model.AuditEvent{
+			Actor:         fromEmployee.Username,
+			Action:        AuditTransfer,
+			Target:        toEmployee.Username,
+			BalanceBefore: balanceRef(fromEmployee.Balance),
+			BalanceAfter:  balanceRef(newFromBalance),
+			Details:       fmt.Sprintf("сумма %d, баланс получателя %d -> %d", amount, toEmployee.Balance, newToBalance),
+		}); err != nil {
+			return &transferError{metrics.TransferFailureDatabase, fmt.Errorf("не удалось записать перевод в журнал аудита")}
+		}
+		return nil
+	})
+	if err != nil {
+		var transferErr *transferError
+		if errors.As(err, &transferErr) {
+			return transferFailed(transferErr.reason, transferErr.err)
+		}
+		return transferFailed(metrics.TransferFailureDatabase, fmt.Errorf("не удалось зафиксировать транзакцию: %v", err))
 	}
+	metrics.CoinsTransferredTotal.Add(float64(amount))
 
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось создать запись о переводе")
+	return fmt.Sprintf("Перевод успешен! Кол-во: %d монет пользователю %s. Новый баланс: отправитель %d, получатель %d", amount, toUsername, newFromBalance, newToBalance), nil
+}
+
+// lockTransferParties блокирует отправителя и получателя и проверяет, что оба активны
+func lockTransferParties(ctx context.Context, employees repository.EmployeeRepository, fromUsername, toUsername string) (from, to *model.Employee, err error) {
+	locked, err := lockEmployees(ctx, employees, fromUsername, toUsername)
+	if err != nil {
+		var notFound *employeeNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, nil, &transferError{metrics.TransferFailureDatabase, err}
+		}
+		reason := metrics.TransferFailureReceiverNotFound
+		if notFound.username == fromUsername {
+			reason = metrics.TransferFailureSenderNotFound
+		}
+		return nil, nil, &transferError{reason, fmt.Errorf("пользователь %s не найден", notFound.username)}
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("не удалось зафиксировать транзакцию: %v", err)
+	from, to = locked[fromUsername], locked[toUsername]
+	if !from.Active() {
+		return nil, nil, &transferError{metrics.TransferFailureSenderDeactivated, ErrEmployeeDeactivated}
+	}
+	if !to.Active() {
+		return nil, nil, &transferError{metrics.TransferFailureReceiverDeactivated, fmt.Errorf("пользователь %s деактивирован и не может получать монеты", toUsername)}
 	}
+	return from, to, nil
+}
 
-	return fmt.Sprintf("Перевод успешен! Кол-во: %d монет пользователю %s. Новый баланс: отправитель %d, получатель %d", amount, toUsername, newFromBalance, newToBalance), nil
+func transferFailed(reason string, err error) (string, error) {
+	metrics.FailedTransfersTotal.WithLabelValues(reason).Inc()
+	return "", err
 }
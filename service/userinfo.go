@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"gorm.io/gorm"
-	"merch-api/model"
+	"merch-api/repository"
 )
 
 type InventoryItem struct {
@@ -11,12 +11,14 @@ type InventoryItem struct {
 	Quantity int    `json:"quantity"`
 }
 type ReceivedCoinsItem struct {
-	FromUser string `json:"fromUser" gorm:"column:fromUser"`
-	Amount   int    `json:"amount"`
+	FromUser            string `json:"fromUser"`
+	FromUserDisplayName string `json:"fromUserDisplayName"`
+	Amount              int    `json:"amount"`
 }
 type SentCoinsItem struct {
-	ToUser string `json:"toUser" gorm:"column:toUser"`
-	Amount int    `json:"amount"`
+	ToUser            string `json:"toUser"`
+	ToUserDisplayName string `json:"toUserDisplayName"`
+	Amount            int    `json:"amount"`
 }
 type CoinHistoryItem struct {
 	Received []ReceivedCoinsItem `json:"received"`
@@ -30,48 +32,70 @@ type UserInfo struct {
 }
 
 type UserInfoService interface {
-	GetUserInfo(db *gorm.DB, username string) (UserInfo, error)
+	GetUserInfo(ctx context.Context, username string) (UserInfo, error)
 }
 
-type UserInfoServiceImpl struct{}
+type UserInfoServiceImpl struct {
+	store repository.Store
+}
 
-func NewUserInfoService() *UserInfoServiceImpl {
-	return &UserInfoServiceImpl{}
+func NewUserInfoService(store repository.Store) *UserInfoServiceImpl {
+	return &UserInfoServiceImpl{
+		store: store,
+	}
 }
 
-func (s *UserInfoServiceImpl) GetUserInfo(db *gorm.DB, username string) (UserInfo, error) {
+func (s *UserInfoServiceImpl) GetUserInfo(ctx context.Context, username string) (_ UserInfo, err error) {
+	ctx, span := startSpan(ctx, "UserInfoService.GetUserInfo")
+	defer func() { endSpan(span, err) }()
+
 	var userInfo UserInfo
 
-	var employee model.Employee
-	if err := db.Where("username = ?", username).First(&employee).Error; err != nil {
+	employee, err := s.store.Employees().FindByUsername(ctx, username)
+	if err != nil {
 		return userInfo, fmt.Errorf("пользователь %s не найден", username)
 	}
 	userInfo.Coins = employee.Balance
 
-	if err := db.Table("purchase").
-		Select("merch.name as type, COUNT(purchase.id) as quantity").
-		Joins("JOIN merch ON merch.id = purchase.merch_id").
-		Where("purchase.employee_id = ?", employee.ID).
-		Group("merch.name").
-		Scan(&userInfo.Inventory).Error; err != nil {
+	inventory, err := s.store.Purchases().InventoryByEmployee(ctx, employee.ID)
+	if err != nil {
 		return userInfo, fmt.Errorf("не удалось получить инвентарь пользователя: %v", err)
 	}
+	for _, row := range inventory {
+		userInfo.Inventory = append(userInfo.Inventory, InventoryItem{Type: row.Type, Quantity: row.Quantity})
+	}
 
-	if err := db.Table("transaction").
-		Select("employee.username as \"toUser\", transaction.amount").
-		Joins("JOIN employee employee ON transaction.receiver_id = employee.id").
-		Where("transaction.sender_id = ?", employee.ID).
-		Scan(&userInfo.CoinHistory.Sent).Error; err != nil {
+	sent, err := s.store.Transactions().SentBy(ctx, employee.ID)
+	if err != nil {
 		return userInfo, fmt.Errorf("не удалось получить отправленные транзакции пользователя: %v", err)
 	}
+	for _, transfer := range sent {
+		userInfo.CoinHistory.Sent = append(userInfo.CoinHistory.Sent, SentCoinsItem{
+			ToUser:            transfer.Username,
+			ToUserDisplayName: displayName(transfer),
+			Amount:            transfer.Amount,
+		})
+	}
 
-	if err := db.Debug().Table("transaction").
-		Select("employee.username as \"fromUser\", transaction.amount").
-		Joins("JOIN employee employee ON transaction.sender_id = employee.id").
-		Where("transaction.receiver_id = ?", employee.ID).
-		Scan(&userInfo.CoinHistory.Received).Error; err != nil {
+	received, err := s.store.Transactions().ReceivedBy(ctx, employee.ID)
+	if err != nil {
 		return userInfo, fmt.Errorf("не удалось получить полученные транзакции пользователя: %v", err)
 	}
+	for _, transfer := range received {
+		userInfo.CoinHistory.Received = append(userInfo.CoinHistory.Received, ReceivedCoinsItem{
+			FromUser:            transfer.Username,
+			FromUserDisplayName: displayName(transfer),
+			Amount:              transfer.Amount,
+		})
+	}
 
 	return userInfo, nil
 }
+
+// displayName повторяет model.Employee.Name: без отображаемого имени показывается логин
+func displayName(transfer repository.CoinTransfer) string {
+	if transfer.DisplayName != "" {
+		return transfer.DisplayName
+	}
+	return transfer.Username
+}
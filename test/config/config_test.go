@@ -0,0 +1,346 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"merch-api/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const validSecret = "4937045454f893df5bd8aff7a3aba8fc"
+
+func setValidEnv(t *testing.T) {
+	t.Setenv("DB_USER", "merchuser")
+	t.Setenv("DB_NAME", "merchdb")
+	t.Setenv("JWT_SECRET", validSecret)
+}
+
+func TestLoad_FromEnv(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("HTTP_PORT", "9000")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "30s")
+	t.Setenv("LEGACY_BUY_GET_ENABLED", "false")
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	if err != nil {
+		t.Fatalf("ошибка при загрузке конфигурации: %v", err)
+	}
+
+	assert.Equal(t, "9000", cfg.HTTP.Port)
+	assert.Equal(t, 30*time.Second, cfg.HTTP.WriteTimeout)
+	assert.Equal(t, []byte(validSecret), cfg.JWT.Secret)
+	assert.Equal(t, 24*time.Hour, cfg.JWT.TTL)
+	assert.False(t, cfg.Features.LegacyBuyGet)
+	assert.Equal(t, "user=merchuser password= dbname=merchdb host=localhost port=5432 sslmode=disable", cfg.DB.DSN())
+}
+
+func TestLoad_EnvFileAndFlags(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	content := "DB_USER=fileuser\nDB_NAME=filedb\nJWT_SECRET=" + validSecret + "\nHTTP_PORT=7000\n"
+	if err := os.WriteFile(envFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("ошибка при записи env-файла: %v", err)
+	}
+	for _, key := range []string{"DB_USER", "DB_NAME", "JWT_SECRET", "HTTP_PORT"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	cfg, err := config.Load([]string{"-env-file", envFile, "-http-port", "7100", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("ошибка при загрузке конфигурации: %v", err)
+	}
+
+	assert.Equal(t, "fileuser", cfg.DB.User)
+	assert.Equal(t, "7100", cfg.HTTP.Port)
+	assert.Equal(t, "debug", cfg.Log.Level)
+}
+
+func TestLoad_MissingExplicitEnvFile(t *testing.T) {
+	setValidEnv(t)
+
+	_, err := config.Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+	assert.Error(t, err)
+}
+
+func TestLoad_RejectsEmptySecret(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("JWT_SECRET", "")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_SECRET не задан")
+}
+
+func TestLoad_RejectsShortSecret(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("JWT_SECRET", "short")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_SECRET короче")
+}
+
+func TestLoad_RejectsConflictingLegacySecret(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("JWT_SECRET_KEY", "another-secret-another-secret-another")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_SECRET_KEY устарела")
+}
+
+func TestLoad_RejectsInvalidDuration(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("HTTP_READ_TIMEOUT", "ten seconds")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "HTTP_READ_TIMEOUT")
+}
+
+func TestLoad_RejectsRequestTimeoutNotBelowWriteTimeout(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("HTTP_WRITE_TIMEOUT", "5s")
+	t.Setenv("HTTP_REQUEST_TIMEOUT", "5s")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "HTTP_REQUEST_TIMEOUT")
+}
+
+func TestLoadDB_DoesNotRequireJWTSecret(t *testing.T) {
+	t.Setenv("DB_USER", "merchuser")
+	t.Setenv("DB_NAME", "merchdb")
+	t.Setenv("JWT_SECRET", "")
+
+	db, args, err := config.LoadDB([]string{"-env-file", os.DevNull, "down", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "merchuser", db.User)
+	assert.Equal(t, []string{"down", "2"}, args)
+}
+
+func TestLoad_PasswordResetTTL(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("PASSWORD_RESET_TTL", "2h")
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, cfg.Auth.PasswordResetTTL)
+
+	t.Setenv("PASSWORD_RESET_TTL", "-1h")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "PASSWORD_RESET_TTL")
+}
+
+func TestLoad_LoginThrottleAndTrustedProxies(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("AUTH_MAX_FAILURES_PER_USER", "3")
+	t.Setenv("AUTH_LOCKOUT_MAX", "1h")
+	t.Setenv("HTTP_TRUSTED_PROXIES", "10.0.0.1, 192.168.0.0/16,")
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.Auth.Throttle.MaxFailuresPerUser)
+	assert.Equal(t, 50, cfg.Auth.Throttle.MaxFailuresPerIP)
+	assert.Equal(t, time.Hour, cfg.Auth.Throttle.LockoutMax)
+	assert.Equal(t, []string{"10.0.0.1", "192.168.0.0/16"}, cfg.HTTP.TrustedProxies)
+}
+
+func TestLoad_RejectsInvalidLoginThrottle(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("AUTH_MAX_FAILURES_PER_USER", "five")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "AUTH_MAX_FAILURES_PER_USER")
+
+	t.Setenv("AUTH_MAX_FAILURES_PER_USER", "5")
+	t.Setenv("AUTH_LOCKOUT_BASE", "10m")
+	t.Setenv("AUTH_LOCKOUT_MAX", "1m")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "AUTH_LOCKOUT_MAX")
+
+	t.Setenv("AUTH_LOCKOUT_BASE", "")
+	t.Setenv("AUTH_LOCKOUT_MAX", "")
+	t.Setenv("HTTP_TRUSTED_PROXIES", "proxy.local")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "HTTP_TRUSTED_PROXIES")
+}
+
+func TestLoad_PasswordHash(t *testing.T) {
+	setValidEnv(t)
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, config.HashBcrypt, cfg.Auth.PasswordHash.Algorithm)
+	assert.Equal(t, 12, cfg.Auth.PasswordHash.BcryptCost)
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "argon2id")
+	t.Setenv("ARGON2_MEMORY_KIB", "19456")
+	cfg, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, config.HashArgon2id, cfg.Auth.PasswordHash.Algorithm)
+	assert.Equal(t, 19456, cfg.Auth.PasswordHash.Argon2.Memory)
+
+	t.Setenv("ARGON2_PARALLELISM", "300")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "ARGON2_PARALLELISM")
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "md5")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "PASSWORD_HASH_ALGORITHM")
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("BCRYPT_COST", "3")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "BCRYPT_COST")
+}
+
+func TestLoad_AuthProviders(t *testing.T) {
+	setValidEnv(t)
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, config.ProviderLocal, cfg.Auth.Provider)
+	assert.False(t, cfg.Auth.OIDC.Enabled())
+
+	t.Setenv("AUTH_PROVIDER", "ldap")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "LDAP_URL")
+
+	t.Setenv("LDAP_URL", "ldaps://ldap.example.com")
+	t.Setenv("LDAP_BASE_DN", "ou=people,dc=example,dc=com")
+	cfg, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, "(uid=%s)", cfg.Auth.LDAP.UserFilter)
+	assert.Equal(t, 5*time.Second, cfg.Auth.LDAP.Timeout)
+
+	t.Setenv("LDAP_USER_FILTER", "(uid=alice)")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "LDAP_USER_FILTER")
+
+	t.Setenv("AUTH_PROVIDER", "kerberos")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "AUTH_PROVIDER")
+}
+
+func TestLoad_OIDC(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("OIDC_ISSUER", "https://sso.example.com")
+
+	_, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "OIDC_CLIENT_ID")
+
+	t.Setenv("OIDC_CLIENT_ID", "merch-api")
+	t.Setenv("OIDC_REDIRECT_URL", "https://merch.example.com/api/auth/oidc/callback")
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.OIDC.Enabled())
+	assert.Equal(t, []string{"openid", "profile", "email"}, cfg.Auth.OIDC.Scopes)
+	assert.Equal(t, "preferred_username", cfg.Auth.OIDC.UsernameClaim)
+	assert.Len(t, cfg.Warnings(), 1)
+	assert.Contains(t, cfg.Warnings()[0], "OIDC_USERNAME_CLAIM")
+
+	t.Setenv("OIDC_USERNAME_CLAIM", "email")
+	cfg, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Warnings())
+
+	t.Setenv("OIDC_SCOPES", "profile,email")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "OIDC_SCOPES")
+}
+
+func writeKeyFile(t *testing.T, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestLoad_JWTKeys(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("JWT_SECRET", "")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	rsaPath := writeKeyFile(t, "rsa.pem", rsaKey)
+	edPath := writeKeyFile(t, "ed.pem", edKey)
+
+	t.Setenv("JWT_KEYS", "ed-2="+edPath+"@2025-08-01T00:00:00Z, rsa-1="+rsaPath)
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.JWT.Secret)
+	if assert.Len(t, cfg.JWT.SigningKeys, 2) {
+		assert.Equal(t, "rsa-1", cfg.JWT.SigningKeys[0].ID)
+		assert.True(t, cfg.JWT.SigningKeys[0].ActiveFrom.IsZero())
+		assert.Equal(t, "ed-2", cfg.JWT.SigningKeys[1].ID)
+		assert.Equal(t, time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), cfg.JWT.SigningKeys[1].ActiveFrom)
+		assert.True(t, edKey.Equal(cfg.JWT.SigningKeys[1].Private))
+	}
+
+	for _, value := range []string{
+		"rsa-1=" + filepath.Join(t.TempDir(), "missing.pem"),
+		"rsa 1=" + rsaPath,
+		"rsa-1=" + rsaPath + "@tomorrow",
+		"rsa-1=" + rsaPath + ",rsa-1=" + edPath + "@2025-08-01T00:00:00Z",
+		"rsa-1=" + rsaPath + ",ed-2=" + edPath,
+	} {
+		t.Setenv("JWT_KEYS", value)
+		_, err = config.Load([]string{"-env-file", os.DevNull})
+		assert.ErrorContains(t, err, "JWT_KEYS", value)
+	}
+
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	t.Setenv("JWT_KEYS", "weak="+writeKeyFile(t, "weak.pem", weakKey))
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "2048")
+}
+
+func TestLoad_JWTSecretAcceptUntil(t *testing.T) {
+	setValidEnv(t)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	t.Setenv("JWT_KEYS", "ed-1="+writeKeyFile(t, "ed.pem", edKey))
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.True(t, cfg.JWT.SecretAcceptUntil.IsZero())
+	if assert.Len(t, cfg.Warnings(), 1) {
+		assert.Contains(t, cfg.Warnings()[0], "JWT_SECRET_ACCEPT_UNTIL")
+	}
+
+	t.Setenv("JWT_SECRET_ACCEPT_UNTIL", "2025-08-02T00:00:00Z")
+	cfg, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 8, 2, 0, 0, 0, 0, time.UTC), cfg.JWT.SecretAcceptUntil)
+	assert.Empty(t, cfg.Warnings())
+
+	t.Setenv("JWT_SECRET_ACCEPT_UNTIL", "tomorrow")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_SECRET_ACCEPT_UNTIL")
+
+	t.Setenv("JWT_SECRET_ACCEPT_UNTIL", "2025-08-02T00:00:00Z")
+	t.Setenv("JWT_KEYS", "")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_SECRET_ACCEPT_UNTIL")
+}
+
+func TestLoad_JWTClaims(t *testing.T) {
+	setValidEnv(t)
+
+	cfg, err := config.Load([]string{"-env-file", os.DevNull})
+	assert.NoError(t, err)
+	assert.Equal(t, "merch-api", cfg.JWT.Issuer)
+	assert.Equal(t, "merch-api", cfg.JWT.Audience)
+	assert.Equal(t, 30*time.Second, cfg.JWT.ClockSkew)
+
+	t.Setenv("JWT_CLOCK_SKEW", "1h")
+	_, err = config.Load([]string{"-env-file", os.DevNull})
+	assert.ErrorContains(t, err, "JWT_CLOCK_SKEW")
+}
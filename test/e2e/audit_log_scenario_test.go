@@ -0,0 +1,11 @@
+package e2e
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAuditLog_RejectsTruncate_E2E(t *testing.T) {
+	err := db.Exec("TRUNCATE TABLE audit_log").Error
+	assert.ErrorContains(t, err, "audit_log")
+}
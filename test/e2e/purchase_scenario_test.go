@@ -3,13 +3,12 @@ package e2e
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"merch-api/config"
 	model2 "merch-api/model"
 	router2 "merch-api/router"
 	"net/http"
@@ -20,6 +19,7 @@ import (
 )
 
 var db *gorm.DB
+var cfg *config.Config
 
 func TestMain(m *testing.M) {
 	wd, err := os.Getwd()
@@ -28,20 +28,12 @@ func TestMain(m *testing.M) {
 	}
 
 	envFile := filepath.Join(wd, "../..", ".env.test")
-	err = godotenv.Load(envFile)
+	cfg, err = config.Load([]string{"-env-file", envFile})
 	if err != nil {
-		log.Fatalf("Ошибка при загрузке .env файла: %v", err)
+		log.Fatalf("Ошибка при загрузке конфигурации: %v", err)
 	}
-	dsn := fmt.Sprintf(
-		"user=%s password=%s dbname=%s host=%s port=%s sslmode=disable",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-	)
-
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+
+	db, err = gorm.Open(postgres.Open(cfg.DB.DSN()), &gorm.Config{})
 	if err != nil {
 		panic("Failed to connect to db: " + err.Error())
 	}
@@ -58,7 +50,7 @@ func resetTables() {
 
 func TestPurchaseMerch_E2E(t *testing.T) {
 	resetTables()
-	router := router2.SetupRouter(db)
+	router := router2.SetupRouter(db, cfg)
 	hashedPswd, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
 	merchName := "cup"
 
@@ -103,3 +95,48 @@ func TestPurchaseMerch_E2E(t *testing.T) {
 	db.First(&purchase, "employee_id = ? AND merch_id = ?", employee.ID, merch.ID)
 	assert.NotNil(t, purchase)
 }
+
+func TestCreatePurchase_E2E(t *testing.T) {
+	resetTables()
+	router := router2.SetupRouter(db, cfg)
+	hashedPswd, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	merchName := "cup"
+
+	db.Create(&model2.Employee{
+		Username: "test_user1",
+		Password: string(hashedPswd),
+		Balance:  100,
+	})
+	db.FirstOrCreate(&model2.Merch{
+		Name:  merchName,
+		Price: 20,
+	})
+
+	authRequestBody, _ := json.Marshal(map[string]string{"username": "test_user1", "password": "password123"})
+	authReq := httptest.NewRequest(http.MethodPost, "/api/auth", bytes.NewBuffer(authRequestBody))
+	authReq.Header.Set("Content-Type", "application/json")
+
+	authW := httptest.NewRecorder()
+	router.ServeHTTP(authW, authReq)
+	assert.Equal(t, http.StatusOK, authW.Code)
+	var authResponse map[string]interface{}
+	err = json.NewDecoder(authW.Body).Decode(&authResponse)
+	assert.NoError(t, err)
+	token := authResponse["token"].(string)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{"item": merchName, "quantity": 2, "idempotencyKey": "e2e-key"})
+	for _, expectedStatus := range []int{http.StatusCreated, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/api/purchases", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expectedStatus, w.Code)
+		assert.Contains(t, w.Body.String(), `"balance":60`)
+	}
+
+	var employee model2.Employee
+	db.First(&employee, "username = ?", "test_user1")
+	assert.Equal(t, 60, employee.Balance)
+}
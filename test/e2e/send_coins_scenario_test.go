@@ -14,7 +14,7 @@ import (
 
 func TestSendCoins_E2E(t *testing.T) {
 	resetTables()
-	router := router2.SetupRouter(db)
+	router := router2.SetupRouter(db, cfg)
 	hashedPswd1, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
 	hashedPswd2, err := bcrypt.GenerateFromPassword([]byte("password456"), bcrypt.MinCost)
 
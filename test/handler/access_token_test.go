@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenService) CreateToken(ctx context.Context, username string, req service.CreateAccessTokenRequest) (*service.IssuedAccessToken, error) {
+	args := m.Called(ctx, username, req)
+	token, _ := args.Get(0).(*service.IssuedAccessToken)
+	return token, args.Error(1)
+}
+
+func (m *MockAccessTokenService) ListTokens(ctx context.Context, username string) ([]service.AccessTokenInfo, error) {
+	args := m.Called(ctx, username)
+	tokens, _ := args.Get(0).([]service.AccessTokenInfo)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAccessTokenService) RevokeToken(ctx context.Context, username string, id uint) error {
+	args := m.Called(ctx, username, id)
+	return args.Error(0)
+}
+
+func newAccessTokenRequestContext(method, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "alice")
+	c.Request = httptest.NewRequest(method, "/me/tokens", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestCreateAccessTokenHandler(t *testing.T) {
+	createdAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(30 * 24 * time.Hour)
+	mockService := new(MockAccessTokenService)
+	mockService.On("CreateToken", mock.Anything, "alice", service.CreateAccessTokenRequest{
+		Name:      "slack-bot",
+		Scopes:    []string{service.ScopeInfoRead},
+		ExpiresAt: &expiresAt,
+	}).Return(&service.IssuedAccessToken{
+		AccessTokenInfo: service.AccessTokenInfo{
+			ID:        7,
+			Name:      "slack-bot",
+			Prefix:    "mpat_abcdef",
+			Scopes:    []string{service.ScopeInfoRead},
+			CreatedAt: createdAt,
+			ExpiresAt: &expiresAt,
+		},
+		Token: "mpat_abcdef-secret",
+	}, nil)
+
+	c, w := newAccessTokenRequestContext(http.MethodPost, `{"name":"slack-bot","scopes":["info:read"],"expiresAt":"2025-05-31T12:00:00Z"}`)
+	handler.NewAccessTokenHandler(mockService).CreateToken(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{
+		"id": 7,
+		"name": "slack-bot",
+		"prefix": "mpat_abcdef",
+		"scopes": ["info:read"],
+		"createdAt": "2025-05-01T12:00:00Z",
+		"expiresAt": "2025-05-31T12:00:00Z",
+		"lastUsedAt": null,
+		"token": "mpat_abcdef-secret"
+	}`, w.Body.String())
+}
+
+func TestCreateAccessTokenHandler_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: name обязателен", service.ErrInvalidAccessTokenRequest), http.StatusBadRequest},
+		{service.ErrTooManyAccessTokens, http.StatusConflict},
+		{service.ErrEmployeeNotFound, http.StatusNotFound},
+	} {
+		mockService := new(MockAccessTokenService)
+		mockService.On("CreateToken", mock.Anything, "alice", mock.Anything).Return(nil, tc.err)
+
+		c, w := newAccessTokenRequestContext(http.MethodPost, `{"name":"","scopes":["info:read"]}`)
+		handler.NewAccessTokenHandler(mockService).CreateToken(c)
+
+		assert.Equal(t, tc.status, w.Code, tc.err.Error())
+	}
+}
+
+func TestListAccessTokensHandler(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	mockService.On("ListTokens", mock.Anything, "alice").Return([]service.AccessTokenInfo{}, nil)
+
+	c, w := newAccessTokenRequestContext(http.MethodGet, "")
+	handler.NewAccessTokenHandler(mockService).ListTokens(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tokens":[]}`, w.Body.String())
+}
+
+func TestRevokeAccessTokenHandler(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	mockService.On("RevokeToken", mock.Anything, "alice", uint(7)).Return(nil)
+	mockService.On("RevokeToken", mock.Anything, "alice", uint(8)).Return(service.ErrAccessTokenNotFound)
+
+	for _, tc := range []struct {
+		id     string
+		status int
+	}{
+		{"7", http.StatusOK},
+		{"8", http.StatusNotFound},
+		{"abc", http.StatusBadRequest},
+		{"0", http.StatusBadRequest},
+	} {
+		c, w := newAccessTokenRequestContext(http.MethodDelete, "")
+		c.Params = gin.Params{{Key: "id", Value: tc.id}}
+		handler.NewAccessTokenHandler(mockService).RevokeToken(c)
+
+		assert.Equal(t, tc.status, w.Code, tc.id)
+	}
+}
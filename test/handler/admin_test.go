@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockEmployeeService struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeService) Deactivate(ctx context.Context, req service.DeactivateRequest) (*service.EmployeeStatus, error) {
+	args := m.Called(ctx, req)
+	status, _ := args.Get(0).(*service.EmployeeStatus)
+	return status, args.Error(1)
+}
+
+func (m *MockEmployeeService) Reactivate(ctx context.Context, username string) (*service.EmployeeStatus, error) {
+	args := m.Called(ctx, username)
+	status, _ := args.Get(0).(*service.EmployeeStatus)
+	return status, args.Error(1)
+}
+
+func newAdminRequestContext(username, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "username", Value: username}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/employees/"+username+"/deactivate", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestDeactivateEmployee_WithoutBody(t *testing.T) {
+	mockService := new(MockEmployeeService)
+	mockService.On("Deactivate", mock.Anything, service.DeactivateRequest{Username: "alice"}).
+		Return(&service.EmployeeStatus{Username: "alice", Balance: 300}, nil)
+
+	c, w := newAdminRequestContext("alice", "")
+	handler.NewAdminHandler(mockService, nil).DeactivateEmployee(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response service.EmployeeStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "alice", response.Username)
+	assert.False(t, response.Active)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeactivateEmployee_TransfersBalance(t *testing.T) {
+	mockService := new(MockEmployeeService)
+	mockService.On("Deactivate", mock.Anything, service.DeactivateRequest{Username: "alice", TransferTo: "bob"}).
+		Return(&service.EmployeeStatus{Username: "alice", TransferredTo: "bob", Transferred: 300}, nil)
+
+	c, w := newAdminRequestContext("alice", `{"transferTo":"bob"}`)
+	handler.NewAdminHandler(mockService, nil).DeactivateEmployee(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"transferred":300`)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeactivateEmployee_ErrorStatuses(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"not found":          {service.ErrEmployeeNotFound, http.StatusNotFound},
+		"already":            {service.ErrEmployeeDeactivated, http.StatusConflict},
+		"invalid action":     {service.ErrInvalidBalanceAction, http.StatusBadRequest},
+		"target deactivated": {service.ErrTransferTargetDeactivated, http.StatusUnprocessableEntity},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockEmployeeService)
+			mockService.On("Deactivate", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			c, w := newAdminRequestContext("alice", `{"forfeitBalance":true}`)
+			handler.NewAdminHandler(mockService, nil).DeactivateEmployee(c)
+
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestReactivateEmployee(t *testing.T) {
+	mockService := new(MockEmployeeService)
+	mockService.On("Reactivate", mock.Anything, "alice").
+		Return(&service.EmployeeStatus{Username: "alice", Active: true}, nil)
+
+	c, w := newAdminRequestContext("alice", "")
+	handler.NewAdminHandler(mockService, nil).ReactivateEmployee(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":true`)
+	mockService.AssertExpectations(t)
+}
+
+func TestReactivateEmployee_AlreadyActive(t *testing.T) {
+	mockService := new(MockEmployeeService)
+	mockService.On("Reactivate", mock.Anything, "alice").Return(nil, service.ErrEmployeeAlreadyActive)
+
+	c, w := newAdminRequestContext("alice", "")
+	handler.NewAdminHandler(mockService, nil).ReactivateEmployee(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+type MockLoginUnlocker struct {
+	mock.Mock
+}
+
+func (m *MockLoginUnlocker) UnlockUser(ctx context.Context, username string) error {
+	return m.Called(ctx, username).Error(0)
+}
+
+func (m *MockLoginUnlocker) UnlockIP(ctx context.Context, ip string) error {
+	return m.Called(ctx, ip).Error(0)
+}
+
+func TestUnlockEmployeeLogin(t *testing.T) {
+	unlocker := new(MockLoginUnlocker)
+	unlocker.On("UnlockUser", mock.Anything, "alice").Return(nil)
+
+	c, w := newAdminRequestContext("alice", "")
+	handler.NewAdminHandler(nil, unlocker).UnlockEmployeeLogin(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	unlocker.AssertExpectations(t)
+}
+
+func TestUnlockIPLogin(t *testing.T) {
+	unlocker := new(MockLoginUnlocker)
+	unlocker.On("UnlockIP", mock.Anything, "2001:db8::1").Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "ip", Value: "2001:DB8:0::1"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	handler.NewAdminHandler(nil, unlocker).UnlockIPLogin(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, "адрес приводится к каноническому виду, как его видит c.ClientIP()")
+	unlocker.AssertExpectations(t)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "ip", Value: "office"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	handler.NewAdminHandler(nil, unlocker).UnlockIPLogin(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
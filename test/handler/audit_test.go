@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Search(ctx context.Context, query service.AuditQuery) (*service.AuditPage, error) {
+	args := m.Called(ctx, query)
+	page, _ := args.Get(0).(*service.AuditPage)
+	return page, args.Error(1)
+}
+
+func (m *MockAuditService) Export(ctx context.Context, query service.AuditQuery, fn func(service.AuditEntry) error) error {
+	args := m.Called(ctx, query)
+	entries, _ := args.Get(0).([]service.AuditEntry)
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+var auditCreatedAt = time.Date(2025, 5, 10, 9, 30, 0, 0, time.UTC)
+
+func newAuditRequestContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return c, w
+}
+
+func TestSearchAuditHandler(t *testing.T) {
+	before, after := 300, 260
+	mockService := new(MockAuditService)
+	mockService.On("Search", mock.Anything, service.AuditQuery{
+		Actor:  "alice",
+		Action: service.AuditTransfer,
+		From:   auditCreatedAt,
+		Before: 42,
+		Limit:  10,
+	}).Return(&service.AuditPage{
+		Events: []service.AuditEntry{{
+			ID:            41,
+			CreatedAt:     auditCreatedAt,
+			Actor:         "alice",
+			Action:        service.AuditTransfer,
+			Target:        "bob",
+			BalanceBefore: &before,
+			BalanceAfter:  &after,
+			IP:            "10.0.0.7",
+			RequestID:     "req-1",
+		}},
+		NextBefore: 41,
+	}, nil)
+
+	c, w := newAuditRequestContext("/admin/audit?actor=alice&action=transfer.create&from=2025-05-10T09:30:00Z&before=42&limit=10")
+	handler.NewAuditHandler(mockService).SearchAudit(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"events": [{
+			"id": 41,
+			"createdAt": "2025-05-10T09:30:00Z",
+			"actor": "alice",
+			"action": "transfer.create",
+			"target": "bob",
+			"balanceBefore": 300,
+			"balanceAfter": 260,
+			"ip": "10.0.0.7",
+			"requestId": "req-1"
+		}],
+		"nextBefore": 41
+	}`, w.Body.String())
+}
+
+func TestSearchAuditHandler_BadRequest(t *testing.T) {
+	mockService := new(MockAuditService)
+	mockService.On("Search", mock.Anything, mock.Anything).Return(nil, service.ErrInvalidAuditQuery)
+
+	for _, target := range []string{
+		"/admin/audit?from=вчера",
+		"/admin/audit?before=-1",
+		"/admin/audit?limit=много",
+		"/admin/audit?limit=1000",
+	} {
+		c, w := newAuditRequestContext(target)
+		handler.NewAuditHandler(mockService).SearchAudit(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestExportAuditHandler_CSV(t *testing.T) {
+	tokenID := uint(9)
+	mockService := new(MockAuditService)
+	mockService.On("Export", mock.Anything, service.AuditQuery{Target: "bob"}).Return([]service.AuditEntry{{
+		ID:            3,
+		CreatedAt:     auditCreatedAt,
+		Actor:         "root",
+		AccessTokenID: &tokenID,
+		Action:        service.AuditEmployeeReactivate,
+		Target:        "bob",
+		Details:       "вернулся, с отпуска",
+		IP:            "10.0.0.7",
+		RequestID:     "req-2",
+	}}, nil)
+
+	c, w := newAuditRequestContext("/admin/audit/export?target=bob&limit=5")
+	handler.NewAuditHandler(mockService).ExportAudit(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="audit-log.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,createdAt,actor,accessTokenId,action,target,balanceBefore,balanceAfter,details,ip,requestId\n"+
+		"3,2025-05-10T09:30:00Z,root,9,admin.employee.reactivate,bob,,,\"вернулся, с отпуска\",10.0.0.7,req-2\n", w.Body.String())
+}
+
+func TestExportAuditHandler_CSVNeutralizesFormulas(t *testing.T) {
+	mockService := new(MockAuditService)
+	mockService.On("Export", mock.Anything, service.AuditQuery{}).Return([]service.AuditEntry{{
+		ID:        4,
+		CreatedAt: auditCreatedAt,
+		Actor:     `=HYPERLINK("http://evil.example","x")`,
+		Action:    service.AuditLoginFailed,
+		Target:    "@SUM(A1)",
+		Details:   "-1+2",
+		RequestID: "+cmd",
+	}}, nil)
+
+	c, w := newAuditRequestContext("/admin/audit/export")
+	handler.NewAuditHandler(mockService).ExportAudit(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,createdAt,actor,accessTokenId,action,target,balanceBefore,balanceAfter,details,ip,requestId\n"+
+		`4,2025-05-10T09:30:00Z,"'=HYPERLINK(""http://evil.example"",""x"")",,auth.login_failed,'@SUM(A1),,,'-1+2,,'+cmd`+"\n", w.Body.String())
+}
+
+func TestExportAuditHandler_EmptyJSONLines(t *testing.T) {
+	mockService := new(MockAuditService)
+	mockService.On("Export", mock.Anything, service.AuditQuery{}).Return(nil, nil)
+
+	c, w := newAuditRequestContext("/admin/audit/export?format=jsonl")
+	handler.NewAuditHandler(mockService).ExportAudit(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestExportAuditHandler_Errors(t *testing.T) {
+	mockService := new(MockAuditService)
+	mockService.On("Export", mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+
+	c, w := newAuditRequestContext("/admin/audit/export?format=xml")
+	handler.NewAuditHandler(mockService).ExportAudit(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	c, w = newAuditRequestContext("/admin/audit/export")
+	handler.NewAuditHandler(mockService).ExportAudit(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
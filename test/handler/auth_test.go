@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) AuthenticateUser(ctx context.Context, req service.LoginRequest) (string, error) {
+	args := m.Called(ctx, req)
+	return args.String(0), args.Error(1)
+}
+
+func TestAuthenticateHandler_PassesClientIP(t *testing.T) {
+	mockService := new(MockAuthService)
+	mockService.On("AuthenticateUser", mock.Anything, service.LoginRequest{Username: "alice", Password: "password", ClientIP: "192.0.2.10"}).
+		Return("token", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{"username":"alice","password":"password"}`))
+	c.Request.RemoteAddr = "192.0.2.10:51234"
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.NewAuthHandler(mockService).Authenticate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthenticateHandler_TooManyAttempts(t *testing.T) {
+	mockService := new(MockAuthService)
+	mockService.On("AuthenticateUser", mock.Anything, mock.Anything).
+		Return("", &service.TooManyAttemptsError{RetryAfter: 90*time.Second + time.Millisecond})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{"username":"alice","password":"wrong"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.NewAuthHandler(mockService).Authenticate(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "91", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockDirectoryService struct {
+	mock.Mock
+}
+
+func (m *MockDirectoryService) SearchEmployees(ctx context.Context, search service.DirectorySearch) (*service.DirectoryPage, error) {
+	args := m.Called(ctx, search)
+	page, _ := args.Get(0).(*service.DirectoryPage)
+	return page, args.Error(1)
+}
+
+func newDirectoryRequestContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "alice")
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return c, w
+}
+
+func TestSearchEmployeesHandler(t *testing.T) {
+	mockService := new(MockDirectoryService)
+	mockService.On("SearchEmployees", mock.Anything, service.DirectorySearch{Query: "мар", Limit: 5, Offset: 10}).
+		Return(&service.DirectoryPage{
+			Employees: []service.DirectoryEntry{{Username: "mivanova", DisplayName: "Мария Иванова"}},
+			Limit:     5,
+			Offset:    10,
+			Total:     11,
+		}, nil)
+
+	c, w := newDirectoryRequestContext("/employees?q=%D0%BC%D0%B0%D1%80&limit=5&offset=10")
+	handler.NewDirectoryHandler(mockService).SearchEmployees(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"employees":[{"username":"mivanova","displayName":"Мария Иванова","department":"","team":"","avatarUrl":""}],"limit":5,"offset":10,"total":11}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchEmployeesHandler_DefaultLimit(t *testing.T) {
+	mockService := new(MockDirectoryService)
+	mockService.On("SearchEmployees", mock.Anything, service.DirectorySearch{Limit: service.DefaultDirectoryLimit}).
+		Return(&service.DirectoryPage{Employees: []service.DirectoryEntry{}, Limit: service.DefaultDirectoryLimit}, nil)
+
+	c, w := newDirectoryRequestContext("/employees")
+	handler.NewDirectoryHandler(mockService).SearchEmployees(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchEmployeesHandler_NotANumber(t *testing.T) {
+	mockService := new(MockDirectoryService)
+
+	c, w := newDirectoryRequestContext("/employees?limit=ten")
+	handler.NewDirectoryHandler(mockService).SearchEmployees(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "limit")
+	mockService.AssertNotCalled(t, "SearchEmployees", mock.Anything, mock.Anything)
+}
+
+func TestSearchEmployeesHandler_InvalidSearch(t *testing.T) {
+	mockService := new(MockDirectoryService)
+	mockService.On("SearchEmployees", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w: offset не может быть отрицательным", service.ErrInvalidDirectorySearch))
+
+	c, w := newDirectoryRequestContext("/employees?offset=-1")
+	handler.NewDirectoryHandler(mockService).SearchEmployees(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "offset не может быть отрицательным")
+}
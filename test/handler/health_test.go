@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	handler2 "merch-api/handler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newHealthHandler(t *testing.T, expectedVersion int64) (*handler2.HealthHandler, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	mock.ExpectPing()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	return handler2.NewHealthHandler(gdb, expectedVersion), mock
+}
+
+func serveReadiness(h *handler2.HealthHandler) (*httptest.ResponseRecorder, handler2.ReadinessResponse) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	h.Readiness(c)
+
+	var response handler2.ReadinessResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	return w, response
+}
+
+func TestLiveness(t *testing.T) {
+	h, _ := newHealthHandler(t, 1)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	h.Liveness(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+}
+
+func TestReadiness_Ready(t *testing.T) {
+	h, mock := newHealthHandler(t, 20250301120000)
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT version, dirty FROM \"schema_migrations\" ORDER BY version DESC LIMIT (.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(20250301120000, false))
+
+	w, response := serveReadiness(h)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ready", response.Status)
+	assert.Equal(t, "ok", response.Checks["database"].Status)
+	assert.Equal(t, "ok", response.Checks["migrations"].Status)
+	assert.Equal(t, int64(20250301120000), *response.Checks["migrations"].Current)
+}
+
+func TestReadiness_DatabaseUnavailable(t *testing.T) {
+	h, mock := newHealthHandler(t, 20250301120000)
+	mock.ExpectPing().WillReturnError(fmt.Errorf("connection refused"))
+	mock.ExpectQuery("SELECT version, dirty FROM \"schema_migrations\"(.+)").
+		WillReturnError(fmt.Errorf("connection refused"))
+
+	w, response := serveReadiness(h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "not_ready", response.Status)
+	assert.Equal(t, "fail", response.Checks["database"].Status)
+	assert.Equal(t, "connection refused", response.Checks["database"].Error)
+}
+
+func TestReadiness_MigrationsBehind(t *testing.T) {
+	h, mock := newHealthHandler(t, 20250301120000)
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT version, dirty FROM \"schema_migrations\"(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(20250214012839, false))
+
+	w, response := serveReadiness(h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "ok", response.Checks["database"].Status)
+	assert.Equal(t, "fail", response.Checks["migrations"].Status)
+	assert.Equal(t, int64(20250214012839), *response.Checks["migrations"].Current)
+	assert.Equal(t, int64(20250301120000), *response.Checks["migrations"].Expected)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"merch-api/config"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKSHandler_PublishesPublicKeysOnly(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := service.NewJWTKeys(config.JWTConfig{
+		Secret:      []byte("test-secret-test-secret-test-secret"),
+		TTL:         time.Hour,
+		SigningKeys: []config.SigningKey{{ID: "ed-1", Private: edKey}},
+	}, service.SystemClock{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	handler.NewJWKSHandler(keys).Keys(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "max-age")
+	var body struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Keys, 1)
+	assert.Equal(t, "ed-1", body.Keys[0]["kid"])
+	assert.Equal(t, "OKP", body.Keys[0]["kty"])
+	assert.NotContains(t, body.Keys[0], "d")
+	assert.NotContains(t, w.Body.String(), "test-secret")
+}
+
+func TestJWKSHandler_EmptyWithoutAsymmetricKeys(t *testing.T) {
+	keys := service.NewJWTKeys(config.JWTConfig{Secret: []byte("test-secret-test-secret-test-secret"), TTL: time.Hour}, service.SystemClock{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	handler.NewJWKSHandler(keys).Keys(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+}
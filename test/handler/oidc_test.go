@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockOIDCAuth struct {
+	mock.Mock
+}
+
+func (m *MockOIDCAuth) BeginLogin(ctx context.Context) (*service.OIDCLogin, error) {
+	args := m.Called(ctx)
+	login, _ := args.Get(0).(*service.OIDCLogin)
+	return login, args.Error(1)
+}
+
+func (m *MockOIDCAuth) CompleteLogin(ctx context.Context, code, verifier, nonce string) (string, error) {
+	args := m.Called(ctx, code, verifier, nonce)
+	return args.String(0), args.Error(1)
+}
+
+func TestOIDCLogin_RedirectsAndStoresStateInCookies(t *testing.T) {
+	mockService := new(MockOIDCAuth)
+	mockService.On("BeginLogin", mock.Anything).Return(&service.OIDCLogin{
+		URL:      "https://sso.example.com/authorize?state=s1",
+		State:    "s1",
+		Nonce:    "n1",
+		Verifier: "v1",
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil)
+	handler.NewOIDCHandler(mockService, true).Login(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://sso.example.com/authorize?state=s1", w.Header().Get("Location"))
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	for name, value := range map[string]string{"oidc_state": "s1", "oidc_nonce": "n1", "oidc_verifier": "v1"} {
+		if assert.Contains(t, cookies, name) {
+			assert.Equal(t, value, cookies[name].Value)
+			assert.True(t, cookies[name].HttpOnly)
+			assert.True(t, cookies[name].Secure)
+			assert.Equal(t, http.SameSiteLaxMode, cookies[name].SameSite)
+		}
+	}
+}
+
+func TestOIDCCallback_ExchangesCodeWhenStateMatches(t *testing.T) {
+	mockService := new(MockOIDCAuth)
+	mockService.On("CompleteLogin", mock.Anything, "code1", "v1", "n1").Return("token", nil)
+
+	w := performOIDCCallback(mockService, "/auth/oidc/callback?code=code1&state=s1")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"token":"token"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestOIDCCallback_RejectsWithoutExchange(t *testing.T) {
+	tests := map[string]struct {
+		url    string
+		status int
+	}{
+		"state mismatch":    {"/auth/oidc/callback?code=code1&state=forged", http.StatusBadRequest},
+		"missing code":      {"/auth/oidc/callback?state=s1", http.StatusBadRequest},
+		"provider rejected": {"/auth/oidc/callback?error=access_denied&state=s1", http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockOIDCAuth)
+
+			w := performOIDCCallback(mockService, tt.url)
+
+			assert.Equal(t, tt.status, w.Code)
+			mockService.AssertNotCalled(t, "CompleteLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOIDCCallback_InvalidLogin(t *testing.T) {
+	mockService := new(MockOIDCAuth)
+	mockService.On("CompleteLogin", mock.Anything, "code1", "v1", "n1").Return("", service.ErrInvalidOIDCLogin)
+
+	w := performOIDCCallback(mockService, "/auth/oidc/callback?code=code1&state=s1")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCCallback_IdentityConflict(t *testing.T) {
+	mockService := new(MockOIDCAuth)
+	mockService.On("CompleteLogin", mock.Anything, "code1", "v1", "n1").Return("", service.ErrExternalIdentityConflict)
+
+	w := performOIDCCallback(mockService, "/auth/oidc/callback?code=code1&state=s1")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func performOIDCCallback(svc service.OIDCAuth, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, url, nil)
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "s1"})
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_nonce", Value: "n1"})
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_verifier", Value: "v1"})
+	handler.NewOIDCHandler(svc, false).Callback(c)
+	return w
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockPasswordService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordService) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) (string, error) {
+	args := m.Called(ctx, username, oldPassword, newPassword)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPasswordService) IssueReset(ctx context.Context, username string) (*service.PasswordReset, error) {
+	args := m.Called(ctx, username)
+	reset, _ := args.Get(0).(*service.PasswordReset)
+	return reset, args.Error(1)
+}
+
+func (m *MockPasswordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
+
+func newPasswordRequestContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestChangePasswordHandler_ReturnsNewToken(t *testing.T) {
+	mockService := new(MockPasswordService)
+	mockService.On("ChangePassword", mock.Anything, "alice", "old-password", "new-password").Return("new-token", nil)
+
+	c, w := newPasswordRequestContext(`{"oldPassword":"old-password","newPassword":"new-password"}`)
+	c.Set("username", "alice")
+	handler.NewPasswordHandler(mockService).ChangePassword(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"token":"new-token"}`, w.Body.String())
+}
+
+func TestChangePasswordHandler_WrongOldPassword(t *testing.T) {
+	mockService := new(MockPasswordService)
+	mockService.On("ChangePassword", mock.Anything, "alice", "wrong", "new-password").Return("", service.ErrPasswordMismatch)
+
+	c, w := newPasswordRequestContext(`{"oldPassword":"wrong","newPassword":"new-password"}`)
+	c.Set("username", "alice")
+	handler.NewPasswordHandler(mockService).ChangePassword(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Неверный текущий пароль")
+}
+
+func TestChangePasswordHandler_MissingFields(t *testing.T) {
+	mockService := new(MockPasswordService)
+
+	c, w := newPasswordRequestContext(`{"newPassword":"new-password"}`)
+	c.Set("username", "alice")
+	handler.NewPasswordHandler(mockService).ChangePassword(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIssueResetHandler(t *testing.T) {
+	expiresAt := time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)
+	mockService := new(MockPasswordService)
+	mockService.On("IssueReset", mock.Anything, "alice").Return(&service.PasswordReset{Token: "reset-token", ExpiresAt: expiresAt}, nil)
+
+	c, w := newPasswordRequestContext("")
+	c.Params = gin.Params{{Key: "username", Value: "alice"}}
+	handler.NewPasswordHandler(mockService).IssueReset(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"token":"reset-token","expiresAt":"2025-04-21T12:00:00Z"}`, w.Body.String())
+}
+
+func TestResetPasswordHandler_InvalidToken(t *testing.T) {
+	mockService := new(MockPasswordService)
+	mockService.On("ResetPassword", mock.Anything, "used-token", "new-password").Return(service.ErrInvalidResetToken)
+
+	c, w := newPasswordRequestContext(`{"token":"used-token","newPassword":"new-password"}`)
+	handler.NewPasswordHandler(mockService).ResetPassword(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), service.ErrInvalidResetToken.Error())
+}
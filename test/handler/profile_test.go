@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"merch-api/handler"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) GetProfile(ctx context.Context, username string) (*service.Profile, error) {
+	args := m.Called(ctx, username)
+	profile, _ := args.Get(0).(*service.Profile)
+	return profile, args.Error(1)
+}
+
+func (m *MockProfileService) UpdateProfile(ctx context.Context, username string, update service.ProfileUpdate) (*service.Profile, error) {
+	args := m.Called(ctx, username, update)
+	profile, _ := args.Get(0).(*service.Profile)
+	return profile, args.Error(1)
+}
+
+func newProfileRequestContext(method, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "alice")
+	c.Request = httptest.NewRequest(method, "/me/profile", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestGetProfileHandler(t *testing.T) {
+	mockService := new(MockProfileService)
+	mockService.On("GetProfile", mock.Anything, "alice").
+		Return(&service.Profile{Username: "alice", DisplayName: "Алиса"}, nil)
+
+	c, w := newProfileRequestContext(http.MethodGet, "")
+	handler.NewProfileHandler(mockService).GetProfile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"username":"alice","displayName":"Алиса","email":"","department":"","team":"","avatarUrl":""}`, w.Body.String())
+}
+
+func TestUpdateProfileHandler_PassesOnlyProvidedFields(t *testing.T) {
+	mockService := new(MockProfileService)
+	mockService.On("UpdateProfile", mock.Anything, "alice", mock.MatchedBy(func(update service.ProfileUpdate) bool {
+		return update.DisplayName != nil && *update.DisplayName == "Алиса" &&
+			update.Team != nil && *update.Team == "" &&
+			update.Email == nil && update.Department == nil && update.AvatarURL == nil
+	})).Return(&service.Profile{Username: "alice", DisplayName: "Алиса"}, nil)
+
+	c, w := newProfileRequestContext(http.MethodPatch, `{"displayName":"Алиса","team":""}`)
+	handler.NewProfileHandler(mockService).UpdateProfile(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateProfileHandler_InvalidProfile(t *testing.T) {
+	mockService := new(MockProfileService)
+	mockService.On("UpdateProfile", mock.Anything, "alice", mock.Anything).
+		Return(nil, service.ErrInvalidProfile)
+
+	c, w := newProfileRequestContext(http.MethodPatch, `{"email":"nope"}`)
+	handler.NewProfileHandler(mockService).UpdateProfile(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,14 +1,16 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"github.com/DATA-DOG/go-sqlmock"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 	handler2 "merch-api/handler"
+	"merch-api/httpx"
+	"merch-api/service"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,11 +20,17 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) PurchaseMerch(db *gorm.DB, username string, itemName string) (string, error) {
-	args := m.Called(db, username, itemName)
+func (m *MockService) PurchaseMerch(ctx context.Context, username string, itemName string) (string, error) {
+	args := m.Called(ctx, username, itemName)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockService) CreatePurchase(ctx context.Context, req service.PurchaseRequest) (*service.PurchaseResult, error) {
+	args := m.Called(ctx, req)
+	result, _ := args.Get(0).(*service.PurchaseResult)
+	return result, args.Error(1)
+}
+
 func TestBuyItemHandler(t *testing.T) {
 	mockService := new(MockService)
 	mockService.On("PurchaseMerch", mock.Anything, "testuser", "item1").Return("Покупка успешна", nil)
@@ -30,25 +38,15 @@ func TestBuyItemHandler(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser")
 
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-	c.Set("db", gdb)
 	c.Params = append(c.Params, gin.Param{Key: "item", Value: "item1"})
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/item1", nil)
 
 	pHandler := handler2.NewPurchaseHandler(mockService)
 	pHandler.BuyItem(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var response map[string]string
-	err = json.NewDecoder(w.Body).Decode(&response)
+	err := json.NewDecoder(w.Body).Decode(&response)
 	assert.NoError(t, err)
 	assert.Equal(t, "Покупка успешна", response["message"])
 
@@ -84,43 +82,143 @@ func TestBuyItemHandler_InvalidUsername(t *testing.T) {
 	assert.Equal(t, "Некорректный username", response["errors"])
 }
 
-func TestBuyItemHandler_DatabaseError(t *testing.T) {
+func TestBuyItemHandler_V2Envelope(t *testing.T) {
 	mockService := new(MockService)
+	mockService.On("PurchaseMerch", mock.Anything, "testuser", "item1").Return("Покупка успешна", nil)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser")
+	c.Set(httpx.APIVersionKey, httpx.APIVersionV2)
+
+	c.Params = append(c.Params, gin.Param{Key: "item", Value: "item1"})
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/item1", nil)
 
 	pHandler := handler2.NewPurchaseHandler(mockService)
 	pHandler.BuyItem(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	var response map[string]string
-	json.NewDecoder(w.Body).Decode(&response)
-	assert.Equal(t, "БД недоступна", response["errors"])
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]map[string]string
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Покупка успешна", response["data"]["message"])
 
 	mockService.AssertExpectations(t)
 }
 
-func TestBuyItemHandler_WrongDatabaseConnection(t *testing.T) {
+func TestBuyItemHandler_V2ErrorEnvelope(t *testing.T) {
 	mockService := new(MockService)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(httpx.APIVersionKey, httpx.APIVersionV2)
+
+	pHandler := handler2.NewPurchaseHandler(mockService)
+	pHandler.BuyItem(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var response map[string]httpx.ErrorBody
+	json.NewDecoder(w.Body).Decode(&response)
+	assert.Equal(t, "unauthorized", response["error"].Code)
+	assert.Equal(t, "Неавторизован", response["error"].Message)
+}
+
+func newPurchaseRequestContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser")
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/purchases", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
 
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-	c.Set("db", db)
+	return c, w
+}
+
+func TestCreatePurchaseHandler(t *testing.T) {
+	mockService := new(MockService)
+	mockService.On("CreatePurchase", mock.Anything, service.PurchaseRequest{
+		Username:       "testuser",
+		ItemName:       "cup",
+		Quantity:       2,
+		IdempotencyKey: "key-1",
+	}).Return(&service.PurchaseResult{
+		Purchase: service.PurchaseResource{ID: 7, Item: "cup", Quantity: 2, Price: 20, Total: 40, IdempotencyKey: "key-1"},
+		Balance:  960,
+	}, nil)
+
+	c, w := newPurchaseRequestContext(`{"item": "cup", "quantity": 2, "idempotencyKey": "key-1"}`)
 
 	pHandler := handler2.NewPurchaseHandler(mockService)
-	pHandler.BuyItem(c)
+	pHandler.CreatePurchase(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response service.PurchaseResult
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), response.Purchase.ID)
+	assert.Equal(t, 40, response.Purchase.Total)
+	assert.Equal(t, 960, response.Balance)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreatePurchaseHandler_IdempotencyKeyHeaderAndDefaultQuantity(t *testing.T) {
+	mockService := new(MockService)
+	mockService.On("CreatePurchase", mock.Anything, service.PurchaseRequest{
+		Username:       "testuser",
+		ItemName:       "cup",
+		Quantity:       1,
+		IdempotencyKey: "key-2",
+	}).Return(&service.PurchaseResult{
+		Purchase: service.PurchaseResource{ID: 7, Item: "cup", Quantity: 1},
+		Balance:  980,
+		Replayed: true,
+	}, nil)
+
+	c, w := newPurchaseRequestContext(`{"item": "cup"}`)
+	c.Request.Header.Set("Idempotency-Key", "key-2")
+
+	pHandler := handler2.NewPurchaseHandler(mockService)
+	pHandler.CreatePurchase(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreatePurchaseHandler_InvalidRequest(t *testing.T) {
+	mockService := new(MockService)
+	c, w := newPurchaseRequestContext(`{"quantity": 2}`)
+
+	pHandler := handler2.NewPurchaseHandler(mockService)
+	pHandler.CreatePurchase(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 	var response map[string]string
 	json.NewDecoder(w.Body).Decode(&response)
-	assert.Equal(t, "Кривое подключение к БД", response["errors"])
-
+	assert.Equal(t, "Неверный запрос", response["errors"])
 	mockService.AssertExpectations(t)
 }
+
+func TestCreatePurchaseHandler_ServiceErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: cup", service.ErrMerchNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: cup", service.ErrInsufficientFunds), http.StatusUnprocessableEntity},
+		{service.ErrIdempotencyKeyReused, http.StatusConflict},
+		{service.ErrInvalidQuantity, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		mockService := new(MockService)
+		mockService.On("CreatePurchase", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+		c, w := newPurchaseRequestContext(`{"item": "cup", "quantity": 2}`)
+
+		pHandler := handler2.NewPurchaseHandler(mockService)
+		pHandler.CreatePurchase(c)
+
+		assert.Equal(t, tc.status, w.Code, tc.err.Error())
+		var response map[string]string
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, tc.err.Error(), response["errors"])
+	}
+}
@@ -2,15 +2,14 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 	"merch-api/handler"
+	"merch-api/service"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -20,8 +19,8 @@ type MockTransactionService struct {
 	mock.Mock
 }
 
-func (m *MockTransactionService) SendCoins(db *gorm.DB, fromUsername, toUsername string, amount int) (string, error) {
-	args := m.Called(db, fromUsername, toUsername, amount)
+func (m *MockTransactionService) SendCoins(ctx context.Context, fromUsername, toUsername string, amount int) (string, error) {
+	args := m.Called(ctx, fromUsername, toUsername, amount)
 	return args.String(0), args.Error(1)
 }
 
@@ -40,17 +39,6 @@ func TestSendCoinHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser1")
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-	c.Set("db", gdb)
 	c.Request, _ = http.NewRequest(http.MethodPost, "/sendCoin", bytes.NewReader(body))
 	c.Request.Header.Set("Content-Type", "application/json")
 
@@ -112,17 +100,6 @@ func TestSendCoinHandler_InvalidRequest(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-	c.Set("db", gdb)
 	c.Set("username", "testuser1")
 
 	c.Request, _ = http.NewRequest(http.MethodPost, "/sendCoin", bytes.NewReader(body))
@@ -142,6 +119,8 @@ func TestSendCoinHandler_InvalidRequest(t *testing.T) {
 
 func TestSendCoin_SelfTransfer(t *testing.T) {
 	mockService := new(MockTransactionService)
+	selfTransfer := fmt.Errorf("%w: нельзя отправить монеты самому себе", service.ErrInvalidInput)
+	mockService.On("SendCoins", mock.Anything, "testuser1", "testuser1", 100).Return("", selfTransfer)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser1")
@@ -160,48 +139,7 @@ func TestSendCoin_SelfTransfer(t *testing.T) {
 	var response map[string]string
 	err := json.NewDecoder(w.Body).Decode(&response)
 	assert.NoError(t, err)
-	assert.Equal(t, "Нельзя отправить монеты самому себе", response["errors"])
-}
-
-func TestSendCoin_DatabaseNotFound(t *testing.T) {
-	mockService := new(MockTransactionService)
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("username", "testuser")
-	c.Request = httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBufferString(`{"toUser": "testuser2", "amount": 100}`))
-
-	tHandler := handler.NewTransactionHandler(mockService)
-	tHandler.SendCoin(c)
-
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-
-	var response map[string]string
-	err := json.NewDecoder(w.Body).Decode(&response)
-	assert.NoError(t, err)
-	assert.Equal(t, "БД недоступна", response["errors"])
-}
-
-func TestSendCoin_WrongDatabaseConnection(t *testing.T) {
-	mockService := new(MockTransactionService)
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("username", "testuser1")
-
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-	c.Set("db", db)
-	c.Request = httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBufferString(`{"toUser": "testuser2", "amount": 100}`))
-
-	tHandler := handler.NewTransactionHandler(mockService)
-	tHandler.SendCoin(c)
+	assert.Equal(t, selfTransfer.Error(), response["errors"])
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-
-	var response map[string]string
-	err = json.NewDecoder(w.Body).Decode(&response)
-	assert.NoError(t, err)
-	assert.Equal(t, "Кривое подключение к БД", response["errors"])
+	mockService.AssertExpectations(t)
 }
@@ -1,13 +1,11 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 	handler2 "merch-api/handler"
 	"merch-api/service"
 	"net/http"
@@ -19,8 +17,8 @@ type MockUserInfoService struct {
 	mock.Mock
 }
 
-func (m *MockUserInfoService) GetUserInfo(db *gorm.DB, username string) (service.UserInfo, error) {
-	args := m.Called(db, username)
+func (m *MockUserInfoService) GetUserInfo(ctx context.Context, username string) (service.UserInfo, error) {
+	args := m.Called(ctx, username)
 	return args.Get(0).(service.UserInfo), args.Error(1)
 }
 
@@ -34,10 +32,10 @@ func TestInfoHandler(t *testing.T) {
 		},
 		CoinHistory: service.CoinHistoryItem{
 			Received: []service.ReceivedCoinsItem{
-				{"user1", 20},
+				{FromUser: "user1", Amount: 20},
 			},
 			Sent: []service.SentCoinsItem{
-				{"user2", 30},
+				{ToUser: "user2", Amount: 30},
 			},
 		},
 	}, nil)
@@ -45,24 +43,14 @@ func TestInfoHandler(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser")
 
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-	c.Set("db", gdb)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/info", nil)
 
 	uHandler := handler2.NewUserInfoHandler(mockService)
 	uHandler.InfoHandler(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var response service.UserInfo
-	err = json.NewDecoder(w.Body).Decode(&response)
+	err := json.NewDecoder(w.Body).Decode(&response)
 	assert.NoError(t, err)
 	assert.Equal(t, 1000, response.Coins)
 	assert.Len(t, response.Inventory, 2)
@@ -113,43 +101,20 @@ func TestInfoHandler_InvalidUsername(t *testing.T) {
 	assert.Equal(t, "Некорректный username", response["errors"])
 }
 
-func TestInfoHandler_DatabaseNotFound(t *testing.T) {
+func TestInfoHandler_PassesRequestContext(t *testing.T) {
 	mockService := new(MockUserInfoService)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "testuser")
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/info", nil)
 
-	uHandler := handler2.NewUserInfoHandler(mockService)
-	uHandler.InfoHandler(c)
-
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-
-	var response map[string]string
-	err := json.NewDecoder(w.Body).Decode(&response)
-	assert.NoError(t, err)
-	assert.Equal(t, "БД недоступна", response["errors"])
-}
-
-func TestInfoHandler_WrongDatabaseConnection(t *testing.T) {
-	mockService := new(MockUserInfoService)
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("username", "testuser")
-
-	db, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-	c.Set("db", db)
+	mockService.On("GetUserInfo", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx == c.Request.Context()
+	}), "testuser").Return(service.UserInfo{Coins: 1000}, nil)
 
 	uHandler := handler2.NewUserInfoHandler(mockService)
 	uHandler.InfoHandler(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-
-	var response map[string]string
-	err = json.NewDecoder(w.Body).Decode(&response)
-	assert.NoError(t, err)
-	assert.Equal(t, "Кривое подключение к БД", response["errors"])
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
 }
@@ -1,23 +1,66 @@
 package middleware
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"merch-api/config"
 	"merch-api/middleware"
+	"merch-api/model"
+	"merch-api/repository/memory"
 	"merch-api/service"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
 	"time"
 )
 
+var testJWTSecret = []byte("test-secret-test-secret-test-secret")
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Secret:    testJWTSecret,
+		TTL:       time.Hour,
+		Issuer:    "merch-api",
+		Audience:  "merch-api",
+		ClockSkew: 30 * time.Second,
+	}
+}
+
+func testJWTKeys() *service.JWTKeys {
+	return service.NewJWTKeys(testJWTConfig(), service.SystemClock{})
+}
+
+// validRegisteredClaims — стандартные поля, с которыми токен проходит проверку
+func validRegisteredClaims() jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    "merch-api",
+		Audience:  jwt.ClaimStrings{"merch-api"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func testEmployees() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "testuser", Balance: 1000})
+	store.AddEmployee(model.Employee{Username: "admin", Balance: 1000, Role: model.RoleAdmin})
+	deactivatedAt := time.Now()
+	store.AddEmployee(model.Employee{Username: "gone", Balance: 1000, DeactivatedAt: &deactivatedAt})
+	store.AddEmployee(model.Employee{Username: "rotated", Balance: 1000, TokenVersion: 1})
+	return store
+}
+
 func TestJWTMiddleware_NoToken(t *testing.T) {
 	r := gin.Default()
-	r.Use(middleware.JWTMiddleware())
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
 	r.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	})
@@ -41,7 +84,7 @@ func TestJWTMiddleware_NoToken(t *testing.T) {
 
 func TestJWTMiddleware_InvalidTokenFormat(t *testing.T) {
 	r := gin.Default()
-	r.Use(middleware.JWTMiddleware())
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
 	r.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	})
@@ -68,7 +111,7 @@ func TestJWTMiddleware_InvalidOrExpiredToken(t *testing.T) {
 	invalidToken := "InvalidTokenString"
 
 	r := gin.Default()
-	r.Use(middleware.JWTMiddleware())
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
 	r.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	})
@@ -98,7 +141,7 @@ func TestJWTMiddleware_ValidToken(t *testing.T) {
 	}
 
 	r := gin.Default()
-	r.Use(middleware.JWTMiddleware())
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
 	r.GET("/test", func(c *gin.Context) {
 		username, _ := c.Get("username")
 		c.JSON(http.StatusOK, gin.H{"message": "Success", "username": username})
@@ -125,19 +168,385 @@ func TestJWTMiddleware_ValidToken(t *testing.T) {
 }
 
 func generateValidToken(username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := service.Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		Username:         username,
+		RegisteredClaims: validRegisteredClaims(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenString, err := token.SignedString(testJWTSecret)
 	if err != nil {
 		return "", fmt.Errorf("ошибка при подписании токена: %v", err)
 	}
 
 	return tokenString, nil
 }
+
+func serveWithToken(t *testing.T, r *gin.Engine, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	tokenString, err := generateValidToken(username)
+	if err != nil {
+		t.Fatalf("ошибка при генерации валидного токена: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTMiddleware_RejectsDeactivatedEmployee(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := serveWithToken(t, r, "gone")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Учётная запись деактивирована")
+}
+
+func TestJWTMiddleware_RejectsUnknownEmployee(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := serveWithToken(t, r, "stranger")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWTMiddleware_RejectsTokenIssuedBeforePasswordChange(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := serveWithToken(t, r, "rotated")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Токен отозван")
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, service.Claims{
+		Username:         "rotated",
+		TokenVersion:     1,
+		RegisteredClaims: validRegisteredClaims(),
+	}).SignedString(testJWTSecret)
+	if err != nil {
+		t.Fatalf("ошибка при подписании токена: %v", err)
+	}
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWTMiddleware_AsymmetricKeys(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ошибка при генерации ключа: %v", err)
+	}
+	cfg := testJWTConfig()
+	cfg.Secret = nil
+	cfg.SigningKeys = []config.SigningKey{{ID: "ed-1", Private: edKey}}
+	signer := service.NewJWTKeys(cfg, service.SystemClock{})
+	tokenString, err := signer.Sign(service.Claims{
+		Username:         "testuser",
+		RegisteredClaims: validRegisteredClaims(),
+	})
+	if err != nil {
+		t.Fatalf("ошибка при подписании токена: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(signer, testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(tokenString))
+
+	// Без JWT_SECRET токены HS256 не принимаются, даже подписанные чем-то похожим на ключ
+	hmacToken, err := generateValidToken("testuser")
+	if err != nil {
+		t.Fatalf("ошибка при генерации токена: %v", err)
+	}
+	assert.Equal(t, http.StatusUnauthorized, request(hmacToken))
+}
+
+func TestJWTMiddleware_ValidatesStandardClaims(t *testing.T) {
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"username": "testuser",
+			"iss":      "merch-api",
+			"aud":      "merch-api",
+			"iat":      now.Unix(),
+			"exp":      now.Add(time.Hour).Unix(),
+		}
+	}
+	with := func(name string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		status int
+		error  string
+	}{
+		"valid":                     {valid(), http.StatusOK, ""},
+		"missing username":          {with("username", nil), http.StatusUnauthorized, "Invalid token claims"},
+		"empty username":            {with("username", ""), http.StatusUnauthorized, "Invalid token claims"},
+		"username is not a string":  {with("username", 42), http.StatusUnauthorized, "Invalid or expired token"},
+		"missing issuer":            {with("iss", nil), http.StatusUnauthorized, "Invalid token claims"},
+		"foreign issuer":            {with("iss", "other-service"), http.StatusUnauthorized, "Invalid token claims"},
+		"missing audience":          {with("aud", nil), http.StatusUnauthorized, "Invalid token claims"},
+		"foreign audience":          {with("aud", []string{"other-service"}), http.StatusUnauthorized, "Invalid token claims"},
+		"one of audiences":          {with("aud", []string{"other-service", "merch-api"}), http.StatusOK, ""},
+		"missing iat":               {with("iat", nil), http.StatusUnauthorized, "Invalid token claims"},
+		"iat in the future":         {with("iat", now.Add(time.Minute).Unix()), http.StatusUnauthorized, "Invalid token claims"},
+		"iat within clock skew":     {with("iat", now.Add(10*time.Second).Unix()), http.StatusOK, ""},
+		"nbf in the future":         {with("nbf", now.Add(time.Minute).Unix()), http.StatusUnauthorized, "Invalid token claims"},
+		"nbf within clock skew":     {with("nbf", now.Add(10*time.Second).Unix()), http.StatusOK, ""},
+		"missing exp":               {with("exp", nil), http.StatusUnauthorized, "Invalid token claims"},
+		"expired":                   {with("exp", now.Add(-time.Minute).Unix()), http.StatusUnauthorized, "Invalid or expired token"},
+		"expired within clock skew": {with("exp", now.Add(-10*time.Second).Unix()), http.StatusOK, ""},
+	}
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString(testJWTSecret)
+			if err != nil {
+				t.Fatalf("ошибка при подписании токена: %v", err)
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.error)
+		})
+	}
+}
+
+func TestJWTMiddleware_RejectsMalformedTokens(t *testing.T) {
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, service.Claims{
+		Username:         "testuser",
+		RegisteredClaims: validRegisteredClaims(),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("ошибка при подписании токена: %v", err)
+	}
+	valid, err := generateValidToken("testuser")
+	if err != nil {
+		t.Fatalf("ошибка при генерации токена: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for name, header := range map[string]string{
+		"empty bearer":     "Bearer ",
+		"lowercase scheme": "bearer " + valid,
+		"garbage":          "Bearer not.a.jwt",
+		"truncated":        "Bearer " + valid[:len(valid)/2],
+		"alg none":         "Bearer " + unsigned,
+		"tampered payload": "Bearer " + strings.Replace(valid, ".", ".e30", 1),
+		"two tokens":       "Bearer " + valid + " " + valid,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", header)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+func TestJWTMiddleware_PutsPrincipalIntoContext(t *testing.T) {
+	store := testEmployees()
+	admin, _ := store.Employee("admin")
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), store.Employees(), nil))
+	r.GET("/test", func(c *gin.Context) {
+		fromGin, ok := middleware.CurrentPrincipal(c)
+		assert.True(t, ok)
+		fromRequest, ok := service.PrincipalFromContext(c.Request.Context())
+		assert.True(t, ok)
+		assert.Equal(t, fromGin, fromRequest)
+		c.JSON(http.StatusOK, fromRequest)
+	})
+
+	w := serveWithToken(t, r, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var principal service.Principal
+	if err := json.NewDecoder(w.Body).Decode(&principal); err != nil {
+		t.Fatalf("ошибка при декодировании ответа: %v", err)
+	}
+	assert.Equal(t, service.Principal{
+		EmployeeID: admin.ID,
+		Username:   "admin",
+		Roles:      []string{model.RoleAdmin},
+		Scopes:     service.ScopesForRole(model.RoleAdmin),
+	}, principal)
+}
+
+func issueAccessToken(t *testing.T, svc *service.AccessTokenServiceImpl, username string, scopes ...string) string {
+	t.Helper()
+	issued, err := svc.CreateToken(context.Background(), username, service.CreateAccessTokenRequest{Name: "bot", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("ошибка при выпуске токена доступа: %v", err)
+	}
+	return issued.Token
+}
+
+func serveWithBearer(r *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTMiddleware_AccessTokens(t *testing.T) {
+	store := testEmployees()
+	accessTokens := service.NewAccessTokenService(store, service.SystemClock{})
+	infoToken := issueAccessToken(t, accessTokens, "testuser", service.ScopeInfoRead)
+	revoked := issueAccessToken(t, accessTokens, "testuser", service.ScopeInfoRead)
+	tokens, _ := accessTokens.ListTokens(context.Background(), "testuser")
+	if err := accessTokens.RevokeToken(context.Background(), "testuser", tokens[0].ID); err != nil {
+		t.Fatalf("ошибка при отзыве токена: %v", err)
+	}
+	// Токен деактивированного сотрудника перестаёт работать вместе с учётной записью
+	gone, _ := store.Employee("gone")
+	if err := store.Employees().SetDeactivatedAt(context.Background(), gone.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	deactivatedToken := issueAccessToken(t, accessTokens, "gone", service.ScopeInfoRead)
+	deactivatedAt := time.Now()
+	if err := store.Employees().SetDeactivatedAt(context.Background(), gone.ID, &deactivatedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), store.Employees(), accessTokens))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/info", middleware.RequireScope(service.ScopeInfoRead), ok)
+	r.GET("/sendCoin", middleware.RequireScope(service.ScopeTransferWrite), ok)
+	r.GET("/me/profile", middleware.RejectAccessTokens(), ok)
+
+	jwtToken, err := generateValidToken("testuser")
+	if err != nil {
+		t.Fatalf("ошибка при генерации токена: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name, path, token string
+		status            int
+	}{
+		{"право есть", "/info", infoToken, http.StatusOK},
+		{"права нет", "/sendCoin", infoToken, http.StatusForbidden},
+		{"маршрут только для сессии", "/me/profile", infoToken, http.StatusForbidden},
+		{"отозванный", "/info", revoked, http.StatusUnauthorized},
+		{"неизвестный", "/info", service.AccessTokenPrefix + "unknown", http.StatusUnauthorized},
+		{"сотрудник деактивирован", "/info", deactivatedToken, http.StatusUnauthorized},
+		{"JWT не ограничен правами", "/sendCoin", jwtToken, http.StatusOK},
+		{"JWT на маршруте для сессии", "/me/profile", jwtToken, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, serveWithBearer(r, tc.path, tc.token).Code)
+		})
+	}
+
+	w := serveWithBearer(r, "/sendCoin", infoToken)
+	assert.Contains(t, w.Body.String(), service.ScopeTransferWrite)
+}
+
+func TestJWTMiddleware_AccessTokensDisabled(t *testing.T) {
+	store := testEmployees()
+	token := issueAccessToken(t, service.NewAccessTokenService(store, service.SystemClock{}), "testuser", service.ScopeInfoRead)
+
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), store.Employees(), nil))
+	r.GET("/info", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithBearer(r, "/info", token).Code)
+}
+
+func serveWithClaims(t *testing.T, r *gin.Engine, path string, claims service.Claims) *httptest.ResponseRecorder {
+	t.Helper()
+	claims.RegisteredClaims = validRegisteredClaims()
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testJWTSecret)
+	if err != nil {
+		t.Fatalf("ошибка при подписании токена: %v", err)
+	}
+	return serveWithBearer(r, path, tokenString)
+}
+
+func TestRequireScope_ChecksScopesFromToken(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/info", middleware.RequireScope(service.ScopeInfoRead), ok)
+	r.GET("/admin", middleware.RequireScope(service.ScopeAdminEmployees, service.ScopeAdminBalances), ok)
+
+	for _, tc := range []struct {
+		name, path string
+		claims     service.Claims
+		status     int
+	}{
+		{"право в токене", "/info", service.Claims{Username: "testuser", Scope: "info:read transfer:write"}, http.StatusOK},
+		{"права нет в токене", "/info", service.Claims{Username: "testuser", Scope: "transfer:write"}, http.StatusForbidden},
+		{"токен без scope получает права роли", "/info", service.Claims{Username: "testuser"}, http.StatusOK},
+		{"администратор", "/admin", service.Claims{Username: "admin", Scope: "admin:employees admin:balances"}, http.StatusOK},
+		// Права сверяются с текущей ролью: токен не даёт больше, чем сотрудник может сейчас
+		{"сотрудник с правами администратора в токене", "/admin", service.Claims{Username: "testuser", Scope: "admin:employees admin:balances"}, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, serveWithClaims(t, r, tc.path, tc.claims).Code)
+		})
+	}
+}
+
+func TestRequireScope_ListsMissingScopes(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.JWTMiddleware(testJWTKeys(), testEmployees().Employees(), nil))
+	r.GET("/admin", middleware.RequireScope(service.ScopeAdminEmployees, service.ScopeAdminBalances), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := serveWithClaims(t, r, "/admin", service.Claims{Username: "admin", Scope: "admin:employees"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var response map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("ошибка при декодировании ответа: %v", err)
+	}
+	assert.Equal(t, "Недостаточно прав, нужно: admin:balances", response["errors"])
+}
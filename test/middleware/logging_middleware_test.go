@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"merch-api/middleware"
+	"merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID_GeneratesWhenMissing(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"requestID": c.GetString(middleware.RequestIDKey)})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	r.ServeHTTP(w, req)
+
+	requestID := w.Header().Get(middleware.RequestIDHeader)
+	assert.Len(t, requestID, 32)
+	assert.Contains(t, w.Body.String(), requestID)
+}
+
+func TestRequestID_PropagatesIncomingHeader(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", w.Header().Get(middleware.RequestIDHeader))
+}
+
+func TestRequestID_PutsRequestMetaIntoContext(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	var meta service.RequestMeta
+	r.GET("/test", func(c *gin.Context) {
+		meta = service.RequestMetaFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "10.0.0.7:41000"
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, service.RequestMeta{IP: "10.0.0.7", RequestID: "abc-123"}, meta)
+}
+
+func TestRequestLogger_WritesJSONLine(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.RequestLogger(logger))
+	r.GET("/items/:id", func(c *gin.Context) {
+		c.Set("username", "testuser")
+		c.Status(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	r.ServeHTTP(w, req)
+
+	var entry map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("ошибка при декодировании лога: %v", err)
+	}
+	assert.Equal(t, "http request", entry["msg"])
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.Equal(t, "testuser", entry["username"])
+	assert.Equal(t, "/items/:id", entry["route"])
+	assert.Equal(t, float64(http.StatusTeapot), entry["status"])
+	assert.Contains(t, entry, "latency")
+}
+
+func TestRequestLogger_SkipsConfiguredPaths(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := gin.New()
+	r.Use(middleware.RequestLogger(logger, "/healthz"))
+	r.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, buf.String())
+}
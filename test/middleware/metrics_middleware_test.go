@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"merch-api/metrics"
+	"merch-api/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsMiddleware_ObservesRouteTemplate(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.Metrics())
+	r.GET("/metrics-test/:item", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, item := range []string{"cup", "pen"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics-test/"+item, nil)
+		r.ServeHTTP(w, req)
+	}
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("ошибка при сборе метрик: %v", err)
+	}
+
+	var sampleCount uint64
+	for _, family := range families {
+		if family.GetName() != "merch_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == "/metrics-test/:item" && labels["status"] == "200" {
+				sampleCount = metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	assert.Equal(t, uint64(2), sampleCount)
+}
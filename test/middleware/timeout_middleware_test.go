@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"merch-api/httpx"
+	"merch-api/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTimeoutRouter(timeout time.Duration, h gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.APIVersion(httpx.APIVersionV2), middleware.Timeout(timeout))
+	r.GET("/slow", h)
+	return r
+}
+
+func TestTimeout_RespondsGatewayTimeoutWhenHandlerIsSilent(t *testing.T) {
+	r := newTimeoutRouter(10*time.Millisecond, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"timeout","message":"превышено время обработки запроса"}}`, w.Body.String())
+}
+
+func TestTimeout_OverridesErrorCausedByDeadline(t *testing.T) {
+	r := newTimeoutRouter(10*time.Millisecond, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		httpx.RespondError(c, http.StatusInternalServerError, "canceling statement due to user request")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "превышено время обработки запроса")
+}
+
+func TestTimeout_RespondsServiceUnavailableWhenRequestCanceled(t *testing.T) {
+	r := newTimeoutRouter(time.Minute, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "service_unavailable")
+}
+
+func TestTimeout_PassesFastRequestsAndSetsDeadline(t *testing.T) {
+	var hasDeadline bool
+	r := newTimeoutRouter(time.Second, func(c *gin.Context) {
+		_, hasDeadline = c.Request.Context().Deadline()
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, hasDeadline)
+}
+
+func TestTimeout_DisabledWithZero(t *testing.T) {
+	var hasDeadline bool
+	r := newTimeoutRouter(0, func(c *gin.Context) {
+		_, hasDeadline = c.Request.Context().Deadline()
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.False(t, hasDeadline)
+}
@@ -0,0 +1,298 @@
+package migrations
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"merch-api/migrations"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"1_create.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"1_create.down.sql": {Data: []byte("DROP TABLE a;")},
+		"2_index.up.sql":    {Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx_a ON a (id);")},
+		"2_index.down.sql":  {Data: []byte("-- migrate:no-transaction\nDROP INDEX CONCURRENTLY idx_a;")},
+	}
+}
+
+func newMigrator(t *testing.T, fsys fstest.MapFS) (*migrations.Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db, fsys)
+	if err != nil {
+		t.Fatalf("ошибка при чтении миграций: %v", err)
+	}
+	return migrator, mock
+}
+
+func expectLockedState(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+func stateRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"version", "dirty", "applied_at"})
+}
+
+func TestEmbeddedMigrationsAreConsistent(t *testing.T) {
+	loaded, err := migrations.Load(migrations.FS)
+	assert.NoError(t, err)
+	for _, migration := range loaded {
+		assert.NotEmpty(t, migration.Down, "у миграции %s нет down-файла", migration)
+	}
+
+	latest, err := migrations.LatestVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, loaded[len(loaded)-1].Version, latest)
+}
+
+func TestLoad_RejectsUnpairedDown(t *testing.T) {
+	_, err := migrations.Load(fstest.MapFS{
+		"1_create.up.sql":   {Data: []byte("SELECT 1;")},
+		"2_create.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "нет up-файла")
+}
+
+func TestLoad_RejectsUnknownFiles(t *testing.T) {
+	_, err := migrations.Load(fstest.MapFS{
+		"init.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "init.sql")
+}
+
+func TestUp_AppliesPendingInOrder(t *testing.T) {
+	migrator, mock := newMigrator(t, testFS())
+
+	expectLockedState(mock, stateRows())
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version\\) VALUES").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version, dirty\\) VALUES \\(\\$1, true\\)").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("CREATE INDEX CONCURRENTLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty = false").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, done, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_SkipsAppliedAndRollsBackFailed(t *testing.T) {
+	fsys := testFS()
+	fsys["3_broken.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE missing ADD COLUMN b INT;")}
+	migrator, mock := newMigrator(t, fsys)
+
+	expectLockedState(mock, stateRows().
+		AddRow(1, false, time.Now()).
+		AddRow(2, false, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE missing").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "3_broken")
+	assert.Empty(t, done)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_RefusesDirtyState(t *testing.T) {
+	migrator, mock := newMigrator(t, testFS())
+
+	expectLockedState(mock, stateRows().
+		AddRow(1, false, time.Now()).
+		AddRow(2, true, time.Now()))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := migrator.Up(context.Background())
+	assert.ErrorIs(t, err, migrations.ErrDirty)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDown_RollsBackLatest(t *testing.T) {
+	migrator, mock := newMigrator(t, testFS())
+
+	expectLockedState(mock, stateRows().
+		AddRow(1, false, time.Now()).
+		AddRow(2, false, time.Now()))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty = true").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DROP INDEX CONCURRENTLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Down(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, int64(2), done[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatus_ReportsPendingAndMissing(t *testing.T) {
+	migrator, mock := newMigrator(t, testFS())
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM schema_migrations").WillReturnRows(stateRows().
+		AddRow(1, false, time.Now()).
+		AddRow(5, false, time.Now()))
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+	assert.Equal(t, int64(5), statuses[2].Version)
+	assert.True(t, statuses[2].Missing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func integrityFS() fstest.MapFS {
+	return fstest.MapFS{
+		"20250310120000_schema_integrity.up.sql":   {Data: []byte("ALTER TABLE employee ADD CONSTRAINT uq_employee_username UNIQUE (username);")},
+		"20250310120000_schema_integrity.down.sql": {Data: []byte("ALTER TABLE employee DROP CONSTRAINT uq_employee_username;")},
+	}
+}
+
+func expectIntegrityChecks(mock sqlmock.Sqlmock, duplicates, negative, amounts *sqlmock.Rows) {
+	mock.ExpectQuery("SELECT username (.+) FROM employee GROUP BY username HAVING COUNT\\(\\*\\) > 1 (.+) LIMIT 20").WillReturnRows(duplicates)
+	mock.ExpectQuery("SELECT username (.+) FROM employee WHERE balance < 0 (.+) LIMIT 20").WillReturnRows(negative)
+	mock.ExpectQuery("SELECT 'id ' (.+) FROM transaction WHERE amount <= 0 (.+) LIMIT 20").WillReturnRows(amounts)
+}
+
+func violationRows(values ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"violation"})
+	for _, value := range values {
+		rows.AddRow(value)
+	}
+	return rows
+}
+
+func TestUp_StopsWithViolationReport(t *testing.T) {
+	migrator, mock := newMigrator(t, integrityFS())
+
+	expectLockedState(mock, stateRows())
+	expectIntegrityChecks(mock,
+		violationRows("alice (2 записей)"),
+		violationRows("bob: -10"),
+		violationRows())
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.Empty(t, done)
+
+	var violations *migrations.ViolationsError
+	if assert.ErrorAs(t, err, &violations) {
+		assert.Len(t, violations.Violations, 2)
+		assert.Equal(t, []string{"alice (2 записей)"}, violations.Violations[0].Rows)
+	}
+	assert.ErrorContains(t, err, "сотрудники с отрицательным балансом:\n    bob: -10")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_AppliesWhenChecksPass(t *testing.T) {
+	migrator, mock := newMigrator(t, integrityFS())
+
+	expectLockedState(mock, stateRows())
+	expectIntegrityChecks(mock, violationRows(), violationRows(), violationRows())
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE employee ADD CONSTRAINT uq_employee_username").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(int64(20250310120000)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheck_ReportsWithoutApplying(t *testing.T) {
+	migrator, mock := newMigrator(t, integrityFS())
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty, applied_at FROM schema_migrations").WillReturnRows(stateRows())
+	expectIntegrityChecks(mock, violationRows(), violationRows(), violationRows("id 7: 0"))
+
+	reports, err := migrator.Check(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "переводы с неположительной суммой", reports[0].Violations[0].Check.Description)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// legacyFS — первые миграции, схему которых раньше создавал init.sql, и одна более поздняя
+func legacyFS(t *testing.T) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"20250301120000_add_column.up.sql": {Data: []byte("ALTER TABLE purchase ADD COLUMN b INT;")},
+	}
+	for _, name := range []string{
+		"20250214012034_create_tables.up.sql",
+		"20250214012034_create_tables.down.sql",
+		"20250214012839_insert_merch.up.sql",
+		"20250214012839_insert_merch.down.sql",
+	} {
+		data, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			t.Fatalf("ошибка при чтении %s: %v", name, err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}
+
+func TestUp_AdoptsSchemaCreatedByInitSQL(t *testing.T) {
+	migrator, mock := newMigrator(t, legacyFS(t))
+
+	expectLockedState(mock, stateRows())
+	mock.ExpectQuery("SELECT to_regclass\\('employee'\\) IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version\\) VALUES").WithArgs(int64(20250214012034)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version\\) VALUES").WithArgs(int64(20250214012839)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE purchase ADD COLUMN b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version\\) VALUES").WithArgs(int64(20250301120000)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, "20250301120000_add_column", done[0].String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_CreatesSchemaInEmptyDatabase(t *testing.T) {
+	migrator, mock := newMigrator(t, legacyFS(t))
+
+	expectLockedState(mock, stateRows())
+	mock.ExpectQuery("SELECT to_regclass\\('employee'\\) IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	for _, version := range []int64{20250214012034, 20250214012839, 20250301120000} {
+		mock.ExpectBegin()
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations \\(version\\) VALUES").WithArgs(version).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, done, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository"
+	pgrepo "merch-api/repository/postgres"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T) (*pgrepo.Store, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+	return pgrepo.NewStore(gdb), mock
+}
+
+func TestEmployees_FindByUsernameNotFound(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
+		WithArgs("ghost", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := store.Employees().FindByUsername(context.Background(), "ghost")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_FindByUsernameForUpdateLocksRow(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+) FOR UPDATE").
+		WithArgs("user1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).AddRow(1, "user1", 100))
+
+	employee, err := store.Employees().FindByUsernameForUpdate(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, employee.Balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_UpdateBalance(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"employee\" SET \"balance\"=(.+) WHERE \"id\" = (.+)").
+		WithArgs(90, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Employees().UpdateBalance(context.Background(), 1, 90)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchases_Create(t *testing.T) {
+	store, mock := newStore(t)
+	key := "key-1"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO \"purchase\" (.+) VALUES (.+)").
+		WithArgs(1, 2, 3, key).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	purchase := model.Purchase{EmployeeID: 1, MerchID: 2, Quantity: 3, IdempotencyKey: &key}
+	err := store.Purchases().Create(context.Background(), &purchase)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), purchase.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchases_InventoryByEmployee(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT merch.name as type, SUM\\(purchase.quantity\\) as quantity FROM \"purchase\" (.+) GROUP BY \"merch\".\"name\"").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "quantity"}).AddRow("cup", 3))
+
+	rows, err := store.Purchases().InventoryByEmployee(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.InventoryRow{{Type: "cup", Quantity: 3}}, rows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactions_SentAndReceived(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" JOIN employee employee ON transaction.receiver_id = employee.id WHERE transaction.sender_id = (.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "amount"}).AddRow("user2", 10))
+	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" JOIN employee employee ON transaction.sender_id = employee.id WHERE transaction.receiver_id = (.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "amount"}).AddRow("user3", 20))
+
+	sent, err := store.Transactions().SentBy(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.CoinTransfer{{Username: "user2", Amount: 10}}, sent)
+
+	received, err := store.Transactions().ReceivedBy(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.CoinTransfer{{Username: "user3", Amount: 20}}, received)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTransaction_RollsBackOnError(t *testing.T) {
+	store, mock := newStore(t)
+	errFailed := errors.New("failed")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
+		WithArgs("cup", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(2, "cup", 20))
+	mock.ExpectRollback()
+
+	err := store.WithinTransaction(context.Background(), func(tx repository.Store) error {
+		if _, err := tx.Merch().FindByName(context.Background(), "cup"); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_FirstOrCreateReadsRowCreatedConcurrently(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
+		WithArgs("user1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO \"employee\" (.+)").
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
+		WithArgs("user1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).AddRow(3, "user1", 1000))
+
+	employee, err := store.Employees().FirstOrCreate(context.Background(), &model.Employee{Username: "user1", Password: "hash", Balance: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), employee.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_UpdateProfileWritesEmptyFields(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"employee\" SET \"display_name\"=\\$1,\"email\"=\\$2,\"department\"=\\$3,\"team\"=\\$4,\"avatar_url\"=\\$5 WHERE \"id\" = \\$6").
+		WithArgs("Алиса", "", "Разработка", "", "", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Employees().UpdateProfile(context.Background(), 1, model.EmployeeProfile{DisplayName: "Алиса", Department: "Разработка"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_SearchFiltersActiveAndRanksPrefixMatches(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM \"employee\" WHERE deactivated_at IS NULL AND \\(lower\\(username\\) LIKE \\$1 OR lower\\(display_name\\) LIKE \\$2 OR \\$3 <% lower\\(username\\) OR \\$4 <% lower\\(display_name\\)\\)").
+		WithArgs("ma\\_x%", "ma\\_x%", "ma_x", "ma_x").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM \"employee\" WHERE (.+) ORDER BY \\(lower\\(username\\) LIKE \\$5 OR lower\\(display_name\\) LIKE \\$6\\) DESC, GREATEST\\(word_similarity\\(\\$7, lower\\(username\\)\\), word_similarity\\(\\$8, lower\\(display_name\\)\\)\\) DESC, username LIMIT \\$9 OFFSET \\$10").
+		WithArgs("ma\\_x%", "ma\\_x%", "ma_x", "ma_x", "ma\\_x%", "ma\\_x%", "ma_x", "ma_x", 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "ma_xim"))
+
+	employees, total, err := store.Employees().Search(context.Background(), repository.EmployeeSearch{Query: "Ma_X", Limit: 10, Offset: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, employees, 1)
+	assert.Equal(t, "ma_xim", employees[0].Username)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_UpdatePasswordBumpsTokenVersion(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"employee\" SET \"password\"=\\$1,\"token_version\"=token_version \\+ 1 WHERE \"id\" = \\$2").
+		WithArgs("hash", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Employees().UpdatePassword(context.Background(), 1, "hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordResets_RevokeByEmployeeOnlyUnused(t *testing.T) {
+	store, mock := newStore(t)
+	revokedAt := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"password_reset_token\" SET \"used_at\"=\\$1 WHERE employee_id = \\$2 AND used_at IS NULL").
+		WithArgs(revokedAt, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := store.PasswordResets().RevokeByEmployee(context.Background(), 7, revokedAt)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginThrottles_ReserveUpsertsCounterUnlessLocked(t *testing.T) {
+	store, mock := newStore(t)
+	at := time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)
+	windowStart := at.Add(-time.Hour)
+	lockUntil := at.Add(5 * time.Minute)
+	query := "INSERT INTO login_throttle \\(throttle_key, failures, last_failure_at, locked_until\\)\\s+VALUES (.+)\\s+ON CONFLICT \\(throttle_key\\) DO UPDATE SET (.+)\\s+" +
+		"WHERE login_throttle.locked_until IS NULL OR login_throttle.locked_until <= EXCLUDED.last_failure_at\\s+RETURNING failures"
+
+	mock.ExpectQuery(query).
+		WithArgs("user:alice", at, 3, lockUntil, windowStart, windowStart, 3, lockUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+	mock.ExpectQuery(query).
+		WithArgs("user:alice", at, 3, lockUntil, windowStart, windowStart, 3, lockUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}))
+
+	failures, reserved, err := store.LoginThrottles().Reserve(context.Background(), "user:alice", at, windowStart, 3, lockUntil)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, 3, failures)
+
+	_, reserved, err = store.LoginThrottles().Reserve(context.Background(), "user:alice", at, windowStart, 3, lockUntil)
+	assert.NoError(t, err)
+	assert.False(t, reserved, "заблокированный ключ попытку не учитывает")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginThrottles_ReleaseClearsOnlyOwnLock(t *testing.T) {
+	store, mock := newStore(t)
+	lockUntil := time.Date(2025, 4, 25, 12, 5, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"login_throttle\" SET \"failures\"=GREATEST\\(failures - 1, 0\\),\"locked_until\"=CASE WHEN locked_until = \\$1 THEN NULL ELSE locked_until END WHERE \"throttle_key\" = \\$2").
+		WithArgs(lockUntil, "ip:10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.LoginThrottles().Release(context.Background(), "ip:10.0.0.1", lockUntil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_ReplacePasswordHashChecksOldHash(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"employee\" SET \"password\"=\\$1 WHERE password = \\$2 AND \"id\" = \\$3").
+		WithArgs("new-hash", "old-hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := store.Employees().ReplacePasswordHash(context.Background(), 1, "old-hash", "new-hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccessTokens_RevokeOnlyOwnActiveToken(t *testing.T) {
+	store, mock := newStore(t)
+	revokedAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, affected := range []int64{1, 0} {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE \"access_token\" SET \"revoked_at\"=\\$1 WHERE id = \\$2 AND employee_id = \\$3 AND revoked_at IS NULL").
+			WithArgs(revokedAt, 7, 1).
+			WillReturnResult(sqlmock.NewResult(0, affected))
+		mock.ExpectCommit()
+	}
+
+	assert.NoError(t, store.AccessTokens().Revoke(context.Background(), 1, 7, revokedAt))
+	err := store.AccessTokens().Revoke(context.Background(), 1, 7, revokedAt)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccessTokens_CountActiveSkipsExpired(t *testing.T) {
+	store, mock := newStore(t)
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM \"access_token\" WHERE employee_id = \\$1 AND revoked_at IS NULL AND \\(expires_at IS NULL OR expires_at > \\$2\\)").
+		WithArgs(1, now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := store.AccessTokens().CountActive(context.Background(), 1, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditLog_SearchFiltersNewestFirst(t *testing.T) {
+	store, mock := newStore(t)
+	from := time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT \\* FROM \"audit_log\" WHERE actor = \\$1 AND created_at >= \\$2 AND id < \\$3 ORDER BY id DESC LIMIT \\$4").
+		WithArgs("alice", from, 42, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "action"}).AddRow(41, "alice", "transfer.create"))
+
+	events, err := store.AuditLog().Search(context.Background(), repository.AuditSearch{Actor: "alice", From: from, BeforeID: 42, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, uint(41), events[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditLog_SearchWithoutLimitReadsAll(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectQuery("SELECT \\* FROM \"audit_log\" ORDER BY id DESC$").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	events, err := store.AuditLog().Search(context.Background(), repository.AuditSearch{})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmployees_LinkExternalIdentityOnlyWithoutPassword(t *testing.T) {
+	store, mock := newStore(t)
+
+	for _, affected := range []int64{1, 0} {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE \"employee\" SET \"auth_provider\"=\\$1,\"auth_subject\"=\\$2 WHERE \\(auth_provider = '' AND password = ''\\) AND \"id\" = \\$3").
+			WithArgs("https://sso.example.com", "sub-alice", 7).
+			WillReturnResult(sqlmock.NewResult(0, affected))
+		mock.ExpectCommit()
+	}
+
+	err := store.Employees().LinkExternalIdentity(context.Background(), 7, "https://sso.example.com", "sub-alice")
+	assert.NoError(t, err)
+	err = store.Employees().LinkExternalIdentity(context.Background(), 7, "https://sso.example.com", "sub-alice")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
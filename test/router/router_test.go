@@ -0,0 +1,158 @@
+package router
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"merch-api/config"
+	router2 "merch-api/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Secret:   []byte("test-secret-test-secret-test-secret"),
+			TTL:      time.Hour,
+			Issuer:   "merch-api",
+			Audience: "merch-api",
+		},
+		Features: config.FeatureConfig{LegacyBuyGet: true},
+	}
+}
+
+func TestSetupRouter_VersionedRoutes(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	r := router2.SetupRouter(gdb, testConfig())
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, prefix := range []string{"/api", "/api/v1", "/api/v2"} {
+		assert.True(t, registered["POST "+prefix+"/auth"], prefix+"/auth")
+		assert.True(t, registered["GET "+prefix+"/buy/:item"], prefix+"/buy/:item")
+		assert.True(t, registered["POST "+prefix+"/purchases"], prefix+"/purchases")
+		assert.True(t, registered["POST "+prefix+"/sendCoin"], prefix+"/sendCoin")
+		assert.True(t, registered["GET "+prefix+"/info"], prefix+"/info")
+		assert.True(t, registered["GET "+prefix+"/me/profile"], prefix+"/me/profile")
+		assert.True(t, registered["PATCH "+prefix+"/me/profile"], prefix+"/me/profile")
+		assert.True(t, registered["GET "+prefix+"/employees"], prefix+"/employees")
+		assert.True(t, registered["POST "+prefix+"/me/password"], prefix+"/me/password")
+		assert.True(t, registered["GET "+prefix+"/me/tokens"], prefix+"/me/tokens")
+		assert.True(t, registered["POST "+prefix+"/me/tokens"], prefix+"/me/tokens")
+		assert.True(t, registered["DELETE "+prefix+"/me/tokens/:id"], prefix+"/me/tokens/:id")
+		assert.True(t, registered["POST "+prefix+"/password/reset"], prefix+"/password/reset")
+		assert.True(t, registered["POST "+prefix+"/admin/employees/:username/password-reset"], prefix+"/admin password-reset")
+		assert.True(t, registered["POST "+prefix+"/admin/employees/:username/unlock"], prefix+"/admin unlock employee")
+		assert.True(t, registered["POST "+prefix+"/admin/ips/:ip/unlock"], prefix+"/admin unlock ip")
+		assert.True(t, registered["POST "+prefix+"/admin/employees/:username/deactivate"], prefix+"/admin deactivate")
+		assert.True(t, registered["POST "+prefix+"/admin/employees/:username/reactivate"], prefix+"/admin reactivate")
+		assert.True(t, registered["GET "+prefix+"/admin/audit"], prefix+"/admin audit")
+		assert.True(t, registered["GET "+prefix+"/admin/audit/export"], prefix+"/admin audit export")
+	}
+}
+
+func TestSetupRouter_V1AliasKeepsLegacyErrors(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	r := router2.SetupRouter(gdb, testConfig())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/info", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"errors"`)
+}
+
+func TestSetupRouter_V2ErrorEnvelope(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	r := router2.SetupRouter(gdb, testConfig())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/info", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":{"code":"unauthorized"`)
+}
+
+func TestSetupRouter_LegacyBuyDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Features.LegacyBuyGet = false
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	r := router2.SetupRouter(gdb, cfg)
+
+	for _, route := range r.Routes() {
+		assert.NotEqual(t, "/api/buy/:item", route.Path)
+	}
+}
+
+func TestSetupRouter_MetricsEndpoint(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+
+	r := router2.SetupRouter(gdb, testConfig())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `merch_http_request_duration_seconds_count{method="GET",route="/api/info",status="401"}`)
+	assert.Contains(t, w.Body.String(), `merch_auth_failures_total{reason="missing_token"}`)
+	assert.Contains(t, w.Body.String(), `go_sql_max_open_connections{db_name="merch"}`)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	server2 "merch-api/server"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe_DrainsInFlightRequestsOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ошибка при создании listener: %v", err)
+	}
+
+	srv := server2.New(server2.Config{WriteTimeout: time.Second}, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server2.Serve(ctx, srv, listener, 2*time.Second)
+	}()
+
+	responseStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responseStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		responseStatus <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, http.StatusOK, <-responseStatus)
+	assert.NoError(t, <-serveErr)
+
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestServe_ShutdownTimeoutExceeded(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ошибка при создании listener: %v", err)
+	}
+
+	srv := server2.New(server2.Config{}, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server2.Serve(ctx, srv, listener, 50*time.Millisecond)
+	}()
+	go http.Get("http://" + listener.Addr().String())
+
+	<-started
+	cancel()
+
+	assert.ErrorIs(t, <-serveErr, context.DeadlineExceeded)
+}
+
+func TestNew_AppliesConfig(t *testing.T) {
+	srv := server2.New(server2.Config{
+		Host:              "127.0.0.1",
+		Port:              "9090",
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+	}, http.NotFoundHandler())
+
+	assert.Equal(t, "127.0.0.1:9090", srv.Addr)
+	assert.Equal(t, time.Second, srv.ReadTimeout)
+	assert.Equal(t, 2*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 4*time.Second, srv.IdleTimeout)
+}
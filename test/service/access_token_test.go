@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+var accessTokenNow = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newAccessTokenStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "alice", Balance: 1000})
+	store.AddEmployee(model.Employee{Username: "bob", Balance: 1000})
+	return store
+}
+
+func TestCreateAccessToken_StoresOnlyHash(t *testing.T) {
+	store := newAccessTokenStore()
+	svc := service2.NewAccessTokenService(store, fixedClock{now: accessTokenNow})
+
+	issued, err := svc.CreateToken(context.Background(), "alice", service2.CreateAccessTokenRequest{
+		Name:   " Слак-бот ",
+		Scopes: []string{service2.ScopeTransferWrite, service2.ScopeInfoRead, service2.ScopeTransferWrite},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Token, service2.AccessTokenPrefix))
+	assert.True(t, strings.HasPrefix(issued.Token, issued.Prefix))
+	assert.Equal(t, "Слак-бот", issued.Name)
+	assert.Equal(t, []string{service2.ScopeInfoRead, service2.ScopeTransferWrite}, issued.Scopes)
+	assert.Equal(t, accessTokenNow, issued.CreatedAt)
+
+	stored := store.AccessTokensList()
+	require.Len(t, stored, 1)
+	assert.NotContains(t, stored[0].TokenHash, issued.Token)
+	assert.NotEqual(t, issued.Token, stored[0].Prefix)
+
+	employee, token, err := svc.AuthenticateToken(context.Background(), issued.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", employee.Username)
+	assert.Equal(t, issued.ID, token.ID)
+}
+
+func TestCreateAccessToken_Validation(t *testing.T) {
+	past := accessTokenNow.Add(-time.Second)
+	for name, req := range map[string]service2.CreateAccessTokenRequest{
+		"без имени":         {Name: "  ", Scopes: []string{service2.ScopeInfoRead}},
+		"длинное имя":       {Name: strings.Repeat("я", service2.MaxAccessTokenNameLength+1), Scopes: []string{service2.ScopeInfoRead}},
+		"без прав":          {Name: "bot"},
+		"неизвестное право": {Name: "bot", Scopes: []string{"admin:catalog"}},
+		"срок уже наступил": {Name: "bot", Scopes: []string{service2.ScopeInfoRead}, ExpiresAt: &past},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := service2.NewAccessTokenService(newAccessTokenStore(), fixedClock{now: accessTokenNow})
+			_, err := svc.CreateToken(context.Background(), "alice", req)
+			assert.ErrorIs(t, err, service2.ErrInvalidAccessTokenRequest)
+		})
+	}
+}
+
+func TestCreateAccessToken_LimitsActiveTokens(t *testing.T) {
+	store := newAccessTokenStore()
+	svc := service2.NewAccessTokenService(store, fixedClock{now: accessTokenNow})
+	req := service2.CreateAccessTokenRequest{Name: "bot", Scopes: []string{service2.ScopeInfoRead}}
+
+	var first *service2.IssuedAccessToken
+	for i := 0; i < service2.MaxAccessTokensPerEmployee; i++ {
+		issued, err := svc.CreateToken(context.Background(), "alice", req)
+		require.NoError(t, err)
+		if first == nil {
+			first = issued
+		}
+	}
+	_, err := svc.CreateToken(context.Background(), "alice", req)
+	assert.ErrorIs(t, err, service2.ErrTooManyAccessTokens)
+
+	// Ограничение у каждого сотрудника своё, отозванные токены не считаются
+	_, err = svc.CreateToken(context.Background(), "bob", req)
+	assert.NoError(t, err)
+	require.NoError(t, svc.RevokeToken(context.Background(), "alice", first.ID))
+	_, err = svc.CreateToken(context.Background(), "alice", req)
+	assert.NoError(t, err)
+}
+
+func TestCreateAccessToken_ExpiredTokensFreeSlots(t *testing.T) {
+	clock := &movingClock{now: accessTokenNow}
+	svc := service2.NewAccessTokenService(newAccessTokenStore(), clock)
+	expiresAt := accessTokenNow.Add(time.Hour)
+
+	_, err := svc.CreateToken(context.Background(), "alice", service2.CreateAccessTokenRequest{
+		Name:      "temporary",
+		Scopes:    []string{service2.ScopeInfoRead},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	req := service2.CreateAccessTokenRequest{Name: "bot", Scopes: []string{service2.ScopeInfoRead}}
+	for i := 1; i < service2.MaxAccessTokensPerEmployee; i++ {
+		_, err := svc.CreateToken(context.Background(), "alice", req)
+		require.NoError(t, err)
+	}
+	_, err = svc.CreateToken(context.Background(), "alice", req)
+	assert.ErrorIs(t, err, service2.ErrTooManyAccessTokens)
+
+	clock.now = expiresAt
+	tokens, err := svc.ListTokens(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Len(t, tokens, service2.MaxAccessTokensPerEmployee-1)
+	_, err = svc.CreateToken(context.Background(), "alice", req)
+	assert.NoError(t, err)
+}
+
+func TestListAccessTokens_NewestFirstWithoutRevoked(t *testing.T) {
+	svc := service2.NewAccessTokenService(newAccessTokenStore(), fixedClock{now: accessTokenNow})
+	req := service2.CreateAccessTokenRequest{Scopes: []string{service2.ScopeInfoRead}}
+	for _, name := range []string{"first", "second", "third"} {
+		req.Name = name
+		_, err := svc.CreateToken(context.Background(), "alice", req)
+		require.NoError(t, err)
+	}
+	_, err := svc.CreateToken(context.Background(), "bob", service2.CreateAccessTokenRequest{Name: "bob's", Scopes: req.Scopes})
+	require.NoError(t, err)
+
+	tokens, err := svc.ListTokens(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Len(t, tokens, 3)
+	require.NoError(t, svc.RevokeToken(context.Background(), "alice", tokens[1].ID))
+
+	tokens, err = svc.ListTokens(context.Background(), "alice")
+	require.NoError(t, err)
+	var names []string
+	for _, token := range tokens {
+		names = append(names, token.Name)
+	}
+	assert.Equal(t, []string{"third", "first"}, names)
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	svc := service2.NewAccessTokenService(newAccessTokenStore(), fixedClock{now: accessTokenNow})
+	issued, err := svc.CreateToken(context.Background(), "alice", service2.CreateAccessTokenRequest{
+		Name:   "bot",
+		Scopes: []string{service2.ScopeInfoRead},
+	})
+	require.NoError(t, err)
+
+	err = svc.RevokeToken(context.Background(), "bob", issued.ID)
+	assert.ErrorIs(t, err, service2.ErrAccessTokenNotFound, "чужой токен отозвать нельзя")
+
+	require.NoError(t, svc.RevokeToken(context.Background(), "alice", issued.ID))
+	_, _, err = svc.AuthenticateToken(context.Background(), issued.Token)
+	assert.ErrorIs(t, err, service2.ErrInvalidAccessToken)
+
+	err = svc.RevokeToken(context.Background(), "alice", issued.ID)
+	assert.ErrorIs(t, err, service2.ErrAccessTokenNotFound, "повторный отзыв")
+}
+
+func TestAuthenticateAccessToken_Expiry(t *testing.T) {
+	clock := &movingClock{now: accessTokenNow}
+	svc := service2.NewAccessTokenService(newAccessTokenStore(), clock)
+	expiresAt := accessTokenNow.Add(24 * time.Hour)
+	issued, err := svc.CreateToken(context.Background(), "alice", service2.CreateAccessTokenRequest{
+		Name:      "bot",
+		Scopes:    []string{service2.ScopeInfoRead},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	clock.now = expiresAt.Add(-time.Second)
+	_, _, err = svc.AuthenticateToken(context.Background(), issued.Token)
+	assert.NoError(t, err)
+
+	clock.now = expiresAt
+	_, _, err = svc.AuthenticateToken(context.Background(), issued.Token)
+	assert.ErrorIs(t, err, service2.ErrInvalidAccessToken)
+
+	_, _, err = svc.AuthenticateToken(context.Background(), service2.AccessTokenPrefix+"unknown")
+	assert.ErrorIs(t, err, service2.ErrInvalidAccessToken)
+}
+
+func TestAuthenticateAccessToken_RecordsUsageAtMostOncePerMinute(t *testing.T) {
+	store := newAccessTokenStore()
+	clock := &movingClock{now: accessTokenNow}
+	svc := service2.NewAccessTokenService(store, clock)
+	issued, err := svc.CreateToken(context.Background(), "alice", service2.CreateAccessTokenRequest{
+		Name:   "bot",
+		Scopes: []string{service2.ScopeInfoRead},
+	})
+	require.NoError(t, err)
+	lastUsed := func() *time.Time { return store.AccessTokensList()[0].LastUsedAt }
+
+	assert.Nil(t, lastUsed())
+	for _, at := range []time.Duration{0, 30 * time.Second} {
+		clock.now = accessTokenNow.Add(at)
+		_, _, err = svc.AuthenticateToken(context.Background(), issued.Token)
+		require.NoError(t, err)
+	}
+	require.NotNil(t, lastUsed())
+	assert.Equal(t, accessTokenNow, *lastUsed())
+
+	clock.now = accessTokenNow.Add(time.Minute)
+	_, _, err = svc.AuthenticateToken(context.Background(), issued.Token)
+	require.NoError(t, err)
+	assert.Equal(t, accessTokenNow.Add(time.Minute), *lastUsed())
+}
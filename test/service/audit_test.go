@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+func auditContext(username string) context.Context {
+	ctx := service2.WithPrincipal(context.Background(), service2.Principal{Username: username, Roles: []string{model.RoleAdmin}})
+	return service2.WithRequestMeta(ctx, service2.RequestMeta{IP: "10.0.0.7", RequestID: "req-1"})
+}
+
+func TestAudit_TransferRecordsBalances(t *testing.T) {
+	store := newEmployeeStore()
+
+	_, err := service2.NewTransactionService(store).SendCoins(auditContext("alice"), "alice", "bob", 40)
+	require.NoError(t, err)
+
+	events := store.AuditLogList()
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, service2.AuditTransfer, event.Action)
+	assert.Equal(t, "alice", event.Actor)
+	assert.Equal(t, "bob", event.Target)
+	assert.Equal(t, 300, *event.BalanceBefore)
+	assert.Equal(t, 260, *event.BalanceAfter)
+	assert.Equal(t, "сумма 40, баланс получателя 100 -> 140", event.Details)
+	assert.Equal(t, "10.0.0.7", event.IP)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Nil(t, event.AccessTokenID)
+}
+
+func TestAudit_FailedOperationLeavesNoEvent(t *testing.T) {
+	store := newEmployeeStore()
+
+	_, err := service2.NewTransactionService(store).SendCoins(auditContext("bob"), "bob", "alice", 1000)
+	assert.Error(t, err)
+	assert.Empty(t, store.AuditLogList())
+}
+
+func TestAudit_RecordsAccessTokenAndAdminActor(t *testing.T) {
+	store := newEmployeeStore()
+	ctx := service2.WithPrincipal(context.Background(), service2.Principal{Username: "root", AccessTokenID: 9})
+
+	_, err := newEmployeeService(store).Deactivate(ctx, service2.DeactivateRequest{Username: "alice", ForfeitBalance: true})
+	require.NoError(t, err)
+
+	events := store.AuditLogList()
+	require.Len(t, events, 1)
+	assert.Equal(t, service2.AuditEmployeeDeactivate, events[0].Action)
+	assert.Equal(t, "root", events[0].Actor)
+	assert.Equal(t, "alice", events[0].Target)
+	assert.Equal(t, uint(9), *events[0].AccessTokenID)
+	assert.Equal(t, 300, *events[0].BalanceBefore)
+	assert.Equal(t, 0, *events[0].BalanceAfter)
+}
+
+func TestAudit_LoginEvents(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+	ctx := service2.WithRequestMeta(context.Background(), service2.RequestMeta{IP: "10.0.0.8"})
+
+	_, err := authService.AuthenticateUser(ctx, service2.LoginRequest{Username: "alice", Password: "secret"})
+	require.NoError(t, err)
+	_, err = authService.AuthenticateUser(ctx, service2.LoginRequest{Username: "alice", Password: "wrong"})
+	require.ErrorIs(t, err, service2.ErrPasswordMismatch)
+
+	events := store.AuditLogList()
+	require.Len(t, events, 2)
+	assert.Equal(t, service2.AuditLogin, events[0].Action)
+	assert.Equal(t, "alice", events[0].Actor)
+	assert.Equal(t, service2.AuditLoginFailed, events[1].Action)
+	assert.Equal(t, "неверный логин или пароль", events[1].Details)
+	assert.Equal(t, "10.0.0.8", events[1].IP)
+}
+
+func TestAudit_ClipsLongActor(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), stubAuthenticator{err: service2.ErrPasswordMismatch}, nil)
+	username := strings.Repeat("я", 100)
+
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: username, Password: "wrong"})
+	require.ErrorIs(t, err, service2.ErrPasswordMismatch)
+
+	events := store.AuditLogList()
+	require.Len(t, events, 1)
+	assert.Equal(t, strings.Repeat("я", 64), events[0].Actor)
+}
+
+func appendAuditEvents(t *testing.T, store *memory.Store, count int) {
+	for i := 1; i <= count; i++ {
+		action := service2.AuditPurchase
+		if i%2 == 0 {
+			action = service2.AuditTransfer
+		}
+		err := store.AuditLog().Append(context.Background(), &model.AuditEvent{
+			Actor:     "alice",
+			Action:    action,
+			Target:    fmt.Sprintf("item-%d", i),
+			CreatedAt: time.Date(2025, 5, 10, 0, i, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestAuditSearch_PagesNewestFirst(t *testing.T) {
+	store := memory.NewStore()
+	appendAuditEvents(t, store, 5)
+	svc := service2.NewAuditService(store)
+
+	page, err := svc.Search(context.Background(), service2.AuditQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, []uint{5, 4}, []uint{page.Events[0].ID, page.Events[1].ID})
+	assert.Equal(t, uint(4), page.NextBefore)
+
+	page, err = svc.Search(context.Background(), service2.AuditQuery{Limit: 2, Before: page.NextBefore, Action: service2.AuditPurchase})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, []uint{3, 1}, []uint{page.Events[0].ID, page.Events[1].ID})
+	assert.Zero(t, page.NextBefore)
+
+	page, err = svc.Search(context.Background(), service2.AuditQuery{
+		From: time.Date(2025, 5, 10, 0, 2, 0, 0, time.UTC),
+		To:   time.Date(2025, 5, 10, 0, 4, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, "item-3", page.Events[0].Target)
+	assert.Equal(t, "item-2", page.Events[1].Target)
+}
+
+func TestAuditSearch_RejectsInvalidQuery(t *testing.T) {
+	now := time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC)
+	svc := service2.NewAuditService(memory.NewStore())
+	for name, query := range map[string]service2.AuditQuery{
+		"отрицательный limit":   {Limit: -1},
+		"слишком большой limit": {Limit: service2.MaxAuditLimit + 1},
+		"from не раньше to":     {From: now, To: now},
+	} {
+		_, err := svc.Search(context.Background(), query)
+		assert.ErrorIs(t, err, service2.ErrInvalidAuditQuery, name)
+	}
+}
+
+func TestAuditExport_ReadsAllBatches(t *testing.T) {
+	store := memory.NewStore()
+	appendAuditEvents(t, store, 1201)
+
+	var ids []uint
+	err := service2.NewAuditService(store).Export(context.Background(), service2.AuditQuery{}, func(entry service2.AuditEntry) error {
+		ids = append(ids, entry.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1201)
+	assert.Equal(t, uint(1201), ids[0])
+	assert.Equal(t, uint(1), ids[len(ids)-1])
+}
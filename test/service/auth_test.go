@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"merch-api/config"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestIssueToken_UsesClockAndConfig(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	secret := []byte("test-secret-test-secret-test-secret")
+	authService := service2.NewAuthService(nil, service2.NewJWTKeys(config.JWTConfig{Secret: secret, TTL: time.Hour}, fixedClock{now: now}), service2.NewLocalAuthenticator(nil, testHasher()), nil)
+
+	tokenString, err := authService.IssueToken(&model.Employee{Username: "user1", TokenVersion: 3})
+	assert.NoError(t, err)
+
+	claims := &service2.Claims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithTimeFunc(func() time.Time { return now }))
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", claims.Username)
+	assert.Equal(t, 3, claims.TokenVersion)
+	assert.True(t, now.Equal(claims.IssuedAt.Time))
+	assert.True(t, now.Add(time.Hour).Equal(claims.ExpiresAt.Time))
+}
+
+func TestIssueToken_PassesParseToken(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testJWTConfig()
+	cfg.Issuer, cfg.Audience, cfg.ClockSkew = "merch-api", "merch-api", 30*time.Second
+	authService := service2.NewAuthService(nil, service2.NewJWTKeys(cfg, fixedClock{now: now}), nil, nil)
+
+	tokenString, err := authService.IssueToken(&model.Employee{Username: "user1", TokenVersion: 2})
+	assert.NoError(t, err)
+
+	claims, err := service2.NewJWTKeys(cfg, fixedClock{now: now}).ParseToken(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", claims.Username)
+	assert.Equal(t, 2, claims.TokenVersion)
+	assert.Equal(t, "merch-api", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"merch-api"}, claims.Audience)
+	assert.True(t, now.Equal(claims.NotBefore.Time))
+
+	other := cfg
+	other.Audience = "reports"
+	_, err = service2.NewJWTKeys(other, fixedClock{now: now}).ParseToken(tokenString)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	_, err = service2.NewJWTKeys(cfg, fixedClock{now: now.Add(time.Hour + time.Minute)}).ParseToken(tokenString)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{Secret: []byte("test-secret-test-secret-test-secret"), TTL: time.Hour}
+}
+
+func testJWTKeys() *service2.JWTKeys {
+	return service2.NewJWTKeys(testJWTConfig(), service2.SystemClock{})
+}
+
+func TestAuthenticateUser_CreatesEmployeeOnFirstLogin(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+
+	token, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "newcomer", Password: "password"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	employee, ok := store.Employee("newcomer")
+	assert.True(t, ok)
+	assert.Equal(t, 1000, employee.Balance)
+	assert.NotEqual(t, "password", employee.Password)
+
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "newcomer", Password: "wrong"})
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+}
+
+// stubAuthenticator подменяет провайдера входа: возвращает заданную личность или ошибку
+type stubAuthenticator struct {
+	identity *service2.Identity
+	err      error
+}
+
+func (a stubAuthenticator) Authenticate(context.Context, string, string) (*service2.Identity, error) {
+	return a.identity, a.err
+}
+
+func TestAuthenticateUser_ProvisionsIdentityOnce(t *testing.T) {
+	store := memory.NewStore()
+	identity := &service2.Identity{
+		Username: "alice",
+		Provider: service2.LDAPProvider,
+		Subject:  "uid=alice,ou=people,dc=example,dc=com",
+		Profile:  model.EmployeeProfile{DisplayName: strings.Repeat("я", service2.MaxDisplayNameLength+10)},
+	}
+	authService := service2.NewAuthService(store, testJWTKeys(), stubAuthenticator{identity: identity}, nil)
+
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.NoError(t, err)
+	employee, ok := store.Employee("alice")
+	assert.True(t, ok)
+	assert.Equal(t, strings.Repeat("я", service2.MaxDisplayNameLength), employee.Profile.DisplayName)
+
+	// Профиль из провайдера не перетирает то, что сотрудник поменял сам
+	identity.Profile.DisplayName = "Алиса"
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.NoError(t, err)
+	employee, _ = store.Employee("alice")
+	assert.Equal(t, strings.Repeat("я", service2.MaxDisplayNameLength), employee.Profile.DisplayName)
+}
+
+func TestAuthenticateUser_ProviderOutageIsNotCountedAsFailure(t *testing.T) {
+	store := memory.NewStore()
+	outage := errors.New("LDAP недоступен")
+	throttle := service2.NewLoginThrottle(store, testThrottleConfig(), service2.SystemClock{})
+	authService := service2.NewAuthService(store, testJWTKeys(), stubAuthenticator{err: outage}, throttle)
+
+	for range testThrottleConfig().MaxFailuresPerUser + 1 {
+		_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+		assert.ErrorIs(t, err, outage)
+	}
+}
+
+func TestAuthenticateUser_LocalRejectsEmployeesWithoutPassword(t *testing.T) {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "sso-user", Balance: 1000})
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "sso-user", Password: ""})
+	assert.ErrorIs(t, err, service2.ErrInvalidInput)
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "sso-user", Password: "anything"})
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+}
+
+func ssoIdentity(username, subject string) *service2.Identity {
+	return &service2.Identity{Username: username, Provider: "https://sso.example.com", Subject: subject}
+}
+
+func TestLoginWithIdentity_RefusesEmployeeWithLocalPassword(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+
+	// Логин коллеги заранее заняли через локальный вход
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "squatter"})
+	require.NoError(t, err)
+
+	_, err = authService.LoginWithIdentity(context.Background(), ssoIdentity("alice", "sub-alice"))
+	assert.ErrorIs(t, err, service2.ErrExternalIdentityConflict)
+
+	employee, _ := store.Employee("alice")
+	assert.Empty(t, employee.AuthProvider)
+	events := store.AuditLogList()
+	require.NotEmpty(t, events)
+	assert.Equal(t, service2.AuditLoginFailed, events[len(events)-1].Action)
+}
+
+func TestLoginWithIdentity_MatchesSubjectNotUsername(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), nil, nil)
+
+	_, err := authService.LoginWithIdentity(context.Background(), ssoIdentity("alice", "sub-alice"))
+	require.NoError(t, err)
+	alice, _ := store.Employee("alice")
+	assert.Equal(t, "https://sso.example.com", alice.AuthProvider)
+	assert.Equal(t, "sub-alice", alice.AuthSubject)
+
+	// Другая учётная запись провайдера назвалась alice: связь уже есть, входа нет
+	_, err = authService.LoginWithIdentity(context.Background(), ssoIdentity("alice", "sub-mallory"))
+	assert.ErrorIs(t, err, service2.ErrExternalIdentityConflict)
+
+	// Сменившая логин у провайдера alice по-прежнему входит в свою учётную запись
+	token, err := authService.LoginWithIdentity(context.Background(), ssoIdentity("alice.smith", "sub-alice"))
+	require.NoError(t, err)
+	claims, err := testJWTKeys().ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	_, ok := store.Employee("alice.smith")
+	assert.False(t, ok)
+}
+
+func TestLoginWithIdentity_LinksEmployeeCreatedBySSOBefore(t *testing.T) {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "bob", Balance: 700})
+	authService := service2.NewAuthService(store, testJWTKeys(), nil, nil)
+
+	_, err := authService.LoginWithIdentity(context.Background(), ssoIdentity("bob", "sub-bob"))
+	require.NoError(t, err)
+
+	bob, _ := store.Employee("bob")
+	assert.Equal(t, 700, bob.Balance)
+	assert.Equal(t, "sub-bob", bob.AuthSubject)
+}
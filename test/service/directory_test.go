@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newDirectoryStore() *memory.Store {
+	store := memory.NewStore()
+	deactivatedAt := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	store.AddEmployee(model.Employee{Username: "amaria", Profile: model.EmployeeProfile{DisplayName: "Анна Мария"}})
+	store.AddEmployee(model.Employee{Username: "mivanova", Profile: model.EmployeeProfile{DisplayName: "Мария Иванова", Team: "Платформа"}})
+	store.AddEmployee(model.Employee{Username: "mpetrov", Profile: model.EmployeeProfile{DisplayName: "Максим Петров"}})
+	store.AddEmployee(model.Employee{Username: "mgone", DeactivatedAt: &deactivatedAt, Profile: model.EmployeeProfile{DisplayName: "Мария Ушедшая"}})
+	store.AddEmployee(model.Employee{Username: "bob"})
+	return store
+}
+
+func TestSearchEmployees_PrefixMatchesFirstAndDeactivatedExcluded(t *testing.T) {
+	directoryService := service2.NewDirectoryService(newDirectoryStore())
+
+	page, err := directoryService.SearchEmployees(context.Background(), service2.DirectorySearch{Query: " мария "})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Equal(t, service2.DefaultDirectoryLimit, page.Limit)
+	assert.Equal(t, []service2.DirectoryEntry{
+		{Username: "mivanova", DisplayName: "Мария Иванова", Team: "Платформа"},
+		{Username: "amaria", DisplayName: "Анна Мария"},
+	}, page.Employees)
+}
+
+func TestSearchEmployees_Pagination(t *testing.T) {
+	directoryService := service2.NewDirectoryService(newDirectoryStore())
+
+	page, err := directoryService.SearchEmployees(context.Background(), service2.DirectorySearch{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	assert.Len(t, page.Employees, 2)
+	assert.Equal(t, "mivanova", page.Employees[0].Username)
+	assert.Equal(t, "mpetrov", page.Employees[1].Username)
+}
+
+func TestSearchEmployees_EmptyResultIsNotNil(t *testing.T) {
+	directoryService := service2.NewDirectoryService(newDirectoryStore())
+
+	page, err := directoryService.SearchEmployees(context.Background(), service2.DirectorySearch{Query: "nobody"})
+	assert.NoError(t, err)
+	assert.NotNil(t, page.Employees)
+	assert.Empty(t, page.Employees)
+}
+
+func TestSearchEmployees_InvalidParams(t *testing.T) {
+	directoryService := service2.NewDirectoryService(nil)
+
+	for _, search := range []service2.DirectorySearch{
+		{Limit: -1},
+		{Limit: service2.MaxDirectoryLimit + 1},
+		{Offset: -1},
+		{Query: strings.Repeat("я", service2.MaxDirectoryQuery+1)},
+	} {
+		_, err := directoryService.SearchEmployees(context.Background(), search)
+		assert.ErrorIs(t, err, service2.ErrInvalidDirectorySearch)
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"testing"
+	"time"
+)
+
+var deactivationTime = time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+
+func newEmployeeStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "alice", Balance: 300})
+	store.AddEmployee(model.Employee{Username: "bob", Balance: 100})
+	store.AddEmployee(model.Employee{Username: "carol", Balance: 50, DeactivatedAt: &deactivationTime})
+	return store
+}
+
+func newEmployeeService(store *memory.Store) *service2.EmployeeServiceImpl {
+	return service2.NewEmployeeService(store, fixedClock{now: deactivationTime})
+}
+
+func TestDeactivate_KeepsBalance(t *testing.T) {
+	store := newEmployeeStore()
+
+	status, err := newEmployeeService(store).Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice"})
+	assert.NoError(t, err)
+	assert.False(t, status.Active)
+	assert.Equal(t, 300, status.Balance)
+
+	alice, _ := store.Employee("alice")
+	assert.False(t, alice.Active())
+	assert.True(t, deactivationTime.Equal(*alice.DeactivatedAt))
+	assert.Equal(t, 300, alice.Balance)
+}
+
+func TestDeactivate_ForfeitsBalance(t *testing.T) {
+	store := newEmployeeStore()
+
+	status, err := newEmployeeService(store).Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice", ForfeitBalance: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 300, status.Forfeited)
+	assert.Equal(t, 0, status.Balance)
+
+	alice, _ := store.Employee("alice")
+	assert.Equal(t, 0, alice.Balance)
+	assert.Empty(t, store.TransactionsList())
+}
+
+func TestDeactivate_TransfersBalance(t *testing.T) {
+	store := newEmployeeStore()
+
+	status, err := newEmployeeService(store).Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice", TransferTo: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", status.TransferredTo)
+	assert.Equal(t, 300, status.Transferred)
+
+	alice, _ := store.Employee("alice")
+	bob, _ := store.Employee("bob")
+	assert.Equal(t, 0, alice.Balance)
+	assert.Equal(t, 400, bob.Balance)
+
+	transactions := store.TransactionsList()
+	if assert.Len(t, transactions, 1) {
+		assert.Equal(t, alice.ID, transactions[0].SenderID)
+		assert.Equal(t, bob.ID, transactions[0].ReceiverID)
+		assert.Equal(t, 300, transactions[0].Amount)
+	}
+}
+
+func TestDeactivate_RejectsDeactivatedTarget(t *testing.T) {
+	store := newEmployeeStore()
+
+	_, err := newEmployeeService(store).Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice", TransferTo: "carol"})
+	assert.ErrorIs(t, err, service2.ErrTransferTargetDeactivated)
+
+	alice, _ := store.Employee("alice")
+	assert.True(t, alice.Active(), "деактивация откатывается вместе с переводом")
+	assert.Equal(t, 300, alice.Balance)
+}
+
+func TestDeactivate_Errors(t *testing.T) {
+	employeeService := newEmployeeService(newEmployeeStore())
+
+	_, err := employeeService.Deactivate(context.Background(), service2.DeactivateRequest{Username: "carol"})
+	assert.ErrorIs(t, err, service2.ErrEmployeeDeactivated)
+
+	_, err = employeeService.Deactivate(context.Background(), service2.DeactivateRequest{Username: "ghost"})
+	assert.ErrorIs(t, err, service2.ErrEmployeeNotFound)
+
+	_, err = employeeService.Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice", ForfeitBalance: true, TransferTo: "bob"})
+	assert.ErrorIs(t, err, service2.ErrInvalidBalanceAction)
+
+	_, err = employeeService.Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice", TransferTo: "alice"})
+	assert.ErrorIs(t, err, service2.ErrInvalidBalanceAction)
+}
+
+func TestReactivate(t *testing.T) {
+	store := newEmployeeStore()
+	employeeService := newEmployeeService(store)
+
+	status, err := employeeService.Reactivate(context.Background(), "carol")
+	assert.NoError(t, err)
+	assert.True(t, status.Active)
+
+	carol, _ := store.Employee("carol")
+	assert.True(t, carol.Active())
+
+	_, err = employeeService.Reactivate(context.Background(), "carol")
+	assert.ErrorIs(t, err, service2.ErrEmployeeAlreadyActive)
+}
+
+func TestSendCoins_ToDeactivatedEmployee(t *testing.T) {
+	store := newEmployeeStore()
+
+	_, err := service2.NewTransactionService(store).SendCoins(context.Background(), "alice", "carol", 10)
+	assert.EqualError(t, err, "пользователь carol деактивирован и не может получать монеты")
+
+	alice, _ := store.Employee("alice")
+	carol, _ := store.Employee("carol")
+	assert.Equal(t, 300, alice.Balance)
+	assert.Equal(t, 50, carol.Balance)
+}
+
+func TestAuthenticateUser_RejectsDeactivatedEmployee(t *testing.T) {
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.NoError(t, err)
+	_, err = newEmployeeService(store).Deactivate(context.Background(), service2.DeactivateRequest{Username: "alice"})
+	assert.NoError(t, err)
+
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.ErrorIs(t, err, service2.ErrEmployeeDeactivated)
+}
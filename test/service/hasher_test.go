@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"merch-api/config"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+)
+
+func testHasher() *service2.PasswordHasher {
+	return service2.NewPasswordHasher(config.PasswordHashConfig{Algorithm: config.HashBcrypt, BcryptCost: bcrypt.MinCost})
+}
+
+// testArgon2Config — минимальные параметры argon2id, чтобы тесты не тратили память и время
+func testArgon2Config() config.PasswordHashConfig {
+	return config.PasswordHashConfig{
+		Algorithm:  config.HashArgon2id,
+		BcryptCost: bcrypt.MinCost,
+		Argon2:     config.Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1},
+	}
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	hasher := service2.NewPasswordHasher(config.PasswordHashConfig{Algorithm: config.HashBcrypt, BcryptCost: bcrypt.MinCost + 1})
+
+	hash, err := hasher.Hash("password")
+	assert.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(hash))
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+
+	match, err := hasher.Verify(hash, "password")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = hasher.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+	weak, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.True(t, hasher.NeedsRehash(string(weak)))
+}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	hasher := service2.NewPasswordHasher(testArgon2Config())
+
+	hash, err := hasher.Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+
+	match, err := hasher.Verify(hash, "password")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = hasher.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, match)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.True(t, hasher.NeedsRehash(string(bcryptHash)), "после перехода на argon2id старые bcrypt-хеши пересчитываются")
+	match, err = hasher.Verify(string(bcryptHash), "password")
+	assert.NoError(t, err)
+	assert.True(t, match, "старые bcrypt-хеши продолжают проверяться")
+
+	stronger := testArgon2Config()
+	stronger.Argon2.Iterations = 2
+	assert.True(t, service2.NewPasswordHasher(stronger).NeedsRehash(hash))
+	assert.True(t, testHasher().NeedsRehash(hash), "при возврате на bcrypt argon2id-хеши тоже пересчитываются")
+}
+
+func TestPasswordHasher_CorruptedHash(t *testing.T) {
+	_, err := testHasher().Verify("not-a-hash", "password")
+	assert.Error(t, err)
+	_, err = testHasher().Verify("$argon2id$v=19$m=64,t=1,p=1$!!!$!!!", "password")
+	assert.Error(t, err)
+}
+
+func TestAuthenticateUser_UpgradesWeakHashOnLogin(t *testing.T) {
+	store := memory.NewStore()
+	weak, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	store.AddEmployee(model.Employee{Username: "alice", Password: string(weak), Balance: 1000})
+
+	hasher := service2.NewPasswordHasher(testArgon2Config())
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, hasher), nil)
+
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "wrong"})
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+	employee, _ := store.Employee("alice")
+	assert.Equal(t, string(weak), employee.Password, "неудачный вход хеш не трогает")
+
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.NoError(t, err)
+	employee, _ = store.Employee("alice")
+	assert.True(t, strings.HasPrefix(employee.Password, "$argon2id$"))
+	assert.Equal(t, 0, employee.TokenVersion, "пересчёт хеша не отзывает токены")
+
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "password"})
+	assert.NoError(t, err)
+}
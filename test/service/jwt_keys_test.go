@@ -0,0 +1,166 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"merch-api/config"
+	service2 "merch-api/service"
+	"testing"
+	"time"
+)
+
+var rotationStart = time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// testRotatingKeys: RSA-ключ rsa-1 действует с самого начала, Ed25519-ключ ed-2 сменяет его в rotationStart
+func testRotatingKeys(t *testing.T) (config.JWTConfig, *rsa.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return config.JWTConfig{
+		TTL: time.Hour,
+		SigningKeys: []config.SigningKey{
+			{ID: "rsa-1", Private: rsaKey},
+			{ID: "ed-2", ActiveFrom: rotationStart, Private: edKey},
+		},
+	}, rsaKey, edKey
+}
+
+func signAndParse(t *testing.T, keys *service2.JWTKeys, verifier *service2.JWTKeys) (*jwt.Token, error) {
+	t.Helper()
+	tokenString, err := keys.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+	return jwt.Parse(tokenString, verifier.Keyfunc, jwt.WithValidMethods(verifier.Algorithms()))
+}
+
+func TestJWTKeys_RotatesSigningKeyOnSchedule(t *testing.T) {
+	cfg, _, _ := testRotatingKeys(t)
+	clock := &movingClock{now: rotationStart.Add(-time.Minute)}
+	keys := service2.NewJWTKeys(cfg, clock)
+
+	before, err := signAndParse(t, keys, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "rsa-1", before.Header["kid"])
+	assert.Equal(t, "RS256", before.Method.Alg())
+
+	clock.now = rotationStart
+	after, err := signAndParse(t, keys, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "ed-2", after.Header["kid"])
+	assert.Equal(t, "EdDSA", after.Method.Alg())
+}
+
+func TestJWTKeys_RetiresReplacedKeyAfterTTL(t *testing.T) {
+	cfg, _, _ := testRotatingKeys(t)
+	signClock := &movingClock{now: rotationStart.Add(-time.Minute)}
+	verifyClock := &movingClock{now: rotationStart.Add(cfg.TTL - time.Second)}
+	signer := service2.NewJWTKeys(cfg, signClock)
+	verifier := service2.NewJWTKeys(cfg, verifyClock)
+
+	_, err := signAndParse(t, signer, verifier)
+	assert.NoError(t, err)
+	assert.Len(t, verifier.JWKS().Keys, 2)
+
+	verifyClock.now = rotationStart.Add(cfg.TTL)
+	_, err = signAndParse(t, signer, verifier)
+	assert.Error(t, err)
+	jwks := verifier.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "ed-2", jwks.Keys[0].KeyID)
+}
+
+func TestJWTKeys_JWKSPublishesUpcomingKeys(t *testing.T) {
+	cfg, rsaKey, edKey := testRotatingKeys(t)
+	jwks := service2.NewJWTKeys(cfg, &movingClock{now: rotationStart.Add(-24 * time.Hour)}).JWKS()
+
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, service2.JWK{
+		KeyType:   "RSA",
+		KeyID:     "rsa-1",
+		Use:       "sig",
+		Algorithm: "RS256",
+		N:         base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}, jwks.Keys[0])
+	assert.Equal(t, service2.JWK{
+		KeyType:   "OKP",
+		KeyID:     "ed-2",
+		Use:       "sig",
+		Algorithm: "EdDSA",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)),
+	}, jwks.Keys[1])
+}
+
+func TestJWTKeys_RejectsForgedTokens(t *testing.T) {
+	cfg, _, _ := testRotatingKeys(t)
+	keys := service2.NewJWTKeys(cfg, &movingClock{now: rotationStart})
+	parse := func(token *jwt.Token, key interface{}) error {
+		tokenString, err := token.SignedString(key)
+		require.NoError(t, err)
+		_, err = jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+		return err
+	}
+
+	// Чужой ключ с известным kid
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"username": "alice"})
+	forged.Header["kid"] = "ed-2"
+	assert.Error(t, parse(forged, otherKey))
+
+	// Неизвестный kid
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"username": "alice"})
+	unknown.Header["kid"] = "ed-9"
+	assert.Error(t, parse(unknown, otherKey))
+
+	// HS256 с открытым ключом вместо секрета
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "alice"})
+	confused.Header["kid"] = "ed-2"
+	assert.Error(t, parse(confused, []byte(cfg.SigningKeys[1].Private.Public().(ed25519.PublicKey))))
+
+	// Без секрета токены HS256 без kid не принимаются
+	assert.Error(t, parse(jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "alice"}), []byte{}))
+}
+
+func TestJWTKeys_SecretKeepsVerifyingAfterMigration(t *testing.T) {
+	cfg, _, _ := testRotatingKeys(t)
+	cfg.Secret = testJWTConfig().Secret
+	keys := service2.NewJWTKeys(cfg, &movingClock{now: rotationStart})
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "alice"}).SignedString(cfg.Secret)
+	require.NoError(t, err)
+	_, err = jwt.Parse(legacy, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"HS256", "RS256", "EdDSA"}, keys.Algorithms())
+
+	token, err := signAndParse(t, keys, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "ed-2", token.Header["kid"])
+}
+
+func TestJWTKeys_SecretRejectedAfterAcceptUntil(t *testing.T) {
+	cfg, _, _ := testRotatingKeys(t)
+	cfg.Secret = testJWTConfig().Secret
+	cfg.SecretAcceptUntil = rotationStart.Add(cfg.TTL)
+	clock := &movingClock{now: cfg.SecretAcceptUntil.Add(-time.Second)}
+	keys := service2.NewJWTKeys(cfg, clock)
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "alice"}).SignedString(cfg.Secret)
+	require.NoError(t, err)
+	_, err = jwt.Parse(legacy, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+	assert.NoError(t, err)
+
+	clock.now = cfg.SecretAcceptUntil
+	_, err = jwt.Parse(legacy, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+	assert.Error(t, err)
+	assert.NotContains(t, keys.Algorithms(), "HS256")
+}
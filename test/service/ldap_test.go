@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"merch-api/config"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	ldapServiceDN       = "cn=merch,ou=services,dc=example,dc=com"
+	ldapServicePassword = "service-secret"
+)
+
+type fakeLDAPEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeLDAP — минимальный LDAP-сервер: понимает bind, поиск с фильтрами из равенств и unbind
+type fakeLDAP struct {
+	listener net.Listener
+	entries  []fakeLDAPEntry
+
+	mu    sync.Mutex
+	binds []string
+}
+
+func newFakeLDAP(t *testing.T, entries ...fakeLDAPEntry) *fakeLDAP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &fakeLDAP{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeLDAP) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAP) boundDNs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.binds)
+}
+
+func (s *fakeLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		messageID := packet.Children[0].Value
+		op := packet.Children[1]
+		switch op.Tag {
+		case ber.Tag(0): // BindRequest
+			conn.Write(ldapResponse(messageID, 1, s.bind(op.Children[1].Data.String(), op.Children[2].Data.String())).Bytes())
+		case ber.Tag(3): // SearchRequest
+			for _, entry := range s.search(op.Children[6]) {
+				conn.Write(ldapEntry(messageID, entry).Bytes())
+			}
+			conn.Write(ldapResponse(messageID, 5, 0).Bytes())
+		default: // UnbindRequest и всё, что фейк не умеет
+			return
+		}
+	}
+}
+
+func (s *fakeLDAP) bind(dn, password string) int64 {
+	s.mu.Lock()
+	s.binds = append(s.binds, dn)
+	s.mu.Unlock()
+
+	if dn == ldapServiceDN && password == ldapServicePassword {
+		return 0
+	}
+	for _, entry := range s.entries {
+		if entry.dn == dn && entry.password == password {
+			return 0
+		}
+	}
+	return 49 // invalidCredentials
+}
+
+func (s *fakeLDAP) search(filter *ber.Packet) []fakeLDAPEntry {
+	var found []fakeLDAPEntry
+	for _, entry := range s.entries {
+		if matchesFilter(entry, filter) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// matchesFilter понимает только and (тег 0) и equalityMatch (тег 3)
+func matchesFilter(entry fakeLDAPEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ber.Tag(0):
+		for _, child := range filter.Children {
+			if !matchesFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ber.Tag(3):
+		return slices.Contains(entry.attrs[filter.Children[0].Data.String()], filter.Children[1].Data.String())
+	default:
+		return false
+	}
+}
+
+func ldapMessage(messageID interface{}, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func ldapResponse(messageID interface{}, tag ber.Tag, resultCode int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, resultCode, "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return ldapMessage(messageID, op)
+}
+
+func ldapEntry(messageID interface{}, entry fakeLDAPEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "SearchResultEntry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attributes := ber.NewSequence("attributes")
+	for name, values := range entry.attrs {
+		attribute := ber.NewSequence("attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return ldapMessage(messageID, op)
+}
+
+func testLDAPDirectory(t *testing.T) *fakeLDAP {
+	return newFakeLDAP(t,
+		fakeLDAPEntry{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice-secret",
+			attrs: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"alice"},
+				"cn":          {"Алиса Иванова"},
+				"mail":        {"alice@example.com"},
+			},
+		},
+		fakeLDAPEntry{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob-secret",
+			attrs: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+			},
+		},
+	)
+}
+
+func testLDAPConfig(url string) config.LDAPConfig {
+	return config.LDAPConfig{
+		URL:                  url,
+		BindDN:               ldapServiceDN,
+		BindPassword:         ldapServicePassword,
+		BaseDN:               "ou=people,dc=example,dc=com",
+		UserFilter:           "(&(objectClass=person)(uid=%s))",
+		UsernameAttribute:    "uid",
+		DisplayNameAttribute: "cn",
+		EmailAttribute:       "mail",
+		Timeout:              2 * time.Second,
+	}
+}
+
+func TestLDAPAuthenticator_BindsAsFoundEntry(t *testing.T) {
+	directory := testLDAPDirectory(t)
+	authenticator := service2.NewLDAPAuthenticator(testLDAPConfig(directory.url()))
+
+	identity, err := authenticator.Authenticate(context.Background(), "alice", "alice-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Username)
+	assert.Equal(t, "Алиса Иванова", identity.Profile.DisplayName)
+	assert.Equal(t, "alice@example.com", identity.Profile.Email)
+	assert.Equal(t, []string{ldapServiceDN, "uid=alice,ou=people,dc=example,dc=com"}, directory.boundDNs())
+}
+
+func TestLDAPAuthenticator_RejectsBadCredentials(t *testing.T) {
+	directory := testLDAPDirectory(t)
+	authenticator := service2.NewLDAPAuthenticator(testLDAPConfig(directory.url()))
+
+	for name, creds := range map[string][2]string{
+		"wrong password":   {"alice", "bob-secret"},
+		"unknown user":     {"mallory", "alice-secret"},
+		"empty password":   {"alice", ""},
+		"filter wildcard":  {"*", "alice-secret"},
+		"filter injection": {"alice)(uid=*", "alice-secret"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := authenticator.Authenticate(context.Background(), creds[0], creds[1])
+			assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+		})
+	}
+}
+
+func TestLDAPAuthenticator_ServiceBindFailureIsNotPasswordMismatch(t *testing.T) {
+	directory := testLDAPDirectory(t)
+	cfg := testLDAPConfig(directory.url())
+	cfg.BindPassword = "rotated"
+	authenticator := service2.NewLDAPAuthenticator(cfg)
+
+	_, err := authenticator.Authenticate(context.Background(), "alice", "alice-secret")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, service2.ErrPasswordMismatch)
+}
+
+func TestAuthenticateUser_LDAPProvisionsEmployeeWithProfile(t *testing.T) {
+	directory := testLDAPDirectory(t)
+	store := memory.NewStore()
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLDAPAuthenticator(testLDAPConfig(directory.url())), nil)
+
+	token, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "alice-secret"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	employee, ok := store.Employee("alice")
+	require.True(t, ok)
+	assert.Equal(t, 1000, employee.Balance)
+	assert.Empty(t, employee.Password)
+	assert.Equal(t, "Алиса Иванова", employee.Profile.DisplayName)
+
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "wrong"})
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+	_, ok = store.Employee("mallory")
+	assert.False(t, ok)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"merch-api/config"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// movingClock позволяет двигать время между попытками входа
+type movingClock struct {
+	now time.Time
+}
+
+func (c *movingClock) Now() time.Time {
+	return c.now
+}
+
+func testThrottleConfig() config.LoginThrottleConfig {
+	return config.LoginThrottleConfig{
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   5,
+		LockoutBase:        time.Minute,
+		LockoutMax:         5 * time.Minute,
+		FailureWindow:      time.Hour,
+	}
+}
+
+func newThrottledAuth(t *testing.T) (*service2.AuthServiceImpl, *service2.LoginThrottle, *movingClock) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("ошибка при хешировании пароля: %v", err)
+	}
+	store := memory.NewStore()
+	for _, username := range []string{"alice", "bob", "carol"} {
+		store.AddEmployee(model.Employee{Username: username, Password: string(hash), Balance: 1000})
+	}
+
+	clock := &movingClock{now: time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)}
+	throttle := service2.NewLoginThrottle(store, testThrottleConfig(), clock)
+	return service2.NewAuthService(store, service2.NewJWTKeys(testJWTConfig(), clock), service2.NewLocalAuthenticator(store, testHasher()), throttle), throttle, clock
+}
+
+func login(authService *service2.AuthServiceImpl, username, password, ip string) error {
+	_, err := authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: username, Password: password, ClientIP: ip})
+	return err
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var throttled *service2.TooManyAttemptsError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("ожидалась ошибка TooManyAttemptsError, получено %v", err)
+	}
+	return throttled.RetryAfter
+}
+
+func TestLoginThrottle_LocksUserWithExponentialBackoff(t *testing.T) {
+	authService, _, clock := newThrottledAuth(t)
+
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, login(authService, "alice", "wrong", "10.0.0.1"), service2.ErrPasswordMismatch)
+	}
+	assert.ErrorIs(t, login(authService, "alice", "wrong", "10.0.0.2"), service2.ErrPasswordMismatch)
+
+	err := login(authService, "alice", "password", "10.0.0.3")
+	assert.ErrorIs(t, err, service2.ErrTooManyAttempts, "блокировка по логину действует с любого адреса, даже с верным паролем")
+	assert.Equal(t, time.Minute, retryAfter(t, err))
+
+	clock.now = clock.now.Add(time.Minute)
+	assert.ErrorIs(t, login(authService, "alice", "wrong", "10.0.0.3"), service2.ErrPasswordMismatch)
+	assert.Equal(t, 2*time.Minute, retryAfter(t, login(authService, "alice", "password", "10.0.0.3")))
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	assert.NoError(t, login(authService, "alice", "password", "10.0.0.3"))
+
+	assert.ErrorIs(t, login(authService, "alice", "wrong", "10.0.0.3"), service2.ErrPasswordMismatch, "успешный вход сбрасывает счётчик")
+}
+
+func TestLoginThrottle_LockoutIsCapped(t *testing.T) {
+	authService, _, clock := newThrottledAuth(t)
+
+	for i := 0; i < 10; i++ {
+		_ = login(authService, "alice", "wrong", "")
+		clock.now = clock.now.Add(10 * time.Minute)
+	}
+	_ = login(authService, "alice", "wrong", "")
+	assert.Equal(t, 5*time.Minute, retryAfter(t, login(authService, "alice", "password", "")))
+}
+
+func TestLoginThrottle_LocksIPAcrossUsers(t *testing.T) {
+	authService, _, _ := newThrottledAuth(t)
+
+	for _, username := range []string{"alice", "alice", "bob", "bob", "carol"} {
+		assert.ErrorIs(t, login(authService, username, "wrong", "10.0.0.1"), service2.ErrPasswordMismatch)
+	}
+
+	assert.ErrorIs(t, login(authService, "carol", "password", "10.0.0.1"), service2.ErrTooManyAttempts)
+	assert.NoError(t, login(authService, "carol", "password", "10.0.0.2"))
+}
+
+func TestLoginThrottle_FailuresOutsideWindowAreForgotten(t *testing.T) {
+	authService, _, clock := newThrottledAuth(t)
+
+	assert.ErrorIs(t, login(authService, "alice", "wrong", ""), service2.ErrPasswordMismatch)
+	assert.ErrorIs(t, login(authService, "alice", "wrong", ""), service2.ErrPasswordMismatch)
+	clock.now = clock.now.Add(2 * time.Hour)
+
+	assert.ErrorIs(t, login(authService, "alice", "wrong", ""), service2.ErrPasswordMismatch)
+	assert.NoError(t, login(authService, "alice", "password", ""))
+}
+
+func TestLoginThrottle_AdminUnlock(t *testing.T) {
+	authService, throttle, _ := newThrottledAuth(t)
+
+	for _, username := range []string{"alice", "alice", "alice", "carol", "carol"} {
+		assert.ErrorIs(t, login(authService, username, "wrong", "10.0.0.1"), service2.ErrPasswordMismatch)
+	}
+	assert.ErrorIs(t, login(authService, "bob", "password", "10.0.0.1"), service2.ErrTooManyAttempts)
+
+	assert.NoError(t, throttle.UnlockIP(context.Background(), "10.0.0.1"))
+	assert.NoError(t, login(authService, "bob", "password", "10.0.0.1"))
+	assert.ErrorIs(t, login(authService, "alice", "password", "10.0.0.1"), service2.ErrTooManyAttempts)
+
+	assert.NoError(t, throttle.UnlockUser(context.Background(), "alice"))
+	assert.NoError(t, login(authService, "alice", "password", "10.0.0.1"))
+}
+
+func TestLoginThrottle_AuditsLockoutOnce(t *testing.T) {
+	store := memory.NewStore()
+	clock := &movingClock{now: time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)}
+	throttle := service2.NewLoginThrottle(store, testThrottleConfig(), clock)
+	authService := service2.NewAuthService(store, testJWTKeys(), stubAuthenticator{err: service2.ErrPasswordMismatch}, throttle)
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, login(authService, "alice", "wrong", ""), service2.ErrPasswordMismatch)
+	}
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, login(authService, "alice", "wrong", ""), service2.ErrTooManyAttempts)
+	}
+
+	actions := map[string]int{}
+	var locked model.AuditEvent
+	for _, event := range store.AuditLogList() {
+		actions[event.Action]++
+		if event.Action == service2.AuditLoginLocked {
+			locked = event
+		}
+	}
+	assert.Equal(t, map[string]int{service2.AuditLoginFailed: 3, service2.AuditLoginLocked: 1}, actions)
+	assert.Equal(t, "alice", locked.Actor)
+	assert.Equal(t, "user:alice", locked.Target)
+	assert.Equal(t, "вход заблокирован на 1m0s после 3 неудачных попыток", locked.Details)
+}
+
+// countingAuthenticator считает проверки пароля и держит каждую, чтобы параллельные входы успели начаться
+type countingAuthenticator struct {
+	checks atomic.Int32
+}
+
+func (a *countingAuthenticator) Authenticate(context.Context, string, string) (*service2.Identity, error) {
+	a.checks.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	return nil, service2.ErrPasswordMismatch
+}
+
+func TestLoginThrottle_ParallelAttemptsDoNotExceedLimit(t *testing.T) {
+	store := memory.NewStore()
+	throttle := service2.NewLoginThrottle(store, testThrottleConfig(), service2.SystemClock{})
+	authenticator := &countingAuthenticator{}
+	authService := service2.NewAuthService(store, testJWTKeys(), authenticator, throttle)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = login(authService, "alice", "wrong", "10.0.0.1")
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, int(authenticator.checks.Load()), testThrottleConfig().MaxFailuresPerUser)
+	assert.ErrorIs(t, login(authService, "alice", "wrong", "10.0.0.1"), service2.ErrTooManyAttempts)
+}
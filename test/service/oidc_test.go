@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"merch-api/config"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	oidcClientID     = "merch-api"
+	oidcClientSecret = "client-secret"
+	oidcRedirectURL  = "https://merch.example.com/api/auth/oidc/callback"
+)
+
+type fakeOIDCGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+// fakeOIDC — провайдер с discovery, JWKS и token endpoint; коды выдаются через authorize
+type fakeOIDC struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]fakeOIDCGrant
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider := &fakeOIDC{key: key, grants: make(map[string]fakeOIDCGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                provider.server.URL,
+			"authorization_endpoint":                provider.server.URL + "/authorize",
+			"token_endpoint":                        provider.server.URL + "/token",
+			"jwks_uri":                              provider.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// authorize имитирует успешный вход пользователя у провайдера и возвращает выданный код
+func (p *fakeOIDC) authorize(t *testing.T, loginURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	u, err := url.Parse(loginURL)
+	require.NoError(t, err)
+	query := u.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.Equal(t, oidcClientID, query.Get("client_id"))
+	require.Equal(t, oidcRedirectURL, query.Get("redirect_uri"))
+
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.grants[code] = fakeOIDCGrant{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *fakeOIDC) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, _ := r.BasicAuth()
+	if clientID != oidcClientID || clientSecret != oidcClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostFormValue("code")]
+	delete(p.grants, r.PostFormValue("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.server.URL,
+		"aud": oidcClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func testOIDCConfig(issuer string) config.OIDCConfig {
+	return config.OIDCConfig{
+		Issuer:        issuer,
+		ClientID:      oidcClientID,
+		ClientSecret:  oidcClientSecret,
+		RedirectURL:   oidcRedirectURL,
+		Scopes:        []string{"openid", "profile", "email"},
+		UsernameClaim: "preferred_username",
+	}
+}
+
+func newTestOIDCService(issuer string, store *memory.Store) *service2.OIDCService {
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+	return service2.NewOIDCService(testOIDCConfig(issuer), authService, service2.SystemClock{})
+}
+
+func TestOIDCService_ProvisionsEmployeeOnFirstLogin(t *testing.T) {
+	provider := newFakeOIDC(t)
+	store := memory.NewStore()
+	oidcService := newTestOIDCService(provider.server.URL, store)
+
+	login, err := oidcService.BeginLogin(context.Background())
+	require.NoError(t, err)
+	code := provider.authorize(t, login.URL, jwt.MapClaims{
+		"sub":                "1f0c",
+		"preferred_username": "alice",
+		"name":               "Алиса Иванова",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+	})
+
+	token, err := oidcService.CompleteLogin(context.Background(), code, login.Verifier, login.Nonce)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	employee, ok := store.Employee("alice")
+	require.True(t, ok)
+	assert.Equal(t, 1000, employee.Balance)
+	assert.Empty(t, employee.Password)
+	assert.Equal(t, "Алиса Иванова", employee.Profile.DisplayName)
+	assert.Equal(t, "alice@example.com", employee.Profile.Email)
+}
+
+func TestOIDCService_IgnoresUnverifiedEmail(t *testing.T) {
+	provider := newFakeOIDC(t)
+	store := memory.NewStore()
+	oidcService := newTestOIDCService(provider.server.URL, store)
+
+	login, err := oidcService.BeginLogin(context.Background())
+	require.NoError(t, err)
+	code := provider.authorize(t, login.URL, jwt.MapClaims{"sub": "2a9d", "preferred_username": "bob", "email": "ceo@example.com"})
+
+	_, err = oidcService.CompleteLogin(context.Background(), code, login.Verifier, login.Nonce)
+	require.NoError(t, err)
+	employee, ok := store.Employee("bob")
+	require.True(t, ok)
+	assert.Empty(t, employee.Profile.Email)
+}
+
+func TestOIDCService_RejectsInvalidLogins(t *testing.T) {
+	provider := newFakeOIDC(t)
+
+	tests := map[string]struct {
+		claims   jwt.MapClaims
+		verifier func(login *service2.OIDCLogin) string
+		nonce    func(login *service2.OIDCLogin) string
+	}{
+		"wrong nonce": {
+			claims: jwt.MapClaims{"sub": "1", "preferred_username": "alice"},
+			nonce:  func(*service2.OIDCLogin) string { return "other" },
+		},
+		"replayed id token": {
+			claims: jwt.MapClaims{"sub": "1", "preferred_username": "alice", "nonce": "stolen"},
+		},
+		"wrong pkce verifier": {
+			claims:   jwt.MapClaims{"sub": "1", "preferred_username": "alice"},
+			verifier: func(*service2.OIDCLogin) string { return "intercepted-code-without-verifier-0123456789" },
+		},
+		"wrong audience": {
+			claims: jwt.MapClaims{"sub": "1", "preferred_username": "alice", "aud": "other-app"},
+		},
+		"no username claim": {
+			claims: jwt.MapClaims{"sub": "1"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := memory.NewStore()
+			oidcService := newTestOIDCService(provider.server.URL, store)
+			login, err := oidcService.BeginLogin(context.Background())
+			require.NoError(t, err)
+			code := provider.authorize(t, login.URL, tt.claims)
+
+			verifier, nonce := login.Verifier, login.Nonce
+			if tt.verifier != nil {
+				verifier = tt.verifier(login)
+			}
+			if tt.nonce != nil {
+				nonce = tt.nonce(login)
+			}
+			_, err = oidcService.CompleteLogin(context.Background(), code, verifier, nonce)
+			assert.ErrorIs(t, err, service2.ErrInvalidOIDCLogin)
+			_, ok := store.Employee("alice")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestOIDCService_UnavailableProvider(t *testing.T) {
+	provider := newFakeOIDC(t)
+	issuer := provider.server.URL
+	provider.server.Close()
+
+	_, err := newTestOIDCService(issuer, memory.NewStore()).BeginLogin(context.Background())
+	assert.ErrorIs(t, err, service2.ErrOIDCUnavailable)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"testing"
+	"time"
+)
+
+const oldPassword = "old-password"
+
+func newPasswordStore(t *testing.T) *memory.Store {
+	hash, err := bcrypt.GenerateFromPassword([]byte(oldPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("ошибка при хешировании пароля: %v", err)
+	}
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "alice", Password: string(hash), Balance: 1000})
+	deactivatedAt := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	store.AddEmployee(model.Employee{Username: "gone", Password: string(hash), DeactivatedAt: &deactivatedAt})
+	return store
+}
+
+func newPasswordService(store *memory.Store, clock service2.Clock) *service2.PasswordServiceImpl {
+	authService := service2.NewAuthService(store, service2.NewJWTKeys(testJWTConfig(), clock), service2.NewLocalAuthenticator(store, testHasher()), nil)
+	return service2.NewPasswordService(store, authService, testHasher(), clock, time.Hour)
+}
+
+func tokenVersion(t *testing.T, tokenString string) int {
+	t.Helper()
+	claims := &service2.Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return testJWTConfig().Secret, nil
+	})
+	assert.NoError(t, err)
+	return claims.TokenVersion
+}
+
+func TestChangePassword(t *testing.T) {
+	store := newPasswordStore(t)
+	passwordService := newPasswordService(store, service2.SystemClock{})
+
+	token, err := passwordService.ChangePassword(context.Background(), "alice", oldPassword, "new-password")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tokenVersion(t, token), "новый токен выпускается с новой версией")
+
+	employee, _ := store.Employee("alice")
+	assert.Equal(t, 1, employee.TokenVersion)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte("new-password")))
+
+	authService := service2.NewAuthService(store, testJWTKeys(), service2.NewLocalAuthenticator(store, testHasher()), nil)
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: oldPassword})
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+	_, err = authService.AuthenticateUser(context.Background(), service2.LoginRequest{Username: "alice", Password: "new-password"})
+	assert.NoError(t, err)
+}
+
+func TestChangePassword_WrongOldPassword(t *testing.T) {
+	store := newPasswordStore(t)
+	passwordService := newPasswordService(store, service2.SystemClock{})
+
+	_, err := passwordService.ChangePassword(context.Background(), "alice", "wrong-password", "new-password")
+	assert.ErrorIs(t, err, service2.ErrPasswordMismatch)
+
+	employee, _ := store.Employee("alice")
+	assert.Equal(t, 0, employee.TokenVersion)
+}
+
+func TestChangePassword_InvalidNewPassword(t *testing.T) {
+	passwordService := newPasswordService(newPasswordStore(t), service2.SystemClock{})
+
+	_, err := passwordService.ChangePassword(context.Background(), "alice", oldPassword, "short")
+	assert.ErrorIs(t, err, service2.ErrInvalidPassword)
+
+	_, err = passwordService.ChangePassword(context.Background(), "alice", oldPassword, string(make([]byte, service2.MaxPasswordLength+1)))
+	assert.ErrorIs(t, err, service2.ErrInvalidPassword)
+}
+
+func TestResetPassword_TokenIsOneTime(t *testing.T) {
+	store := newPasswordStore(t)
+	passwordService := newPasswordService(store, service2.SystemClock{})
+
+	reset, err := passwordService.IssueReset(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, reset.Token)
+	for _, stored := range store.PasswordResetsList() {
+		assert.NotEqual(t, reset.Token, stored.TokenHash, "в БД хранится только хеш токена")
+	}
+
+	assert.NoError(t, passwordService.ResetPassword(context.Background(), reset.Token, "reset-password"))
+	employee, _ := store.Employee("alice")
+	assert.Equal(t, 1, employee.TokenVersion)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte("reset-password")))
+
+	err = passwordService.ResetPassword(context.Background(), reset.Token, "another-password")
+	assert.ErrorIs(t, err, service2.ErrInvalidResetToken)
+}
+
+func TestResetPassword_NewTokenRevokesPrevious(t *testing.T) {
+	passwordService := newPasswordService(newPasswordStore(t), service2.SystemClock{})
+
+	first, err := passwordService.IssueReset(context.Background(), "alice")
+	assert.NoError(t, err)
+	second, err := passwordService.IssueReset(context.Background(), "alice")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, passwordService.ResetPassword(context.Background(), first.Token, "reset-password"), service2.ErrInvalidResetToken)
+	assert.NoError(t, passwordService.ResetPassword(context.Background(), second.Token, "reset-password"))
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+	store := newPasswordStore(t)
+	issuedAt := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	reset, err := newPasswordService(store, fixedClock{now: issuedAt}).IssueReset(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.True(t, issuedAt.Add(time.Hour).Equal(reset.ExpiresAt))
+
+	err = newPasswordService(store, fixedClock{now: issuedAt.Add(time.Hour)}).ResetPassword(context.Background(), reset.Token, "reset-password")
+	assert.ErrorIs(t, err, service2.ErrInvalidResetToken)
+}
+
+func TestResetPassword_UnknownToken(t *testing.T) {
+	passwordService := newPasswordService(newPasswordStore(t), service2.SystemClock{})
+
+	err := passwordService.ResetPassword(context.Background(), "unknown", "reset-password")
+	assert.ErrorIs(t, err, service2.ErrInvalidResetToken)
+}
+
+func TestIssueReset_Errors(t *testing.T) {
+	passwordService := newPasswordService(newPasswordStore(t), service2.SystemClock{})
+
+	_, err := passwordService.IssueReset(context.Background(), "ghost")
+	assert.ErrorIs(t, err, service2.ErrEmployeeNotFound)
+
+	_, err = passwordService.IssueReset(context.Background(), "gone")
+	assert.ErrorIs(t, err, service2.ErrEmployeeDeactivated)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"merch-api/model"
+	service2 "merch-api/service"
+	"testing"
+	"time"
+)
+
+func TestScopesForRole(t *testing.T) {
+	employee := []string{service2.ScopeInfoRead, service2.ScopePurchaseWrite, service2.ScopeTransferWrite}
+	assert.Equal(t, employee, service2.ScopesForRole(model.RoleEmployee))
+	assert.Equal(t, append(employee, service2.ScopeAdminAudit, service2.ScopeAdminBalances, service2.ScopeAdminCatalog, service2.ScopeAdminEmployees),
+		service2.ScopesForRole(model.RoleAdmin))
+	assert.Empty(t, service2.ScopesForRole("guest"))
+
+	// Изменение результата не портит права роли
+	service2.ScopesForRole(model.RoleEmployee)[0] = service2.ScopeAdminCatalog
+	assert.Equal(t, employee, service2.ScopesForRole(model.RoleEmployee))
+}
+
+func TestEffectiveScopes_LimitedByRole(t *testing.T) {
+	token := []string{service2.ScopeInfoRead, service2.ScopeAdminBalances, "unknown:scope"}
+
+	assert.Equal(t, []string{service2.ScopeInfoRead, service2.ScopeAdminBalances}, service2.EffectiveScopes(model.RoleAdmin, token))
+	assert.Equal(t, []string{service2.ScopeInfoRead}, service2.EffectiveScopes(model.RoleEmployee, token))
+	assert.Empty(t, service2.EffectiveScopes(model.RoleEmployee, nil))
+}
+
+func TestClaimsScopeList(t *testing.T) {
+	claims := service2.Claims{Scope: "info:read transfer:write"}
+	assert.Equal(t, []string{service2.ScopeInfoRead, service2.ScopeTransferWrite}, claims.ScopeList(model.RoleAdmin))
+
+	legacy := service2.Claims{}
+	assert.Equal(t, service2.ScopesForRole(model.RoleEmployee), legacy.ScopeList(model.RoleEmployee))
+}
+
+func TestIssueToken_CarriesRoleScopes(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testJWTConfig()
+	authService := service2.NewAuthService(nil, service2.NewJWTKeys(cfg, fixedClock{now: now}), nil, nil)
+
+	tokenString, err := authService.IssueToken(&model.Employee{Username: "root", Role: model.RoleAdmin})
+	assert.NoError(t, err)
+
+	claims, err := service2.NewJWTKeys(cfg, fixedClock{now: now}).ParseToken(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "info:read purchase:write transfer:write admin:audit admin:balances admin:catalog admin:employees", claims.Scope)
+}
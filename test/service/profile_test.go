@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"merch-api/model"
+	"merch-api/repository/memory"
+	service2 "merch-api/service"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func newProfileStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "alice", Balance: 100, Profile: model.EmployeeProfile{
+		DisplayName: "Алиса",
+		Department:  "Разработка",
+		Team:        "Платформа",
+	}})
+	return store
+}
+
+func TestGetProfile(t *testing.T) {
+	profile, err := service2.NewProfileService(newProfileStore()).GetProfile(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, &service2.Profile{
+		Username:    "alice",
+		DisplayName: "Алиса",
+		Department:  "Разработка",
+		Team:        "Платформа",
+	}, profile)
+
+	_, err = service2.NewProfileService(newProfileStore()).GetProfile(context.Background(), "ghost")
+	assert.ErrorIs(t, err, service2.ErrEmployeeNotFound)
+}
+
+func TestUpdateProfile_PartialUpdate(t *testing.T) {
+	store := newProfileStore()
+
+	profile, err := service2.NewProfileService(store).UpdateProfile(context.Background(), "alice", service2.ProfileUpdate{
+		Email:     strPtr(" alice@example.com "),
+		Team:      strPtr(""),
+		AvatarURL: strPtr("https://cdn.example.com/alice.png"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Алиса", profile.DisplayName, "не переданное поле не меняется")
+	assert.Equal(t, "alice@example.com", profile.Email)
+	assert.Equal(t, "", profile.Team, "пустая строка очищает поле")
+
+	alice, _ := store.Employee("alice")
+	assert.Equal(t, "alice@example.com", alice.Profile.Email)
+	assert.Equal(t, "Разработка", alice.Profile.Department)
+	assert.Equal(t, "", alice.Profile.Team)
+}
+
+func TestUpdateProfile_Validation(t *testing.T) {
+	profileService := service2.NewProfileService(newProfileStore())
+
+	for name, update := range map[string]service2.ProfileUpdate{
+		"email":       {Email: strPtr("not-an-email")},
+		"named email": {Email: strPtr("Alice <alice@example.com>")},
+		"avatar":      {AvatarURL: strPtr("javascript:alert(1)")},
+		"long name":   {DisplayName: strPtr(strings.Repeat("я", service2.MaxDisplayNameLength+1))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := profileService.UpdateProfile(context.Background(), "alice", update)
+			assert.ErrorIs(t, err, service2.ErrInvalidProfile)
+		})
+	}
+}
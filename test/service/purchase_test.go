@@ -1,285 +1,204 @@
 package service
 
 import (
-	"fmt"
-	"github.com/DATA-DOG/go-sqlmock"
+	"context"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 	service2 "merch-api/service"
 	"testing"
 )
 
 func TestPurchaseMerch(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+	store := newShopStore()
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
-
-	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "userName", 200))
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs("userName", "", 100, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectQuery("INSERT INTO \"purchase\" (.+) VALUES (.+)").
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
-
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
+	purchaseService := service2.NewPurchaseService(store)
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
 	assert.NoError(t, err)
 	assert.Equal(t, "Покупка успешна", result)
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Fatalf("не все ожидания выполнены: %v", err)
-	}
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 100, employee.Balance)
+	purchases := store.PurchasesList()
+	assert.Len(t, purchases, 1)
+	assert.Equal(t, employee.ID, purchases[0].EmployeeID)
+	assert.Equal(t, 1, purchases[0].Quantity)
 }
 
 func TestPurchaseMerch_ErrorCreatingPurchase(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
+	store := newShopStore()
 
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+	purchaseService := service2.NewPurchaseService(&failingStore{Store: store, failPurchaseCreate: true})
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "не удалось сохранить покупку")
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
-
-	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "userName", 200))
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs("userName", "", 100, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectQuery("INSERT INTO \"purchase\" (.+) VALUES (.+)").
-		WithArgs(1, 1).
-		WillReturnError(fmt.Errorf("не удалось сохранить покупку"))
-
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
-
-	assert.Error(t, err)
-	assert.Equal(t, "не удалось сохранить покупку", err.Error())
-	assert.Empty(t, result)
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 200, employee.Balance, "списание откатывается вместе с транзакцией")
+	assert.Empty(t, store.PurchasesList())
 }
 
 func TestPurchaseMerch_ErrorUpdatingEmployeeBalance(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
+	store := newShopStore()
+	employee, _ := store.Employee("userName")
 
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+	purchaseService := service2.NewPurchaseService(&failingStore{Store: store, failBalanceUpdateFor: employee.ID})
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "не удалось обновить баланс сотрудника")
+	assert.Empty(t, store.PurchasesList())
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
+func TestPurchaseMerch_ErrorCommittingTransaction(t *testing.T) {
+	store := newShopStore()
 
-	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "userName", 200))
+	purchaseService := service2.NewPurchaseService(&failingStore{Store: store, failCommit: true})
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.Equal(t, "", result)
+	assert.ErrorIs(t, err, errStorage)
 
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs("userName", "", 100, 1).
-		WillReturnError(fmt.Errorf("не удалось обновить баланс сотрудника"))
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 200, employee.Balance)
+	assert.Empty(t, store.PurchasesList())
+}
 
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
+func TestPurchaseMerch_MerchNotFound(t *testing.T) {
+	store := newShopStore()
 
-	assert.Error(t, err)
-	assert.Equal(t, "не удалось обновить баланс сотрудника", err.Error())
-	assert.Empty(t, result)
+	purchaseService := service2.NewPurchaseService(store)
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName2")
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "товар itemName2 не найден")
 }
 
-func TestPurchaseMerch_ErrorBeginningTransaction(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
+func TestPurchaseMerch_UserNotFound(t *testing.T) {
+	store := newShopStore()
 
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+	purchaseService := service2.NewPurchaseService(store)
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName2", "itemName")
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "пользователь userName2 не найден")
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
+func TestPurchaseMerch_NotEnoughCoins(t *testing.T) {
+	store := newShopStore()
 
-	mock.ExpectBegin().WillReturnError(fmt.Errorf("ошибка при начале транзакции"))
+	purchaseService := service2.NewPurchaseService(store)
+	_, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.NoError(t, err)
+	_, err = purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.NoError(t, err)
 
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
+	result, err := purchaseService.PurchaseMerch(context.Background(), "userName", "itemName")
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "недостаточно монет для покупки товара itemName")
 
-	assert.Error(t, err)
-	assert.Equal(t, "ошибка при начале транзакции", err.Error())
-	assert.Empty(t, result)
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 0, employee.Balance)
+	assert.Len(t, store.PurchasesList(), 2)
 }
 
-func TestPurchaseMerch_ErrorCommittingTransaction(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+func TestCreatePurchase(t *testing.T) {
+	store := newShopStore()
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
-
-	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "userName", 200))
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs("userName", "", 100, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectQuery("INSERT INTO \"purchase\" (.+) VALUES (.+)").
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit().WillReturnError(fmt.Errorf(""))
-
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
-
-	assert.Error(t, err)
-	assert.Equal(t, "не удалось зафиксировать транзакцию: ", err.Error())
-	assert.Empty(t, result)
+	purchaseService := service2.NewPurchaseService(store)
+	result, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username:       "userName",
+		ItemName:       "cup",
+		Quantity:       3,
+		IdempotencyKey: "key-1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cup", result.Purchase.Item)
+	assert.Equal(t, 3, result.Purchase.Quantity)
+	assert.Equal(t, 20, result.Purchase.Price)
+	assert.Equal(t, 60, result.Purchase.Total)
+	assert.Equal(t, "key-1", result.Purchase.IdempotencyKey)
+	assert.Equal(t, 140, result.Balance)
+	assert.False(t, result.Replayed)
+
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 140, employee.Balance)
+	purchases := store.PurchasesList()
+	assert.Len(t, purchases, 1)
+	assert.Equal(t, result.Purchase.ID, purchases[0].ID)
 }
 
-func TestPurchaseMerch_MerchNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
+func TestCreatePurchase_ReplayedByIdempotencyKey(t *testing.T) {
+	store := newShopStore()
+	purchaseService := service2.NewPurchaseService(store)
+	req := service2.PurchaseRequest{
+		Username:       "userName",
+		ItemName:       "cup",
+		Quantity:       3,
+		IdempotencyKey: "key-1",
 	}
-	defer db.Close()
 
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnError(fmt.Errorf("товар не найден"))
+	first, err := purchaseService.CreatePurchase(context.Background(), req)
+	assert.NoError(t, err)
 
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
-	assert.Error(t, err)
-	assert.Equal(t, "", result)
-	assert.Contains(t, err.Error(), "товар itemName не найден")
+	replayed, err := purchaseService.CreatePurchase(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, replayed.Replayed)
+	assert.Equal(t, first.Purchase.ID, replayed.Purchase.ID)
+	assert.Equal(t, 140, replayed.Balance)
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Fatalf("не все ожидания выполнены: %v", err)
-	}
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 140, employee.Balance, "повтор не списывает монеты второй раз")
+	assert.Len(t, store.PurchasesList(), 1)
 }
 
-func TestPurchaseMerch_UserNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+func TestCreatePurchase_IdempotencyKeyReused(t *testing.T) {
+	store := newShopStore()
+	purchaseService := service2.NewPurchaseService(store)
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
-	mock.ExpectBegin()
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnError(fmt.Errorf("пользователь не найден"))
-
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
-	assert.Error(t, err)
-	assert.Equal(t, "", result)
-	assert.Contains(t, err.Error(), "пользователь userName не найден")
+	_, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "userName", ItemName: "cup", Quantity: 3, IdempotencyKey: "key-1",
+	})
+	assert.NoError(t, err)
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Fatalf("не все ожидания выполнены: %v", err)
-	}
+	result, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "userName", ItemName: "cup", Quantity: 1, IdempotencyKey: "key-1",
+	})
+	assert.ErrorIs(t, err, service2.ErrIdempotencyKeyReused)
+	assert.Nil(t, result)
 }
 
-func TestPurchaseMerch_NotEnoughCoins(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("ошибка при создании mock базы данных: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
+func TestCreatePurchase_NotEnoughCoins(t *testing.T) {
+	store := newShopStore()
+
+	purchaseService := service2.NewPurchaseService(store)
+	result, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "userName",
+		ItemName: "cup",
+		Quantity: 11,
+	})
+	assert.ErrorIs(t, err, service2.ErrInsufficientFunds)
+	assert.Nil(t, result)
+
+	employee, _ := store.Employee("userName")
+	assert.Equal(t, 200, employee.Balance)
+	assert.Empty(t, store.PurchasesList())
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"merch\" WHERE name = (.+)").
-		WithArgs("itemName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
-			AddRow(1, "itemName", 100))
-	mock.ExpectBegin()
+func TestCreatePurchase_NotFound(t *testing.T) {
+	purchaseService := service2.NewPurchaseService(newShopStore())
 
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("userName", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "userName", 50))
+	_, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "userName", ItemName: "hoodie", Quantity: 1,
+	})
+	assert.ErrorIs(t, err, service2.ErrMerchNotFound)
 
-	purchaseService := service2.NewPurchaseService()
-	result, err := purchaseService.PurchaseMerch(gdb, "userName", "itemName")
-	assert.Error(t, err)
-	assert.Equal(t, "", result)
-	assert.Contains(t, err.Error(), "недостаточно монет для покупки товара itemName")
+	_, err = purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "ghost", ItemName: "cup", Quantity: 1,
+	})
+	assert.ErrorIs(t, err, service2.ErrEmployeeNotFound)
+}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Fatalf("не все ожидания выполнены: %v", err)
-	}
+func TestCreatePurchase_InvalidQuantity(t *testing.T) {
+	purchaseService := service2.NewPurchaseService(nil)
+	_, err := purchaseService.CreatePurchase(context.Background(), service2.PurchaseRequest{
+		Username: "userName",
+		ItemName: "cup",
+		Quantity: 0,
+	})
+	assert.ErrorIs(t, err, service2.ErrInvalidQuantity)
 }
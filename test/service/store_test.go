@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"merch-api/model"
+	"merch-api/repository"
+	"merch-api/repository/memory"
+)
+
+var errStorage = errors.New("storage unavailable")
+
+// failingStore оборачивает хранилище в памяти и имитирует отказы отдельных операций
+type failingStore struct {
+	repository.Store
+	failBalanceUpdateFor uint
+	failPurchaseCreate   bool
+	failTransferCreate   bool
+	failInventory        bool
+	failSent             bool
+	failReceived         bool
+	failCommit           bool
+}
+
+func (s *failingStore) Employees() repository.EmployeeRepository {
+	return &failingEmployees{EmployeeRepository: s.Store.Employees(), store: s}
+}
+
+func (s *failingStore) Purchases() repository.PurchaseRepository {
+	return &failingPurchases{PurchaseRepository: s.Store.Purchases(), store: s}
+}
+
+func (s *failingStore) Transactions() repository.TransactionRepository {
+	return &failingTransactions{TransactionRepository: s.Store.Transactions(), store: s}
+}
+
+func (s *failingStore) WithinTransaction(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.Store.WithinTransaction(ctx, func(tx repository.Store) error {
+		wrapped := *s
+		wrapped.Store = tx
+		if err := fn(&wrapped); err != nil {
+			return err
+		}
+		if s.failCommit {
+			return errStorage
+		}
+		return nil
+	})
+}
+
+type failingEmployees struct {
+	repository.EmployeeRepository
+	store *failingStore
+}
+
+func (r *failingEmployees) UpdateBalance(ctx context.Context, id uint, balance int) error {
+	if r.store.failBalanceUpdateFor == id {
+		return errStorage
+	}
+	return r.EmployeeRepository.UpdateBalance(ctx, id, balance)
+}
+
+type failingPurchases struct {
+	repository.PurchaseRepository
+	store *failingStore
+}
+
+func (r *failingPurchases) Create(ctx context.Context, purchase *model.Purchase) error {
+	if r.store.failPurchaseCreate {
+		return errStorage
+	}
+	return r.PurchaseRepository.Create(ctx, purchase)
+}
+
+func (r *failingPurchases) InventoryByEmployee(ctx context.Context, employeeID uint) ([]repository.InventoryRow, error) {
+	if r.store.failInventory {
+		return nil, errStorage
+	}
+	return r.PurchaseRepository.InventoryByEmployee(ctx, employeeID)
+}
+
+type failingTransactions struct {
+	repository.TransactionRepository
+	store *failingStore
+}
+
+func (r *failingTransactions) Create(ctx context.Context, transaction *model.Transaction) error {
+	if r.store.failTransferCreate {
+		return errStorage
+	}
+	return r.TransactionRepository.Create(ctx, transaction)
+}
+
+func (r *failingTransactions) SentBy(ctx context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	if r.store.failSent {
+		return nil, errStorage
+	}
+	return r.TransactionRepository.SentBy(ctx, employeeID)
+}
+
+func (r *failingTransactions) ReceivedBy(ctx context.Context, employeeID uint) ([]repository.CoinTransfer, error) {
+	if r.store.failReceived {
+		return nil, errStorage
+	}
+	return r.TransactionRepository.ReceivedBy(ctx, employeeID)
+}
+
+func newShopStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "userName", Password: "hash", Balance: 200})
+	store.AddMerch(model.Merch{Name: "itemName", Price: 100})
+	store.AddMerch(model.Merch{Name: "cup", Price: 20})
+	return store
+}
@@ -1,316 +1,157 @@
 package service
 
 import (
-	"fmt"
-	"github.com/DATA-DOG/go-sqlmock"
+	"context"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository/memory"
 	service2 "merch-api/service"
 	"testing"
 )
 
-func TestSendCoins(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
+func newTransferStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddEmployee(model.Employee{Username: "user1", Balance: 100})
+	store.AddEmployee(model.Employee{Username: "user2", Balance: 50})
+	return store
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
+func assertBalances(t *testing.T, store *memory.Store, user1, user2 int) {
+	t.Helper()
+	sender, _ := store.Employee("user1")
+	receiver, _ := store.Employee("user2")
+	assert.Equal(t, user1, sender.Balance)
+	assert.Equal(t, user2, receiver.Balance)
+}
 
-	mock.ExpectBegin()
+func TestSendCoins(t *testing.T) {
+	store := newTransferStore()
 
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(90, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	transactionService := service2.NewTransactionService(store)
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 10)
 
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(60, 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "Перевод успешен! Кол-во: 10 монет пользователю user2. Новый баланс: отправитель 90, получатель 60", result)
+	assertBalances(t, store, 90, 60)
 
-	mock.ExpectQuery("INSERT INTO \"transaction\" (.+)").
-		WithArgs(1, 2, 10).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	transactions := store.TransactionsList()
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, uint(1), transactions[0].SenderID)
+	assert.Equal(t, uint(2), transactions[0].ReceiverID)
+	assert.Equal(t, 10, transactions[0].Amount)
+}
 
-	mock.ExpectCommit()
+func TestSendCoins_ReceiverSortsBeforeSender(t *testing.T) {
+	store := newTransferStore()
 
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(store)
+	_, err := transactionService.SendCoins(context.Background(), "user2", "user1", 50)
 
 	assert.NoError(t, err)
-	assert.Equal(t, "Перевод успешен! Кол-во: 10 монет пользователю user2. Новый баланс: отправитель 90, получатель 60", result)
+	assertBalances(t, store, 150, 0)
 }
 
 func TestSendCoins_ErrorCommittingTransaction(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
-
-	mock.ExpectBegin()
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(90, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	store := newTransferStore()
 
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(60, 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	mock.ExpectQuery("INSERT INTO \"transaction\" (.+)").
-		WithArgs(1, 2, 10).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-
-	mock.ExpectCommit().WillReturnError(fmt.Errorf(""))
-
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(&failingStore{Store: store, failCommit: true})
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 10)
 
 	assert.Error(t, err)
-	assert.Equal(t, "не удалось зафиксировать транзакцию: ", err.Error())
+	assert.Equal(t, "не удалось зафиксировать транзакцию: storage unavailable", err.Error())
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
+	assert.Empty(t, store.TransactionsList())
 }
 
 func TestSendCoins_ErrorCreatingTransaction(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
-
-	mock.ExpectBegin()
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(90, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	store := newTransferStore()
 
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(60, 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	mock.ExpectQuery("INSERT INTO \"transaction\" (.+)").
-		WithArgs(1, 2, 10).
-		WillReturnError(fmt.Errorf("не удалось создать запись о переводе"))
-
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(&failingStore{Store: store, failTransferCreate: true})
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 10)
 
 	assert.Error(t, err)
 	assert.Equal(t, "не удалось создать запись о переводе", err.Error())
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
 }
 
-func TestSendCoins_ErrorBeginningTransaction(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
-
-	mock.ExpectBegin().WillReturnError(fmt.Errorf("ошибка при начале транзакции"))
+func TestSendCoins_InsufficientBalance(t *testing.T) {
+	store := newTransferStore()
 
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(store)
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 101)
 
 	assert.Error(t, err)
-	assert.Equal(t, "ошибка при начале транзакции", err.Error())
+	assert.Equal(t, "недостаточно монет на балансе пользователя user1", err.Error())
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
 }
 
-func TestSendCoins_InsufficientBalance(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
+func TestSendCoins_RejectsNonPositiveAmount(t *testing.T) {
+	for _, amount := range []int{0, -10} {
+		store := newTransferStore()
 
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
+		transactionService := service2.NewTransactionService(store)
+		result, err := transactionService.SendCoins(context.Background(), "user1", "user2", amount)
 
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+		assert.ErrorIs(t, err, service2.ErrInvalidInput)
+		assert.Empty(t, result)
+		assertBalances(t, store, 100, 50)
+		assert.Empty(t, store.TransactionsList())
 	}
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 5))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
+func TestSendCoins_RejectsSelfTransfer(t *testing.T) {
+	store := newTransferStore()
 
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(store)
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user1", 10)
 
-	assert.Error(t, err)
-	assert.Equal(t, "недостаточно монет на балансе пользователя user1", err.Error())
+	assert.ErrorIs(t, err, service2.ErrInvalidInput)
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
+	assert.Empty(t, store.TransactionsList())
 }
 
 func TestSendCoins_FromUserNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnError(fmt.Errorf("пользователь не найден"))
-
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(newTransferStore())
+	result, err := transactionService.SendCoins(context.Background(), "user0", "user2", 10)
 
 	assert.Error(t, err)
-	assert.Equal(t, "пользователь user1 не найден", err.Error())
+	assert.Equal(t, "пользователь user0 не найден", err.Error())
 	assert.Empty(t, result)
 }
 
 func TestSendCoins_ToUserNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnError(fmt.Errorf("пользователь не найден"))
-
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(newTransferStore())
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user3", 10)
 
 	assert.Error(t, err)
-	assert.Equal(t, "пользователь user2 не найден", err.Error())
+	assert.Equal(t, "пользователь user3 не найден", err.Error())
 	assert.Empty(t, result)
 }
 
 func TestSendCoins_UpdateSenderBalanceError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
-
-	mock.ExpectBegin()
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(90, 1).
-		WillReturnError(fmt.Errorf("ошибка при обновлении"))
+	store := newTransferStore()
 
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(&failingStore{Store: store, failBalanceUpdateFor: 1})
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 10)
 
 	assert.Error(t, err)
 	assert.Equal(t, "не удалось обновить баланс отправителя", err.Error())
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
 }
 
 func TestSendCoins_UpdateReceiverBalanceError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 100))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(2, "user2", 50))
-
-	mock.ExpectBegin()
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(90, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	mock.ExpectExec("UPDATE \"employee\" SET (.+) WHERE (.+)").
-		WithArgs(60, 2).
-		WillReturnError(fmt.Errorf("ошибка при обновлении"))
+	store := newTransferStore()
 
-	transactionService := service2.NewTransactionService()
-	result, err := transactionService.SendCoins(gdb, "user1", "user2", 10)
+	transactionService := service2.NewTransactionService(&failingStore{Store: store, failBalanceUpdateFor: 2})
+	result, err := transactionService.SendCoins(context.Background(), "user1", "user2", 10)
 
 	assert.Error(t, err)
 	assert.Equal(t, "не удалось обновить баланс получателя", err.Error())
 	assert.Empty(t, result)
+	assertBalances(t, store, 100, 50)
 }
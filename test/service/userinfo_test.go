@@ -1,196 +1,94 @@
 package service
 
 import (
-	"fmt"
-	"github.com/DATA-DOG/go-sqlmock"
+	"context"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"merch-api/model"
+	"merch-api/repository/memory"
 	service2 "merch-api/service"
 	"testing"
 )
 
-func TestGetUserInfo(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Не удалось создать mock db: %v", err)
+func newUserInfoStore() *memory.Store {
+	store := memory.NewStore()
+	user1 := store.AddEmployee(model.Employee{Username: "user1", Balance: 1000})
+	user2 := store.AddEmployee(model.Employee{Username: "user2", Balance: 1000, Profile: model.EmployeeProfile{DisplayName: "Мария Иванова"}})
+	user3 := store.AddEmployee(model.Employee{Username: "user3", Balance: 1000})
+	item1 := store.AddMerch(model.Merch{Name: "item1", Price: 10})
+	item2 := store.AddMerch(model.Merch{Name: "item2", Price: 10})
+
+	ctx := context.Background()
+	for _, purchase := range []model.Purchase{
+		{EmployeeID: user1.ID, MerchID: item1.ID, Quantity: 2},
+		{EmployeeID: user1.ID, MerchID: item2.ID, Quantity: 1},
+		{EmployeeID: user1.ID, MerchID: item2.ID, Quantity: 4},
+		{EmployeeID: user2.ID, MerchID: item1.ID, Quantity: 7},
+	} {
+		_ = store.Purchases().Create(ctx, &purchase)
 	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	for _, transaction := range []model.Transaction{
+		{SenderID: user1.ID, ReceiverID: user2.ID, Amount: 100},
+		{SenderID: user1.ID, ReceiverID: user3.ID, Amount: 200},
+		{SenderID: user3.ID, ReceiverID: user1.ID, Amount: 150},
+		{SenderID: user2.ID, ReceiverID: user3.ID, Amount: 250},
+	} {
+		_ = store.Transactions().Create(ctx, &transaction)
 	}
+	return store
+}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 1000))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"purchase\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"type", "quantity"}).
-			AddRow("item1", 2).
-			AddRow("item2", 5))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"toUser", "amount"}).
-			AddRow("user2", 100).
-			AddRow("user3", 200))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"fromUser", "amount"}).
-			AddRow("user4", 150).
-			AddRow("user5", 250))
-
-	userInfoService := service2.NewUserInfoService()
-	userInfo, err := userInfoService.GetUserInfo(gdb, "user1")
+func TestGetUserInfo(t *testing.T) {
+	userInfoService := service2.NewUserInfoService(newUserInfoStore())
+	userInfo, err := userInfoService.GetUserInfo(context.Background(), "user1")
 
 	assert.NoError(t, err)
 
 	assert.Equal(t, 1000, userInfo.Coins)
-	assert.Len(t, userInfo.Inventory, 2)
-	assert.Equal(t, "item1", userInfo.Inventory[0].Type)
-	assert.Equal(t, 2, userInfo.Inventory[0].Quantity)
-	assert.Equal(t, "item2", userInfo.Inventory[1].Type)
-	assert.Equal(t, 5, userInfo.Inventory[1].Quantity)
-	assert.Len(t, userInfo.CoinHistory.Sent, 2)
-	assert.Equal(t, "user2", userInfo.CoinHistory.Sent[0].ToUser)
-	assert.Equal(t, 100, userInfo.CoinHistory.Sent[0].Amount)
-	assert.Len(t, userInfo.CoinHistory.Received, 2)
-	assert.Equal(t, "user4", userInfo.CoinHistory.Received[0].FromUser)
-	assert.Equal(t, 150, userInfo.CoinHistory.Received[0].Amount)
+	assert.ElementsMatch(t, []service2.InventoryItem{
+		{Type: "item1", Quantity: 2},
+		{Type: "item2", Quantity: 5},
+	}, userInfo.Inventory)
+	assert.ElementsMatch(t, []service2.SentCoinsItem{
+		{ToUser: "user2", ToUserDisplayName: "Мария Иванова", Amount: 100},
+		{ToUser: "user3", ToUserDisplayName: "user3", Amount: 200},
+	}, userInfo.CoinHistory.Sent)
+	assert.Equal(t, []service2.ReceivedCoinsItem{
+		{FromUser: "user3", FromUserDisplayName: "user3", Amount: 150},
+	}, userInfo.CoinHistory.Received)
 }
 
 func TestGetUserInfo_UserNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Не удалось создать mock db: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("nonexistentUser", 1).
-		WillReturnError(fmt.Errorf("пользователь не найден"))
-
-	userInfoService := service2.NewUserInfoService()
-	userInfo, err := userInfoService.GetUserInfo(gdb, "user1")
+	userInfoService := service2.NewUserInfoService(newUserInfoStore())
+	userInfo, err := userInfoService.GetUserInfo(context.Background(), "ghost")
 
 	assert.Error(t, err)
+	assert.Equal(t, "пользователь ghost не найден", err.Error())
 	assert.Empty(t, userInfo)
 }
 
 func TestGetUserInfo_PurchasesFetchError(t *testing.T) {
-
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Не удалось создать mock db: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 1000))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"purchase\" (.+)").
-		WithArgs(1).
-		WillReturnError(fmt.Errorf(""))
-
-	userInfoService := service2.NewUserInfoService()
-	userInfo, err := userInfoService.GetUserInfo(gdb, "user1")
+	userInfoService := service2.NewUserInfoService(&failingStore{Store: newUserInfoStore(), failInventory: true})
+	userInfo, err := userInfoService.GetUserInfo(context.Background(), "user1")
 
 	assert.Error(t, err)
-	assert.Equal(t, "не удалось получить инвентарь пользователя: ", err.Error())
+	assert.Equal(t, "не удалось получить инвентарь пользователя: storage unavailable", err.Error())
 	assert.Empty(t, userInfo.Inventory)
 }
 
 func TestGetUserInfo_SentTransactionsFetchError(t *testing.T) {
-
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Не удалось создать mock db: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 1000))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"purchase\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"type", "quantity"}).
-			AddRow("item1", 2).
-			AddRow("item2", 5))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
-		WithArgs(1).
-		WillReturnError(fmt.Errorf(""))
-
-	userInfoService := service2.NewUserInfoService()
-	userInfo, err := userInfoService.GetUserInfo(gdb, "user1")
+	userInfoService := service2.NewUserInfoService(&failingStore{Store: newUserInfoStore(), failSent: true})
+	userInfo, err := userInfoService.GetUserInfo(context.Background(), "user1")
 
 	assert.Error(t, err)
-	assert.Equal(t, "не удалось получить отправленные транзакции пользователя: ", err.Error())
+	assert.Equal(t, "не удалось получить отправленные транзакции пользователя: storage unavailable", err.Error())
 	assert.Empty(t, userInfo.CoinHistory.Sent)
 }
 
 func TestGetUserInfo_ReceivedTransactionsFetchError(t *testing.T) {
-
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Не удалось создать mock db: %v", err)
-	}
-	defer db.Close()
-
-	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("ошибка при открытии gorm DB: %v", err)
-	}
-
-	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
-		WithArgs("user1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).
-			AddRow(1, "user1", 1000))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"purchase\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"type", "quantity"}).
-			AddRow("item1", 2).
-			AddRow("item2", 5))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"toUser", "amount"}).
-			AddRow("user2", 100).
-			AddRow("user3", 200))
-
-	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
-		WithArgs(1).
-		WillReturnError(fmt.Errorf(""))
-
-	userInfoService := service2.NewUserInfoService()
-	userInfo, err := userInfoService.GetUserInfo(gdb, "user1")
+	userInfoService := service2.NewUserInfoService(&failingStore{Store: newUserInfoStore(), failReceived: true})
+	userInfo, err := userInfoService.GetUserInfo(context.Background(), "user1")
 
 	assert.Error(t, err)
-	assert.Equal(t, "не удалось получить полученные транзакции пользователя: ", err.Error())
+	assert.Equal(t, "не удалось получить полученные транзакции пользователя: storage unavailable", err.Error())
 	assert.Empty(t, userInfo.CoinHistory.Received)
 }
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"merch-api/middleware"
+	pgrepo "merch-api/repository/postgres"
+	service2 "merch-api/service"
+	tracing2 "merch-api/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return exporter
+}
+
+func TestGetUserInfo_SpansPerQuery(t *testing.T) {
+	exporter := setupExporter(t)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Не удалось создать mock db: %v", err)
+	}
+	defer db.Close()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("ошибка при открытии gorm DB: %v", err)
+	}
+	if err := gdb.Use(tracing2.GormPlugin{}); err != nil {
+		t.Fatalf("ошибка при подключении плагина трассировки: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM \"employee\" WHERE username = (.+)").
+		WithArgs("user1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "balance"}).AddRow(1, "user1", 1000))
+	mock.ExpectQuery("SELECT (.+) FROM \"purchase\" (.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "quantity"}))
+	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "amount"}))
+	mock.ExpectQuery("SELECT (.+) FROM \"transaction\" (.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "amount"}))
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	_, err = service2.NewUserInfoService(pgrepo.NewStore(gdb)).GetUserInfo(ctx, "user1")
+	root.End()
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	var serviceSpan tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "UserInfoService.GetUserInfo" {
+			serviceSpan = span
+		}
+	}
+	assert.Equal(t, root.SpanContext().SpanID(), serviceSpan.Parent.SpanID())
+
+	var querySpans int
+	for _, span := range spans {
+		if strings.HasPrefix(span.Name, "gorm.") {
+			querySpans++
+			assert.Equal(t, serviceSpan.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+		}
+	}
+	assert.Equal(t, 4, querySpans)
+}
+
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := setupExporter(t)
+
+	r := gin.New()
+	r.Use(middleware.Tracing())
+	r.GET("/items/:id", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(w, req)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /items/:id", spans[0].Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+		assert.Equal(t, "Error", spans[0].Status.Code.String())
+	}
+}
@@ -0,0 +1,13 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func newResource(serviceName string) *resource.Resource {
+	if serviceName == "" {
+		serviceName = instrumentationName
+	}
+	return resource.NewSchemaless(attribute.String("service.name", serviceName))
+}
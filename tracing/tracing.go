@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+const instrumentationName = "merch-api"
+
+type Config struct {
+	// Endpoint — адрес OTLP/HTTP-коллектора (host:port или URL). Пустой адрес выключает экспорт.
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+}
+
+// Setup настраивает глобальный TracerProvider и возвращает функцию для его остановки
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.Endpoint)}
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewTracerProvider(exporter, cfg.ServiceName)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func NewTracerProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource(serviceName)),
+	)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}